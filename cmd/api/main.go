@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -12,51 +13,165 @@ import (
 
 	"github.com/ctrixcode/go-chi-postgres/internal/config"
 	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/internal/secrets"
 	"github.com/ctrixcode/go-chi-postgres/internal/server"
+	"github.com/ctrixcode/go-chi-postgres/internal/tracing"
 	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
 )
 
+const (
+	// requestDrainTimeout bounds waiting for in-flight requests on shutdown
+	requestDrainTimeout = 30 * time.Second
+	// backgroundDrainTimeout bounds waiting for jobs, relayed events and
+	// scheduled tasks once requests are done
+	backgroundDrainTimeout = 30 * time.Second
+	// forceExitGrace is how long cancelled work gets to record its outcome
+	// before the process exits regardless
+	forceExitGrace = 10 * time.Second
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	loader := config.NewLoader(fs)
+	fs.Parse(os.Args[1:])
+
+	cfg, err := loader.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	// Setup structured logging
-	logger.Init()
+	if err := logger.Init(cfg.Log.Options()); err != nil {
+		slog.Error("failed to initialize logger", "error", err)
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Init(context.Background(), cfg)
+	if err != nil {
+		slog.Error("failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
 
-	cfg := config.LoadConfig()
-	db := database.New(cfg.DatabaseURL)
+	// Background work tied to the process lifetime
+	appCtx, stopApp := context.WithCancel(context.Background())
+	defer stopApp()
+
+	var dbOpts []database.Option
+	password, err := newSecretRefresher(cfg, "database.password", cfg.Database.Password)
+	if err != nil {
+		slog.Error("failed to create secret provider", "error", err)
+		os.Exit(1)
+	}
+	if password != nil {
+		go password.Run(appCtx)
+		dbOpts = append(dbOpts, database.WithPassword(password.Value))
+	}
+
+	db := database.New(cfg.DatabaseURL, dbOpts...)
 
 	s := server.NewServer(cfg, db)
 
+	// Reload settings on SIGHUP and when config files change
+	watchConfig(appCtx, loader, s.Config())
+
+	// Run background jobs alongside the API; replicas with JOBS_ENABLED=false
+	// only enqueue
+	var worker *jobs.Worker
+	if cfg.Jobs.Enabled {
+		worker = s.NewJobWorker()
+		worker.Start()
+	}
+	if cfg.Scheduler.Enabled {
+		s.Scheduler().Start()
+	}
+	var relay *events.Relay
+	if cfg.Outbox.Enabled {
+		relay, err = s.NewOutboxRelay()
+		if err != nil {
+			slog.Error("failed to build outbox relay", "error", err)
+			os.Exit(1)
+		}
+		relay.Start()
+	}
+	if cfg.Stream.Enabled {
+		s.ChangeFeed().Start()
+	}
+
 	// Server run context
 	serverCtx, serverStopCtx := context.WithCancel(context.Background())
 
 	// Listen for syscall signals for process to interrupt/quit
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	go func() {
 		<-sig
 
+		// Fail readiness first so load balancers stop routing new traffic
+		s.Health().SetShuttingDown()
+		slog.Info("shutdown started, draining", "delay", cfg.ShutdownDrainDelay)
+		time.Sleep(cfg.ShutdownDrainDelay)
+
+		// Last resort for a shutdown step that ignores its deadline
+		watchdog := time.AfterFunc(requestDrainTimeout+backgroundDrainTimeout+forceExitGrace, func() {
+			slog.Error("graceful shutdown timed out.. forcing exit.")
+			os.Exit(1)
+		})
+		defer watchdog.Stop()
+
 		// Shutdown signal with grace period of 30 seconds
-		shutdownCtx, _ := context.WithTimeout(serverCtx, 30*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(serverCtx, requestDrainTimeout)
+		defer cancel()
 
-		go func() {
-			<-shutdownCtx.Done()
-			if shutdownCtx.Err() == context.DeadlineExceeded {
-				slog.Error("graceful shutdown timed out.. forcing exit.")
-				os.Exit(1)
+		if admin := s.GetAdminServer(); admin != nil {
+			if err := admin.Shutdown(shutdownCtx); err != nil {
+				slog.Error("admin server shutdown error", "error", err)
 			}
-		}()
+		}
 
-		// Trigger graceful shutdown
-		err := s.GetHTTPServer().Shutdown(shutdownCtx)
-		if err != nil {
+		// Streams only end with the change feed, so stop it before waiting
+		// for requests to finish
+		if err := s.ChangeFeed().Shutdown(shutdownCtx); err != nil {
+			slog.Error("change feed shutdown error", "error", err)
+		}
+
+		// Trigger graceful shutdown, cutting off requests still running at
+		// the deadline
+		if err := s.GetHTTPServer().Shutdown(shutdownCtx); err != nil {
 			slog.Error("server shutdown error", "error", err)
-			os.Exit(1)
+			s.GetHTTPServer().Close()
+		}
+
+		// Let running jobs and tasks finish before the database is closed.
+		// They get their own budget, so work still running at its end is
+		// cancelled and recorded as interrupted instead of lost on exit.
+		drainCtx, cancelDrain := context.WithTimeout(context.Background(), backgroundDrainTimeout)
+		defer cancelDrain()
+		if worker != nil {
+			if err := worker.Shutdown(drainCtx); err != nil {
+				slog.Error("job worker shutdown error", "error", err)
+			}
+		}
+		if relay != nil {
+			if err := relay.Shutdown(drainCtx); err != nil {
+				slog.Error("outbox relay shutdown error", "error", err)
+			}
+		}
+		if err := s.Scheduler().Shutdown(drainCtx); err != nil {
+			slog.Error("scheduler shutdown error", "error", err)
 		}
 		serverStopCtx()
 	}()
 
 	// Run the server
 	slog.Info("server starting", "addr", fmt.Sprintf("http://localhost%s", s.GetHTTPServer().Addr))
-	err := s.Start()
+	err = s.Start()
 	if err != nil && err != http.ErrServerClosed {
 		slog.Error("server failed to start", "error", err)
 		os.Exit(1)
@@ -71,5 +186,30 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Flush pending spans
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("tracing shutdown error", "error", err)
+	}
+
 	slog.Info("server exited properly")
 }
+
+// newSecretRefresher returns a refresher for a setting resolved from a secret
+// provider or read from a *_FILE variable, or nil when the setting is static
+// or refreshing is disabled.
+func newSecretRefresher(cfg *config.Config, path, initial string) (*secrets.Refresher, error) {
+	if cfg.Secrets.RefreshInterval <= 0 {
+		return nil, nil
+	}
+	if name, ok := cfg.SecretRef(path); ok {
+		provider, err := cfg.Secrets.NewProvider()
+		if err != nil {
+			return nil, err
+		}
+		return secrets.NewRefresher(provider, name, initial, cfg.Secrets.RefreshInterval), nil
+	}
+	if file, ok := cfg.SecretFile(path); ok {
+		return secrets.NewRefresher(secrets.NewFileProvider(), file, initial, cfg.Secrets.RefreshInterval), nil
+	}
+	return nil, nil
+}
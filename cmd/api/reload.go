@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+)
+
+// watchConfig re-reads the configuration on SIGHUP or when a config file
+// changes, and applies the reloadable settings to manager.
+func watchConfig(ctx context.Context, loader *config.Loader, manager *config.Manager) {
+	manager.Subscribe(func(old, new *config.Config) {
+		if old.Log.Level == new.Log.Level {
+			return
+		}
+		if level, err := logger.ParseLevel(new.Log.Level); err == nil {
+			logger.SetLevel(level)
+		}
+	})
+
+	var mu sync.Mutex
+	reload := func(trigger string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		next, err := loader.Load()
+		if err != nil {
+			slog.Error("config reload failed, keeping current settings", "trigger", trigger, "error", err)
+			return
+		}
+		changed, ignored := manager.Apply(next)
+		slog.Info("config reloaded", "trigger", trigger, "changed", len(changed), "ignored", len(ignored))
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				reload("SIGHUP")
+			}
+		}
+	}()
+
+	if err := config.Watch(ctx, loader.Files(), func() { reload("file change") }); err != nil {
+		slog.Warn("config file watching disabled", "error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/scaffold"
+)
+
+// Generates a resource with every layer the examples resource has:
+//
+//	go run ./cmd/tools/generate product name:string:required,min=3 price:float:required,gt=0 in_stock:bool
+//
+// Field types are string, text, int, int32, float, bool, time and uuid. The
+// optional third part holds validator rules. Run goose to apply the new
+// migration afterwards.
+func main() {
+	root := flag.String("root", ".", "repository root containing go.mod")
+	plural := flag.String("plural", "", "plural of the entity name, derived when empty")
+	dryRun := flag.Bool("dry-run", false, "print the files instead of writing them")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: generate [flags] <entity> <name:type[:validations]>...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	res, err := scaffold.ParseResource(flag.Arg(0), flag.Args()[1:], *plural)
+	if err != nil {
+		fail(err)
+	}
+
+	if *dryRun {
+		if res.Module, err = scaffold.ModulePath(*root); err != nil {
+			fail(err)
+		}
+		files, err := scaffold.Render(res, time.Now())
+		if err != nil {
+			fail(err)
+		}
+		for _, f := range files {
+			fmt.Printf("==> %s\n%s\n", f.Path, f.Content)
+		}
+		return
+	}
+
+	written, err := scaffold.Generate(*root, res, time.Now())
+	if err != nil {
+		fail(err)
+	}
+	for _, path := range written {
+		fmt.Println(path)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/secrets"
+)
+
+// Creates files for the "file" secrets provider:
+//
+//	go run ./cmd/tools/secrets -genkey
+//	SECRETS_KEY=... go run ./cmd/tools/secrets -out secrets.enc < secrets.json
+func main() {
+	genKey := flag.Bool("genkey", false, "print a new random base64 encryption key and exit")
+	out := flag.String("out", "secrets.enc", "path of the encrypted file to write")
+	flag.Parse()
+
+	if *genKey {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			fail(err)
+		}
+		fmt.Println(base64.StdEncoding.EncodeToString(key))
+		return
+	}
+
+	key, err := base64.StdEncoding.DecodeString(os.Getenv("SECRETS_KEY"))
+	if err != nil || len(key) != 32 {
+		fail(fmt.Errorf("SECRETS_KEY must be a base64 encoded 32 byte key"))
+	}
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fail(err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(input, &values); err != nil {
+		fail(fmt.Errorf("stdin must be a JSON object: %w", err))
+	}
+
+	if err := secrets.EncryptFile(*out, key, values); err != nil {
+		fail(err)
+	}
+	fmt.Fprintf(os.Stderr, "wrote %d secrets to %s\n", len(values), *out)
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"strings"
 
 	"github.com/ctrixcode/go-chi-postgres/internal/config"
 	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
@@ -12,25 +14,32 @@ import (
 )
 
 func main() {
-	logger.Init()
-
-	_ = config.LoadConfig()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if err := logger.Init(cfg.Log.Options()); err != nil {
+		slog.Error("failed to initialize logger", "error", err)
+		os.Exit(1)
+	}
 
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbSSLMode := os.Getenv("DB_SSLMODE")
-	targetDBName := os.Getenv("DB_NAME")
+	target, err := url.Parse(cfg.DatabaseURL)
+	if err != nil {
+		slog.Error("Invalid database URL", "error", err)
+		os.Exit(1)
+	}
+	targetDBName := strings.TrimPrefix(target.Path, "/")
 
 	if targetDBName == "" {
-		slog.Error("DB_NAME is not set")
+		slog.Error("database name is not set")
 		os.Exit(1)
 	}
 
 	// Connect to default 'postgres' database
-	connString := fmt.Sprintf("postgres://%s:%s@%s:%s/postgres?sslmode=%s",
-		dbUser, dbPassword, dbHost, dbPort, dbSSLMode)
+	maintenance := *target
+	maintenance.Path = "/postgres"
+	connString := maintenance.String()
 
 	db, err := sqlx.Connect("pgx", connString)
 	if err != nil {
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/sqlgen"
+)
+
+// Generates typed query methods from the annotated .sql files in
+// database/queries, taking column types from database/migrations:
+//
+//	go run ./cmd/tools/sqlgen
+//
+// With -check nothing is written; it exits with status 1 when the generated
+// package is out of date, e.g. after a migration changed a queried table.
+func main() {
+	root := flag.String("root", ".", "repository root")
+	check := flag.Bool("check", false, "fail if the generated code is out of date instead of writing it")
+	cfg := sqlgen.DefaultConfig
+	flag.StringVar(&cfg.Migrations, "migrations", cfg.Migrations, "goose migrations directory")
+	flag.StringVar(&cfg.Queries, "queries", cfg.Queries, "annotated query files directory")
+	flag.StringVar(&cfg.Out, "out", cfg.Out, "output package directory")
+	flag.StringVar(&cfg.Package, "package", cfg.Package, "output package name")
+	flag.Parse()
+
+	if *check {
+		outdated, err := sqlgen.Check(*root, cfg)
+		if err != nil {
+			fail(err)
+		}
+		if len(outdated) > 0 {
+			for _, path := range outdated {
+				fmt.Fprintln(os.Stderr, path)
+			}
+			fail(fmt.Errorf("generated queries are out of date, run go run ./cmd/tools/sqlgen"))
+		}
+		return
+	}
+
+	written, err := sqlgen.Write(*root, cfg)
+	if err != nil {
+		fail(err)
+	}
+	for _, path := range written {
+		fmt.Println(path)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/webui"
+)
+
+// Vendors the browser UI assets pinned in internal/webui so they are embedded
+// in the binary instead of loaded from a CDN:
+//
+//	go run ./cmd/tools/webui
+//
+// Run it again after changing a pinned version, and commit the files.
+func main() {
+	root := flag.String("root", ".", "repository root")
+	registry := flag.String("registry", "https://cdn.jsdelivr.net/npm", "npm CDN to download the packages from")
+	flag.Parse()
+
+	client := &http.Client{Timeout: time.Minute}
+	for _, pkg := range webui.Packages {
+		for _, file := range pkg.Files {
+			path := filepath.Join(*root, "internal", "webui", "dist", pkg.Dir(), filepath.FromSlash(file))
+			if err := download(client, *registry+"/"+pkg.Dir()+"/"+file, path); err != nil {
+				fail(err)
+			}
+			fmt.Println(path)
+		}
+	}
+}
+
+func download(client *http.Client, url, path string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
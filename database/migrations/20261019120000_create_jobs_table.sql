@@ -0,0 +1,29 @@
+-- +goose Up
+CREATE TABLE jobs (
+    id BIGSERIAL PRIMARY KEY,
+    kind TEXT NOT NULL,
+    queue TEXT NOT NULL DEFAULT 'default',
+    payload JSONB NOT NULL,
+    priority SMALLINT NOT NULL DEFAULT 0,
+    state TEXT NOT NULL DEFAULT 'available',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    unique_key TEXT,
+    locked_by TEXT,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    finished_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT jobs_state_check CHECK (state IN ('available', 'running', 'completed', 'dead'))
+);
+
+-- Workers claim available jobs by priority, then age
+CREATE INDEX jobs_claim_idx ON jobs (queue, priority DESC, run_at, id) WHERE state = 'available';
+CREATE INDEX jobs_lease_idx ON jobs (locked_until) WHERE state = 'running';
+-- A unique key is free again once its job completed or died
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (kind, unique_key) WHERE state IN ('available', 'running');
+
+-- +goose Down
+DROP TABLE jobs;
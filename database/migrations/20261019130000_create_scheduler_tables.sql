@@ -0,0 +1,21 @@
+-- +goose Up
+-- The replica holding the lease runs scheduled tasks
+CREATE TABLE scheduler_leases (
+    name TEXT PRIMARY KEY,
+    holder TEXT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE TABLE schedules (
+    name TEXT PRIMARY KEY,
+    -- The slot the latest run was scheduled for, which may be before it started
+    scheduled_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE schedules;
+DROP TABLE scheduler_leases;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Domain events are written here in the transaction that caused them and
+-- relayed to sinks afterwards, in id order
+CREATE TABLE outbox (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    request_id TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    dispatched_at TIMESTAMP WITH TIME ZONE,
+    -- Set when the relay gave up on the event
+    failed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX outbox_pending_idx ON outbox (id) WHERE dispatched_at IS NULL AND failed_at IS NULL;
+
+-- +goose Down
+DROP TABLE outbox;
@@ -0,0 +1,44 @@
+-- +goose Up
+-- Every change to an example is logged by a trigger and announced on the
+-- example_changes channel with its id, so each replica can stream it to its
+-- own clients. The log is kept for a while so clients can resume.
+CREATE TABLE example_changes (
+    id BIGSERIAL PRIMARY KEY,
+    type TEXT NOT NULL,
+    example_id UUID NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX example_changes_created_at_idx ON example_changes (created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION log_example_change() RETURNS trigger AS $$
+DECLARE
+    change_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO example_changes (type, example_id, data)
+        VALUES ('example.deleted', OLD.id, jsonb_build_object('id', OLD.id))
+        RETURNING id INTO change_id;
+    ELSE
+        INSERT INTO example_changes (type, example_id, data)
+        VALUES (CASE TG_OP WHEN 'INSERT' THEN 'example.created' ELSE 'example.updated' END, NEW.id, to_jsonb(NEW))
+        RETURNING id INTO change_id;
+    END IF;
+    -- Notifications are sent on commit; the payload is only the id, which
+    -- keeps it well under the 8000 byte limit
+    PERFORM pg_notify('example_changes', change_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER examples_log_change
+AFTER INSERT OR UPDATE OR DELETE ON examples
+FOR EACH ROW EXECUTE FUNCTION log_example_change();
+
+-- +goose Down
+DROP TRIGGER examples_log_change ON examples;
+DROP FUNCTION log_example_change();
+DROP TABLE example_changes;
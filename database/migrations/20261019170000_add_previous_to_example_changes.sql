@@ -0,0 +1,51 @@
+-- +goose Up
+-- Updates and deletions also log the example as it was, so subscribers
+-- filtering examples can tell when one stops matching and what changed
+ALTER TABLE example_changes ADD COLUMN previous JSONB;
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION log_example_change() RETURNS trigger AS $$
+DECLARE
+    change_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO example_changes (type, example_id, data, previous)
+        VALUES ('example.deleted', OLD.id, jsonb_build_object('id', OLD.id), to_jsonb(OLD))
+        RETURNING id INTO change_id;
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO example_changes (type, example_id, data, previous)
+        VALUES ('example.updated', NEW.id, to_jsonb(NEW), to_jsonb(OLD))
+        RETURNING id INTO change_id;
+    ELSE
+        INSERT INTO example_changes (type, example_id, data)
+        VALUES ('example.created', NEW.id, to_jsonb(NEW))
+        RETURNING id INTO change_id;
+    END IF;
+    PERFORM pg_notify('example_changes', change_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION log_example_change() RETURNS trigger AS $$
+DECLARE
+    change_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO example_changes (type, example_id, data)
+        VALUES ('example.deleted', OLD.id, jsonb_build_object('id', OLD.id))
+        RETURNING id INTO change_id;
+    ELSE
+        INSERT INTO example_changes (type, example_id, data)
+        VALUES (CASE TG_OP WHEN 'INSERT' THEN 'example.created' ELSE 'example.updated' END, NEW.id, to_jsonb(NEW))
+        RETURNING id INTO change_id;
+    END IF;
+    PERFORM pg_notify('example_changes', change_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+ALTER TABLE example_changes DROP COLUMN previous;
@@ -0,0 +1,75 @@
+-- +goose Up
+-- A change used to keep the id drawn when it was logged, but transactions
+-- commit in a different order than they draw ids, so a reader resuming after
+-- an id could miss a change committed later under a lower one. Changes are
+-- now renumbered as their transaction commits, under a lock that makes ids
+-- follow commit order, and only announced then.
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION log_example_change() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO example_changes (type, example_id, data, previous)
+        VALUES ('example.deleted', OLD.id, jsonb_build_object('id', OLD.id), to_jsonb(OLD));
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO example_changes (type, example_id, data, previous)
+        VALUES ('example.updated', NEW.id, to_jsonb(NEW), to_jsonb(OLD));
+    ELSE
+        INSERT INTO example_changes (type, example_id, data)
+        VALUES ('example.created', NEW.id, to_jsonb(NEW));
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION number_example_change() RETURNS trigger AS $$
+DECLARE
+    change_id BIGINT;
+BEGIN
+    -- The lock ("changes" in ASCII) is held until the transaction has
+    -- committed, so ids drawn after it belong to later commits
+    PERFORM pg_advisory_xact_lock(27980790367741299);
+    UPDATE example_changes SET id = nextval('example_changes_id_seq')
+    WHERE id = NEW.id
+    RETURNING id INTO change_id;
+    PERFORM pg_notify('example_changes', change_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- Deferred, so it runs as the transaction commits
+CREATE CONSTRAINT TRIGGER example_changes_number
+AFTER INSERT ON example_changes
+DEFERRABLE INITIALLY DEFERRED
+FOR EACH ROW EXECUTE FUNCTION number_example_change();
+
+-- +goose Down
+DROP TRIGGER example_changes_number ON example_changes;
+DROP FUNCTION number_example_change();
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION log_example_change() RETURNS trigger AS $$
+DECLARE
+    change_id BIGINT;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO example_changes (type, example_id, data, previous)
+        VALUES ('example.deleted', OLD.id, jsonb_build_object('id', OLD.id), to_jsonb(OLD))
+        RETURNING id INTO change_id;
+    ELSIF TG_OP = 'UPDATE' THEN
+        INSERT INTO example_changes (type, example_id, data, previous)
+        VALUES ('example.updated', NEW.id, to_jsonb(NEW), to_jsonb(OLD))
+        RETURNING id INTO change_id;
+    ELSE
+        INSERT INTO example_changes (type, example_id, data)
+        VALUES ('example.created', NEW.id, to_jsonb(NEW))
+        RETURNING id INTO change_id;
+    END IF;
+    PERFORM pg_notify('example_changes', change_id::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- Queries behind the example change feed in internal/changefeed. The log is
+-- written by the examples_log_change trigger.
+
+-- name: GetExampleChange :one
+SELECT * FROM example_changes WHERE id = @id;
+
+-- name: GetLatestExampleChangeID :one
+SELECT id FROM example_changes ORDER BY id DESC LIMIT 1;
+
+-- name: ListExampleChangesAfter :many
+SELECT * FROM example_changes
+WHERE id > @after
+ORDER BY id
+LIMIT @max;
+
+-- name: DeleteExampleChanges :execrows
+DELETE FROM example_changes WHERE created_at < @before;
@@ -0,0 +1,9 @@
+-- Queries on the examples table. Run go run ./cmd/tools/sqlgen after
+-- changing this file or a migration of a table it uses.
+
+-- name: GetExample :one
+-- GetExample returns the example with the given id, or sql.ErrNoRows.
+SELECT * FROM examples WHERE id = $1;
+
+-- name: DeleteExample :execrows
+DELETE FROM examples WHERE id = $1;
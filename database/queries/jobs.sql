@@ -0,0 +1,53 @@
+-- Queries behind the background job queue in internal/jobs.
+
+-- name: InsertJob :one
+-- InsertJob returns sql.ErrNoRows when a pending job has the same kind and
+-- unique key.
+INSERT INTO jobs (kind, queue, payload, priority, run_at, max_attempts, unique_key)
+VALUES (@kind, @queue, @payload, @priority, @run_at, @max_attempts, @unique_key)
+ON CONFLICT (kind, unique_key) WHERE state IN ('available', 'running') DO NOTHING
+RETURNING *;
+
+-- name: ClaimJobs :many
+-- ClaimJobs leases the next jobs due on the given queues, skipping rows other
+-- workers have locked. Running jobs whose lease expired are claimed again.
+-- Leases are on the database clock, so a replica whose clock runs ahead does
+-- not take over jobs that are still running.
+UPDATE jobs
+SET state = 'running', attempts = attempts + 1, locked_by = @worker::text,
+    locked_until = now() + @lock_ms::bigint * interval '1 millisecond', updated_at = now()
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE queue = ANY(@queues) AND kind = ANY(@kinds)
+      AND ((state = 'available' AND run_at <= now()) OR (state = 'running' AND locked_until < now()))
+    ORDER BY priority DESC, run_at, id
+    LIMIT @max
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CompleteJob :execrows
+-- CompleteJob, RetryJob and KillJob only update the attempt that claimed the
+-- job, so a worker whose lease expired cannot overwrite a newer attempt.
+UPDATE jobs
+SET state = 'completed', finished_at = @now::timestamptz, locked_by = NULL, locked_until = NULL, updated_at = @now
+WHERE id = @id AND attempts = @attempts AND state = 'running';
+
+-- name: RetryJob :execrows
+UPDATE jobs
+SET state = 'available', run_at = @run_at, last_error = @error::text, locked_by = NULL, locked_until = NULL, updated_at = @now
+WHERE id = @id AND attempts = @attempts AND state = 'running';
+
+-- name: KillJob :execrows
+UPDATE jobs
+SET state = 'dead', finished_at = @now::timestamptz, last_error = @error::text, locked_by = NULL, locked_until = NULL, updated_at = @now
+WHERE id = @id AND attempts = @attempts AND state = 'running';
+
+-- name: RequeueDeadJob :execrows
+-- RequeueDeadJob gives a dead job a fresh set of attempts.
+UPDATE jobs
+SET state = 'available', attempts = 0, run_at = @now, finished_at = NULL, updated_at = @now
+WHERE id = @id AND state = 'dead';
+
+-- name: DeleteCompletedJobs :execrows
+DELETE FROM jobs WHERE state = 'completed' AND finished_at < @before;
@@ -0,0 +1,29 @@
+-- Queries behind the transactional outbox in internal/events.
+
+-- name: InsertOutboxEvent :one
+INSERT INTO outbox (type, aggregate_type, aggregate_id, payload, request_id, created_at)
+VALUES (@type, @aggregate_type, @aggregate_id, @payload, @request_id, @created_at)
+RETURNING id;
+
+-- name: ListPendingOutboxEvents :many
+-- ListPendingOutboxEvents returns undelivered events oldest first, including
+-- ones waiting to be retried so the relay keeps their order.
+SELECT * FROM outbox
+WHERE dispatched_at IS NULL AND failed_at IS NULL
+ORDER BY id
+LIMIT @max;
+
+-- name: MarkOutboxEventDispatched :exec
+UPDATE outbox SET dispatched_at = @now::timestamptz, attempts = attempts + 1, last_error = NULL
+WHERE id = @id;
+
+-- name: RetryOutboxEvent :exec
+UPDATE outbox SET attempts = attempts + 1, last_error = @error::text, next_attempt_at = @next_attempt_at
+WHERE id = @id;
+
+-- name: FailOutboxEvent :exec
+UPDATE outbox SET attempts = attempts + 1, last_error = @error::text, failed_at = @now::timestamptz
+WHERE id = @id;
+
+-- name: DeleteDispatchedOutboxEvents :execrows
+DELETE FROM outbox WHERE dispatched_at < @before;
@@ -0,0 +1,40 @@
+-- Queries behind the task scheduler in internal/scheduler.
+
+-- name: AcquireSchedulerLease :execrows
+-- AcquireSchedulerLease takes the lease when it is free or expired, or
+-- extends it for its current holder. Expiry is on the database clock, so
+-- replicas with skewed clocks agree on when the lease is free.
+INSERT INTO scheduler_leases (name, holder, expires_at)
+VALUES (@name, @holder, now() + @ttl_ms::bigint * interval '1 millisecond')
+ON CONFLICT (name) DO UPDATE SET holder = @holder, expires_at = now() + @ttl_ms::bigint * interval '1 millisecond'
+WHERE scheduler_leases.holder = @holder OR scheduler_leases.expires_at < now();
+
+-- name: ReleaseSchedulerLease :exec
+DELETE FROM scheduler_leases WHERE name = @name AND holder = @holder;
+
+-- name: GetSchedulerLease :one
+-- GetSchedulerLease returns the lease unless it has expired.
+SELECT * FROM scheduler_leases WHERE name = $1 AND expires_at > now();
+
+-- name: EnsureSchedule :exec
+INSERT INTO schedules (name, created_at) VALUES (@name, @now)
+ON CONFLICT (name) DO NOTHING;
+
+-- name: ListSchedules :many
+SELECT * FROM schedules ORDER BY name;
+
+-- name: StartScheduledRun :execrows
+-- StartScheduledRun records a run of the given slot. It affects no rows when
+-- the slot already ran, so a slot runs once even across a leader change.
+UPDATE schedules SET scheduled_at = @scheduled_at::timestamptz, started_at = @now::timestamptz
+WHERE name = @name AND (scheduled_at IS NULL OR scheduled_at < @scheduled_at);
+
+-- name: FinishScheduledRun :exec
+UPDATE schedules SET finished_at = @now::timestamptz, last_error = @error
+WHERE name = @name;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys WHERE expires_at < @before;
+
+-- name: DeleteExpiredRateLimits :execrows
+DELETE FROM rate_limits WHERE expires_at < @before;
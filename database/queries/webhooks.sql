@@ -0,0 +1,54 @@
+-- Queries behind the outgoing webhooks in internal/webhooks.
+
+-- name: InsertWebhook :one
+INSERT INTO webhooks (url, events, secret, created_at, updated_at)
+VALUES (@url, @events, @secret, @now, @now)
+RETURNING *;
+
+-- name: GetWebhook :one
+SELECT * FROM webhooks WHERE id = $1;
+
+-- name: ListWebhooks :many
+SELECT * FROM webhooks ORDER BY created_at, id;
+
+-- name: ListEnabledWebhooks :many
+SELECT * FROM webhooks WHERE enabled ORDER BY created_at, id;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET url = @url, events = @events, enabled = @enabled, consecutive_failures = @consecutive_failures,
+    disabled_at = @disabled_at, updated_at = @now
+WHERE id = @id
+RETURNING *;
+
+-- name: DeleteWebhook :execrows
+DELETE FROM webhooks WHERE id = $1;
+
+-- name: ResetWebhookFailures :exec
+UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0;
+
+-- name: CountWebhookFailure :one
+UPDATE webhooks SET consecutive_failures = consecutive_failures + 1 WHERE id = $1
+RETURNING consecutive_failures;
+
+-- name: DisableWebhook :execrows
+UPDATE webhooks SET enabled = false, disabled_at = @now::timestamptz, updated_at = @now
+WHERE id = @id AND enabled;
+
+-- name: InsertWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, attempt, response_status, error, duration_ms, created_at)
+VALUES (@webhook_id, @event_id, @event_type, @payload, @attempt, @response_status, @error, @duration_ms, @created_at)
+RETURNING id;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE id = @id AND webhook_id = @webhook_id;
+
+-- name: ListWebhookDeliveries :many
+-- ListWebhookDeliveries returns the newest attempts first.
+SELECT * FROM webhook_deliveries
+WHERE webhook_id = @webhook_id
+ORDER BY id DESC
+LIMIT @max OFFSET @skip;
+
+-- name: DeleteWebhookDeliveries :execrows
+DELETE FROM webhook_deliveries WHERE created_at < @before;
@@ -0,0 +1,113 @@
+// Package auth authenticates clients by bearer tokens: JWTs signed with
+// HS256 and the configured secret.
+package auth
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrNoToken is returned for requests without a bearer token.
+var ErrNoToken = stderrors.New("auth: no bearer token")
+
+// Claims are the registered claims of a verified token; Subject identifies
+// the client.
+type Claims = jwt.RegisteredClaims
+
+type claimsKey struct{}
+
+// Authenticator verifies the bearer tokens of requests.
+type Authenticator struct {
+	secret func() string
+}
+
+// NewAuthenticator verifies tokens signed with the secret returned by
+// secret. An empty secret disables authentication, as in development.
+func NewAuthenticator(secret func() string) *Authenticator {
+	return &Authenticator{secret: secret}
+}
+
+// Token returns the bearer token of r, from the Authorization header or, for
+// WebSocket upgrades only, the access_token query parameter, which browsers
+// opening a WebSocket have to use as they cannot set headers. Elsewhere the
+// parameter is ignored, so tokens stay out of URLs that end up in proxy logs,
+// browser history and Referer headers.
+func Token(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return token
+	}
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+// Verify returns the claims of a valid token.
+func (a *Authenticator) Verify(token string) (*Claims, error) {
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	secret := a.secret()
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Identify puts the claims of a request with a valid token in its context,
+// and its subject in the logger's, so middleware running before Middleware,
+// such as rate limiting by user, can tell who is calling. Requests without
+// a valid token pass unidentified; it is up to Middleware to reject them.
+func (a *Authenticator) Identify(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.secret() == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if claims, err := a.Verify(Token(r)); err == nil {
+			r = r.WithContext(withClaims(r.Context(), claims))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware rejects requests without a valid token and puts the claims of
+// the others in their context. Every request passes while authentication is
+// disabled.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.secret() == "" || ClaimsFromContext(r.Context()) != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		claims, err := a.Verify(Token(r))
+		if err != nil {
+			logger.FromContext(r.Context()).Debug("authentication failed", "error", err)
+			response.JSONError(w, errors.AuthenticationError(errors.ErrUnauthorized))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+	})
+}
+
+func withClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, claimsKey{}, claims)
+	return logger.WithUserID(ctx, claims.Subject)
+}
+
+// ClaimsFromContext returns the claims put in ctx by Middleware, or nil.
+func ClaimsFromContext(ctx context.Context) *Claims {
+	claims, _ := ctx.Value(claimsKey{}).(*Claims)
+	return claims
+}
@@ -0,0 +1,357 @@
+// Package changefeed streams changes to examples to subscribers in this
+// process. A trigger logs every change and announces it with NOTIFY, so a
+// change made through any replica reaches the subscribers of all of them,
+// and the log lets a subscriber that reconnects catch up on what it missed.
+// Changes are numbered as their transactions commit, so ids follow commit
+// order: the changes after an id are exactly those committed since.
+package changefeed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Channel is the notification channel the examples_log_change trigger
+// announces changes on.
+const Channel = "example_changes"
+
+const (
+	// replayBatch is how many logged changes are read at a time.
+	replayBatch = 500
+	// reconnectMin and reconnectMax bound the wait before listening again
+	// after the connection failed.
+	reconnectMin = time.Second
+	reconnectMax = 30 * time.Second
+)
+
+var (
+	// ErrExpired is returned when resuming after a change that is no longer
+	// in the log, so the changes since then cannot be replayed.
+	ErrExpired = errors.New("changefeed: change is no longer in the log")
+	// ErrFellBehind and ErrClosed tell why the feed ended a subscription.
+	ErrFellBehind = errors.New("changefeed: subscriber fell behind")
+	ErrClosed     = errors.New("changefeed: feed shut down")
+)
+
+// Change is a logged change to an example.
+type Change struct {
+	ID        int64
+	Type      string
+	ExampleID uuid.UUID
+	// Data is the example as JSON, or only its id when it was deleted.
+	Data json.RawMessage
+	// Previous is the example before an update or deletion, nil for
+	// creations.
+	Previous  json.RawMessage
+	CreatedAt time.Time
+}
+
+// ListenFunc calls fn with the payload of every notification on channel
+// until ctx is done or it fails; database.Listen is one.
+type ListenFunc func(ctx context.Context, channel string, listening func(), fn func(payload string)) error
+
+// Options are read for every subscription and stream, so they can change at
+// runtime.
+type Options struct {
+	// Buffer is how many changes a subscriber may fall behind before it is
+	// dropped.
+	Buffer int
+	// Heartbeat is how often a stream with nothing to send writes a comment
+	// and a socket is pinged, keeping proxies from closing them and noticing
+	// clients that went away; 0 sends none.
+	Heartbeat time.Duration
+	// WriteTimeout bounds every write to a stream or socket, and waiting for
+	// a pong, in place of the server's write timeout, which would end them.
+	WriteTimeout time.Duration
+	// Retry is how long stream clients are told to wait before reconnecting;
+	// 0 leaves it to them.
+	Retry time.Duration
+	// MaxSubscriptions is how many filters a socket may subscribe to.
+	MaxSubscriptions int
+	// Origins are the origins besides the server's own that may open a
+	// socket, as host or scheme://host patterns.
+	Origins []string
+}
+
+// Feed fans the changes announced on Channel out to its subscribers. A
+// subscriber that falls behind is dropped rather than holding up the
+// others; it can resume from the log.
+type Feed struct {
+	queries *queries.Queries
+	listen  ListenFunc
+	options func() Options
+
+	subscribed prometheus.Gauge
+	dropped    prometheus.Counter
+
+	mu     sync.Mutex
+	subs   map[*Subscription]struct{}
+	closed bool
+	// active counts the subscriptions not yet closed by their owners.
+	active sync.WaitGroup
+	// last is the newest change broadcast and known whether it was read
+	// from the log yet; both are only used by run.
+	last  int64
+	known bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func New(db queries.DBTX, listen ListenFunc, options func() Options, m *metrics.Metrics) *Feed {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Feed{
+		queries:    database.NewQueries(db),
+		listen:     listen,
+		options:    options,
+		subscribed: m.Gauge("changefeed_subscribers", "Subscribers to the example change feed.").WithLabelValues(),
+		dropped:    m.Counter("changefeed_subscribers_dropped_total", "Subscribers dropped for falling behind the example change feed.").WithLabelValues(),
+		subs:       make(map[*Subscription]struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Options returns the live options.
+func (f *Feed) Options() Options {
+	return f.options()
+}
+
+// Start begins listening for changes in the background.
+func (f *Feed) Start() {
+	if f.started.CompareAndSwap(false, true) {
+		slog.Info("change feed starting", "channel", Channel)
+		go f.run()
+	}
+}
+
+// Shutdown stops listening and ends every subscription, then waits for
+// their owners to close them, so streams and sockets can say goodbye before
+// the server exits.
+func (f *Feed) Shutdown(ctx context.Context) error {
+	f.stopOnce.Do(func() {
+		close(f.stop)
+		f.cancel()
+		f.mu.Lock()
+		f.closed = true
+		for sub := range f.subs {
+			f.drop(sub, ErrClosed)
+		}
+		f.mu.Unlock()
+	})
+
+	closed := make(chan struct{})
+	go func() {
+		f.active.Wait()
+		if f.started.Load() {
+			<-f.done
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscription receives the changes broadcast after it was created.
+type Subscription struct {
+	feed *Feed
+	ch   chan Change
+	// err is set before ch is closed by the feed.
+	err       error
+	closeOnce sync.Once
+}
+
+// Subscribe starts receiving changes. The channel of the subscription is
+// closed when it falls behind or the feed shuts down; Close must be called
+// either way.
+func (f *Feed) Subscribe() *Subscription {
+	sub := &Subscription{feed: f, ch: make(chan Change, max(f.options().Buffer, 1))}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		sub.err = ErrClosed
+		close(sub.ch)
+		sub.closeOnce.Do(func() {})
+		return sub
+	}
+	f.subs[sub] = struct{}{}
+	f.subscribed.Inc()
+	f.active.Add(1)
+	return sub
+}
+
+// Changes returns the channel changes are delivered on.
+func (s *Subscription) Changes() <-chan Change {
+	return s.ch
+}
+
+// Err tells why the feed closed the channel: ErrFellBehind or ErrClosed.
+// It is nil until then.
+func (s *Subscription) Err() error {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.feed.mu.Lock()
+	if _, ok := s.feed.subs[s]; ok {
+		s.feed.drop(s, nil)
+	}
+	s.feed.mu.Unlock()
+	s.closeOnce.Do(s.feed.active.Done)
+}
+
+// drop removes a subscription; f.mu must be held.
+func (f *Feed) drop(sub *Subscription, err error) {
+	delete(f.subs, sub)
+	sub.err = err
+	close(sub.ch)
+	f.subscribed.Dec()
+}
+
+// Since calls fn with every logged change after the change with id after,
+// oldest first. It returns ErrExpired when that change is no longer in the
+// log.
+func (f *Feed) Since(ctx context.Context, after int64, fn func(Change) error) error {
+	if _, err := f.queries.GetExampleChange(ctx, after); errors.Is(err, sql.ErrNoRows) {
+		return ErrExpired
+	} else if err != nil {
+		return err
+	}
+	return f.replay(ctx, after, fn)
+}
+
+func (f *Feed) replay(ctx context.Context, after int64, fn func(Change) error) error {
+	for {
+		rows, err := f.queries.ListExampleChangesAfter(ctx, queries.ListExampleChangesAfterParams{
+			After: after,
+			Max:   replayBatch,
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if err := fn(fromRow(row)); err != nil {
+				return err
+			}
+			after = row.ID
+		}
+		if len(rows) < replayBatch {
+			return nil
+		}
+	}
+}
+
+func (f *Feed) run() {
+	defer close(f.done)
+	wait := reconnectMin
+
+	for {
+		err := f.listen(f.ctx, Channel, func() {
+			f.listening()
+			wait = reconnectMin
+		}, f.notified)
+
+		select {
+		case <-f.stop:
+			return
+		default:
+		}
+		slog.Warn("change feed connection lost, reconnecting", "error", err, "in", wait)
+		select {
+		case <-f.stop:
+			return
+		case <-time.After(wait):
+		}
+		wait = min(wait*2, reconnectMax)
+	}
+}
+
+// listening runs each time the feed is subscribed to Channel. The first time
+// it only notes where the log ends; after a reconnect it broadcasts the
+// changes made while the feed was not listening.
+func (f *Feed) listening() {
+	if !f.known {
+		last, err := f.queries.GetLatestExampleChangeID(f.ctx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("reading the latest example change failed", "error", err)
+			return
+		}
+		f.last = max(f.last, last)
+		f.known = true
+		return
+	}
+	err := f.replay(f.ctx, f.last, func(c Change) error {
+		f.broadcast(c)
+		return nil
+	})
+	if err != nil {
+		slog.Error("catching up on example changes failed", "error", err)
+	}
+}
+
+func (f *Feed) notified(payload string) {
+	id, err := strconv.ParseInt(payload, 10, 64)
+	if err != nil {
+		slog.Warn("ignoring malformed change notification", "payload", payload)
+		return
+	}
+	row, err := f.queries.GetExampleChange(f.ctx, id)
+	if err != nil {
+		// Subscribers miss the change, but can still replay it from the log
+		slog.Error("reading example change failed", "change_id", id, "error", err)
+		return
+	}
+	f.broadcast(fromRow(row))
+}
+
+func (f *Feed) broadcast(c Change) {
+	f.last = max(f.last, c.ID)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for sub := range f.subs {
+		select {
+		case sub.ch <- c:
+		default:
+			f.drop(sub, ErrFellBehind)
+			f.dropped.Inc()
+		}
+	}
+}
+
+func fromRow(row queries.ExampleChange) Change {
+	return Change{
+		ID:        row.ID,
+		Type:      row.Type,
+		ExampleID: row.ExampleID,
+		Data:      row.Data,
+		Previous:  row.Previous.V,
+		CreatedAt: row.CreatedAt,
+	}
+}
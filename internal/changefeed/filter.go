@@ -0,0 +1,85 @@
+package changefeed
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+)
+
+// Match reports whether e is selected by filter.
+func Match(filter models.ExampleFilter, e models.Example) bool {
+	switch {
+	case filter.Name != nil && e.Name != *filter.Name:
+		return false
+	case filter.IsPremium != nil && e.IsPremium != *filter.IsPremium:
+		return false
+	case filter.MinLuckyNumber != nil && e.LuckyNumber < *filter.MinLuckyNumber:
+		return false
+	case filter.MaxLuckyNumber != nil && e.LuckyNumber > *filter.MaxLuckyNumber:
+		return false
+	}
+	return true
+}
+
+// View returns the message a subscriber filtering examples with filter gets
+// for c, and false when c does not concern it.
+func View(filter models.ExampleFilter, c Change) (models.ExampleSocketMessage, bool, error) {
+	msg := models.ExampleSocketMessage{ChangeID: c.ID, ExampleID: &c.ExampleID}
+
+	matchedBefore, err := matches(filter, c.Previous)
+	if err != nil {
+		return msg, false, err
+	}
+	matchesNow := false
+	if c.Type != models.EventExampleDeleted {
+		if matchesNow, err = matches(filter, c.Data); err != nil {
+			return msg, false, err
+		}
+	}
+
+	switch {
+	case matchesNow && !matchedBefore:
+		msg.Type = models.SocketAdded
+		msg.Example = c.Data
+	case matchesNow:
+		msg.Type = models.SocketUpdated
+		if msg.Changes, err = diff(c.Previous, c.Data); err != nil {
+			return msg, false, err
+		}
+	case matchedBefore:
+		msg.Type = models.SocketRemoved
+	default:
+		return msg, false, nil
+	}
+	return msg, true, nil
+}
+
+func matches(filter models.ExampleFilter, data json.RawMessage) (bool, error) {
+	if data == nil {
+		return false, nil
+	}
+	var e models.Example
+	if err := json.Unmarshal(data, &e); err != nil {
+		return false, err
+	}
+	return Match(filter, e), nil
+}
+
+// diff returns the fields of after whose values differ from before.
+func diff(before, after json.RawMessage) (map[string]json.RawMessage, error) {
+	var old, current map[string]json.RawMessage
+	if err := json.Unmarshal(before, &old); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(after, &current); err != nil {
+		return nil, err
+	}
+	changes := make(map[string]json.RawMessage)
+	for field, value := range current {
+		if !bytes.Equal(old[field], value) {
+			changes[field] = value
+		}
+	}
+	return changes, nil
+}
@@ -1,48 +1,628 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
-	"log/slog"
-	"os"
+	"net"
+	"net/url"
 	"strconv"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/graph"
+	"github.com/ctrixcode/go-chi-postgres/internal/idempotency"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/internal/ratelimit"
+	"github.com/ctrixcode/go-chi-postgres/internal/scheduler"
+	"github.com/ctrixcode/go-chi-postgres/internal/secrets"
+	"github.com/ctrixcode/go-chi-postgres/internal/security"
+	"github.com/ctrixcode/go-chi-postgres/internal/webhooks"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/request"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
 )
 
+const (
+	Development = "development"
+	Test        = "test"
+	Production  = "production"
+)
+
+// Config is loaded in layers, each overriding the previous one: defaults for
+// the active profile, the config file, the profile-specific config file,
+// .env, environment variables and finally command line flags.
+//
+// Field tags: yaml is the key used in config files, env the environment
+// variable, secret marks values masked by Redacted, reload marks settings a
+// running server picks up on SIGHUP. Secret fields can also be
+// read from the file named by <ENV>_FILE, and any string setting may hold a
+// "secret:<name>" reference resolved through the configured provider. Flags
+// are derived from the yaml path, e.g. database.host becomes --database-host.
 type Config struct {
-	Port               int
-	DatabaseURL        string
-	JWTSecret          string
-	Environment        string
-	CorsAllowedOrigins string
+	Environment string   `yaml:"-" env:"APP_ENV" validate:"oneof=development test production"`
+	Port        int      `yaml:"port" env:"PORT" validate:"min=1,max=65535"`
+	AdminPort   int      `yaml:"admin_port" env:"ADMIN_PORT" validate:"min=0,max=65535"`
+	AdminToken  string   `yaml:"admin_token" env:"ADMIN_TOKEN" secret:"true"`
+	JWTSecret   string   `yaml:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	Features    []string `yaml:"features" env:"FEATURES" reload:"true"`
+	// APIDocs serves Swagger UI and Redoc under /docs. The OpenAPI document
+	// at /openapi.json is always served.
+	APIDocs bool `yaml:"api_docs" env:"API_DOCS" reload:"true"`
+
+	// DatabaseURL takes precedence over the individual Database settings.
+	DatabaseURL string         `yaml:"database_url" env:"DATABASE_URL" secret:"dsn"`
+	Database    DatabaseConfig `yaml:"database"`
+
+	// ShutdownDrainDelay is how long the server keeps serving after readiness
+	// starts failing, giving load balancers time to stop sending traffic.
+	ShutdownDrainDelay  time.Duration `yaml:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY" validate:"min=0"`
+	MigrationsDir       string        `yaml:"migrations_dir" env:"MIGRATIONS_DIR"`
+	HealthDiskPath      string        `yaml:"health_disk_path" env:"HEALTH_DISK_PATH"`
+	HealthMinFreeDiskMB uint64        `yaml:"health_min_free_disk_mb" env:"HEALTH_MIN_FREE_DISK_MB"`
+
+	ServiceName        string  `yaml:"service_name" env:"OTEL_SERVICE_NAME" validate:"required"`
+	TracingExporter    string  `yaml:"tracing_exporter" env:"TRACING_EXPORTER" validate:"oneof=none stdout otlp"`
+	TracingSampleRatio float64 `yaml:"tracing_sample_ratio" env:"TRACING_SAMPLE_RATIO" validate:"min=0,max=1"`
+
+	Log             LogConfig             `yaml:"log"`
+	Secrets         SecretsConfig         `yaml:"secrets"`
+	CORS            CORSConfig            `yaml:"cors"`
+	SecurityHeaders SecurityHeadersConfig `yaml:"security_headers"`
+	Request         RequestConfig         `yaml:"request"`
+	Response        ResponseConfig        `yaml:"response"`
+	RateLimit       RateLimitConfig       `yaml:"rate_limit"`
+	Idempotency     IdempotencyConfig     `yaml:"idempotency"`
+	OpenAPI         OpenAPIConfig         `yaml:"openapi"`
+	Jobs            JobsConfig            `yaml:"jobs"`
+	Scheduler       SchedulerConfig       `yaml:"scheduler"`
+	Outbox          OutboxConfig          `yaml:"outbox"`
+	Webhooks        WebhooksConfig        `yaml:"webhooks"`
+	Stream          StreamConfig          `yaml:"stream"`
+	GraphQL         GraphQLConfig         `yaml:"graphql"`
+
+	// secretRefs maps config paths to the secret names they were resolved from
+	secretRefs map[string]string
+	// secretFiles maps config paths to the *_FILE paths they were read from
+	secretFiles map[string]string
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" env:"DB_PORT" validate:"min=1,max=65535"`
+	User     string `yaml:"user" env:"DB_USER"`
+	Password string `yaml:"password" env:"DB_PASSWORD" secret:"true"`
+	Name     string `yaml:"name" env:"DB_NAME"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE" validate:"oneof=disable allow prefer require verify-ca verify-full"`
+}
+
+type LogConfig struct {
+	Format           string        `yaml:"format" env:"LOG_FORMAT" validate:"oneof=tint text json"`
+	Level            string        `yaml:"level" env:"LOG_LEVEL" reload:"true"`
+	Output           string        `yaml:"output" env:"LOG_OUTPUT" validate:"required"`
+	MaxSizeMB        int           `yaml:"max_size_mb" env:"LOG_FILE_MAX_SIZE_MB" validate:"min=0"`
+	MaxBackups       int           `yaml:"max_backups" env:"LOG_FILE_MAX_BACKUPS" validate:"min=0"`
+	MaxAgeDays       int           `yaml:"max_age_days" env:"LOG_FILE_MAX_AGE_DAYS" validate:"min=0"`
+	SampleInitial    int           `yaml:"sample_initial" env:"LOG_SAMPLE_INITIAL" validate:"min=0"`
+	SampleThereafter int           `yaml:"sample_thereafter" env:"LOG_SAMPLE_THEREAFTER" validate:"min=0"`
+	SampleWindow     time.Duration `yaml:"sample_window" env:"LOG_SAMPLE_WINDOW" validate:"min=0"`
+}
+
+// SecretsConfig selects the provider used to resolve "secret:" references in
+// other settings.
+type SecretsConfig struct {
+	Provider        string        `yaml:"provider" env:"SECRETS_PROVIDER" validate:"oneof=none file vault"`
+	File            string        `yaml:"file" env:"SECRETS_FILE"`
+	Key             string        `yaml:"key" env:"SECRETS_KEY" secret:"true"`
+	VaultAddr       string        `yaml:"vault_addr" env:"VAULT_ADDR"`
+	VaultToken      string        `yaml:"vault_token" env:"VAULT_TOKEN" secret:"true"`
+	VaultMount      string        `yaml:"vault_mount" env:"VAULT_MOUNT"`
+	RefreshInterval time.Duration `yaml:"refresh_interval" env:"SECRETS_REFRESH_INTERVAL" validate:"min=0"`
+}
+
+// CORSConfig is the default CORS policy. Routes override it for matching
+// paths; settings a route leaves out are inherited. Routes are a list of
+// objects in config files and a JSON array in CORS_ROUTES, e.g.
+// [{"path":"/public/*","allowed_origins":["*"]}].
+type CORSConfig struct {
+	AllowedOrigins   []string            `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" reload:"true"`
+	AllowedMethods   []string            `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS" reload:"true"`
+	AllowedHeaders   []string            `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS" reload:"true"`
+	ExposedHeaders   []string            `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS" reload:"true"`
+	AllowCredentials bool                `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" reload:"true"`
+	MaxAge           time.Duration       `yaml:"max_age" env:"CORS_MAX_AGE" reload:"true" validate:"min=0"`
+	Routes           []security.CORSRule `yaml:"routes" env:"CORS_ROUTES" reload:"true"`
+}
+
+func (c CORSConfig) Options() security.CORSOptions {
+	credentials := c.AllowCredentials
+	return security.CORSOptions{
+		Default: security.CORSRule{
+			AllowedOrigins:   c.AllowedOrigins,
+			AllowedMethods:   c.AllowedMethods,
+			AllowedHeaders:   c.AllowedHeaders,
+			ExposedHeaders:   c.ExposedHeaders,
+			AllowCredentials: &credentials,
+		},
+		Routes: c.Routes,
+		MaxAge: c.MaxAge,
+	}
+}
+
+// SecurityHeadersConfig controls the security headers sent with every
+// response. With report_only the CSP is only reported, never enforced.
+type SecurityHeadersConfig struct {
+	Enabled               bool          `yaml:"enabled" env:"SECURITY_HEADERS_ENABLED" reload:"true"`
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" env:"HSTS_MAX_AGE" reload:"true" validate:"min=0"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" env:"HSTS_INCLUDE_SUBDOMAINS" reload:"true"`
+	HSTSPreload           bool          `yaml:"hsts_preload" env:"HSTS_PRELOAD" reload:"true"`
+	ContentSecurityPolicy string        `yaml:"content_security_policy" env:"CONTENT_SECURITY_POLICY" reload:"true"`
+	ReportOnly            bool          `yaml:"report_only" env:"CSP_REPORT_ONLY" reload:"true"`
+	ReportURI             string        `yaml:"report_uri" env:"CSP_REPORT_URI" reload:"true"`
+	ReferrerPolicy        string        `yaml:"referrer_policy" env:"REFERRER_POLICY" reload:"true"`
+	PermissionsPolicy     string        `yaml:"permissions_policy" env:"PERMISSIONS_POLICY" reload:"true"`
+	FrameOptions          string        `yaml:"frame_options" env:"FRAME_OPTIONS" reload:"true" validate:"omitempty,oneof=DENY SAMEORIGIN"`
+}
+
+func (c SecurityHeadersConfig) Options() security.HeadersOptions {
+	return security.HeadersOptions{
+		Enabled:               c.Enabled,
+		HSTSMaxAge:            c.HSTSMaxAge,
+		HSTSIncludeSubdomains: c.HSTSIncludeSubdomains,
+		HSTSPreload:           c.HSTSPreload,
+		ContentSecurityPolicy: c.ContentSecurityPolicy,
+		ReportOnly:            c.ReportOnly,
+		ReportURI:             c.ReportURI,
+		ReferrerPolicy:        c.ReferrerPolicy,
+		PermissionsPolicy:     c.PermissionsPolicy,
+		FrameOptions:          c.FrameOptions,
+	}
+}
+
+// RequestConfig caps request body sizes. BodyLimits override the default for
+// matching routes and are a JSON array in REQUEST_BODY_LIMITS, e.g.
+// [{"path":"/uploads/*","methods":["POST"],"max_bytes":10485760}]. 0 means
+// unlimited.
+type RequestConfig struct {
+	MaxBodyBytes int64               `yaml:"max_body_bytes" env:"REQUEST_MAX_BODY_BYTES" reload:"true" validate:"min=0"`
+	BodyLimits   []request.BodyLimit `yaml:"body_limits" env:"REQUEST_BODY_LIMITS" reload:"true"`
+}
+
+func (c RequestConfig) Options() request.LimitOptions {
+	return request.LimitOptions{
+		MaxBytes: c.MaxBodyBytes,
+		Routes:   c.BodyLimits,
+	}
+}
+
+// ResponseConfig selects the formats clients may ask for with Accept, in
+// addition to JSON, and how responses are compressed.
+type ResponseConfig struct {
+	Formats              []string `yaml:"formats" env:"RESPONSE_FORMATS" reload:"true" validate:"dive,oneof=json msgpack cbor yaml"`
+	Compression          bool     `yaml:"compression" env:"RESPONSE_COMPRESSION" reload:"true"`
+	CompressionMinSize   int      `yaml:"compression_min_size" env:"RESPONSE_COMPRESSION_MIN_SIZE" reload:"true" validate:"min=0"`
+	CompressionEncodings []string `yaml:"compression_encodings" env:"RESPONSE_COMPRESSION_ENCODINGS" reload:"true" validate:"dive,oneof=zstd br gzip"`
+	CompressibleTypes    []string `yaml:"compressible_types" env:"RESPONSE_COMPRESSIBLE_TYPES" reload:"true"`
+}
+
+func (c ResponseConfig) CompressOptions() response.CompressOptions {
+	return response.CompressOptions{
+		Enabled:   c.Compression,
+		MinSize:   c.CompressionMinSize,
+		Encodings: c.CompressionEncodings,
+		Types:     c.CompressibleTypes,
+	}
+}
+
+// RateLimitConfig controls request rate limiting. Policies are a list of
+// objects in config files and a JSON array in RATE_LIMIT_POLICIES, e.g.
+// [{"name":"examples","path":"/examples*","limit":100,"window":"1m"}].
+// The postgres backend shares limits across replicas.
+type RateLimitConfig struct {
+	Enabled           bool               `yaml:"enabled" env:"RATE_LIMIT_ENABLED" reload:"true"`
+	Backend           string             `yaml:"backend" env:"RATE_LIMIT_BACKEND" validate:"oneof=memory postgres"`
+	TrustForwardedFor bool               `yaml:"trust_forwarded_for" env:"RATE_LIMIT_TRUST_FORWARDED_FOR" reload:"true"`
+	APIKeyHeader      string             `yaml:"api_key_header" env:"RATE_LIMIT_API_KEY_HEADER" reload:"true"`
+	Policies          []ratelimit.Policy `yaml:"policies" env:"RATE_LIMIT_POLICIES" reload:"true"`
+}
+
+func (c RateLimitConfig) Options() ratelimit.Options {
+	return ratelimit.Options{
+		Enabled:           c.Enabled,
+		Policies:          c.Policies,
+		TrustForwardedFor: c.TrustForwardedFor,
+		APIKeyHeader:      c.APIKeyHeader,
+	}
+}
+
+// IdempotencyConfig controls replay of POST requests sent with an
+// Idempotency-Key header.
+type IdempotencyConfig struct {
+	Enabled     bool          `yaml:"enabled" env:"IDEMPOTENCY_ENABLED" reload:"true"`
+	TTL         time.Duration `yaml:"ttl" env:"IDEMPOTENCY_TTL" reload:"true" validate:"min=0"`
+	LockTimeout time.Duration `yaml:"lock_timeout" env:"IDEMPOTENCY_LOCK_TIMEOUT" reload:"true" validate:"min=0"`
+}
+
+func (c IdempotencyConfig) Options() idempotency.Options {
+	return idempotency.Options{
+		Enabled:     c.Enabled,
+		TTL:         c.TTL,
+		LockTimeout: c.LockTimeout,
+	}
+}
+
+// OpenAPIConfig controls validation against the generated OpenAPI document.
+// Response validation is meant for development and tests, where strict turns
+// contract drift into a 500.
+type OpenAPIConfig struct {
+	ValidateRequests  bool   `yaml:"validate_requests" env:"OPENAPI_VALIDATE_REQUESTS" reload:"true"`
+	ValidateResponses string `yaml:"validate_responses" env:"OPENAPI_VALIDATE_RESPONSES" reload:"true" validate:"oneof=off log strict"`
+}
+
+func (c OpenAPIConfig) Options() openapi.ValidatorOptions {
+	return openapi.ValidatorOptions{
+		Requests:  c.ValidateRequests,
+		Responses: c.ValidateResponses,
+	}
+}
+
+// JobsConfig controls the background job worker. Enabled is read at
+// startup; the rest applies from the next poll.
+type JobsConfig struct {
+	Enabled      bool          `yaml:"enabled" env:"JOBS_ENABLED"`
+	Queues       []string      `yaml:"queues" env:"JOBS_QUEUES" reload:"true" validate:"min=1"`
+	Concurrency  int           `yaml:"concurrency" env:"JOBS_CONCURRENCY" reload:"true" validate:"min=1"`
+	PollInterval time.Duration `yaml:"poll_interval" env:"JOBS_POLL_INTERVAL" reload:"true" validate:"min=10ms"`
+	LockTimeout  time.Duration `yaml:"lock_timeout" env:"JOBS_LOCK_TIMEOUT" reload:"true" validate:"min=1s"`
+	BackoffBase  time.Duration `yaml:"backoff_base" env:"JOBS_BACKOFF_BASE" reload:"true" validate:"min=0"`
+	BackoffMax   time.Duration `yaml:"backoff_max" env:"JOBS_BACKOFF_MAX" reload:"true" validate:"min=0"`
+	Retention    time.Duration `yaml:"retention" env:"JOBS_RETENTION" reload:"true" validate:"min=0"`
+}
+
+func (c JobsConfig) Options() jobs.Options {
+	return jobs.Options{
+		Queues:       c.Queues,
+		Concurrency:  c.Concurrency,
+		PollInterval: c.PollInterval,
+		LockTimeout:  c.LockTimeout,
+		BackoffBase:  c.BackoffBase,
+		BackoffMax:   c.BackoffMax,
+		Retention:    c.Retention,
+	}
+}
+
+// SchedulerConfig controls periodic tasks. Every enabled replica competes
+// for the lease and only the holder runs tasks.
+type SchedulerConfig struct {
+	Enabled  bool          `yaml:"enabled" env:"SCHEDULER_ENABLED"`
+	LeaseTTL time.Duration `yaml:"lease_ttl" env:"SCHEDULER_LEASE_TTL" reload:"true" validate:"min=1s"`
+}
+
+func (c SchedulerConfig) Options() scheduler.Options {
+	return scheduler.Options{
+		LeaseTTL: c.LeaseTTL,
+	}
+}
+
+// OutboxConfig controls the relay delivering domain events from the outbox.
+// Events are recorded whether or not it is enabled. Enabled and the sinks,
+// Log and Webhooks, are read at startup; the rest applies from the next
+// batch.
+type OutboxConfig struct {
+	Enabled         bool          `yaml:"enabled" env:"OUTBOX_ENABLED"`
+	PollInterval    time.Duration `yaml:"poll_interval" env:"OUTBOX_POLL_INTERVAL" reload:"true" validate:"min=10ms"`
+	BatchSize       int           `yaml:"batch_size" env:"OUTBOX_BATCH_SIZE" reload:"true" validate:"min=1"`
+	DeliveryTimeout time.Duration `yaml:"delivery_timeout" env:"OUTBOX_DELIVERY_TIMEOUT" reload:"true" validate:"min=1s"`
+	MaxAttempts     int           `yaml:"max_attempts" env:"OUTBOX_MAX_ATTEMPTS" reload:"true" validate:"min=0"`
+	BackoffBase     time.Duration `yaml:"backoff_base" env:"OUTBOX_BACKOFF_BASE" reload:"true" validate:"min=0"`
+	BackoffMax      time.Duration `yaml:"backoff_max" env:"OUTBOX_BACKOFF_MAX" reload:"true" validate:"min=0"`
+	Retention       time.Duration `yaml:"retention" env:"OUTBOX_RETENTION" reload:"true" validate:"min=0"`
+	Log             bool          `yaml:"log" env:"OUTBOX_LOG"`
+	Webhooks        []string      `yaml:"webhooks" env:"OUTBOX_WEBHOOKS" validate:"dive,url"`
+}
+
+func (c OutboxConfig) Options() events.Options {
+	return events.Options{
+		PollInterval:    c.PollInterval,
+		BatchSize:       c.BatchSize,
+		DeliveryTimeout: c.DeliveryTimeout,
+		MaxAttempts:     c.MaxAttempts,
+		BackoffBase:     c.BackoffBase,
+		BackoffMax:      c.BackoffMax,
+	}
+}
+
+// WebhooksConfig controls deliveries to the webhooks managed under
+// /webhooks. They run as jobs, so they need a job worker and take its
+// backoff settings.
+type WebhooksConfig struct {
+	Timeout      time.Duration `yaml:"timeout" env:"WEBHOOKS_TIMEOUT" reload:"true" validate:"min=1s"`
+	MaxAttempts  int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" reload:"true" validate:"min=1"`
+	DisableAfter int           `yaml:"disable_after" env:"WEBHOOKS_DISABLE_AFTER" reload:"true" validate:"min=0"`
+	Retention    time.Duration `yaml:"retention" env:"WEBHOOKS_RETENTION" reload:"true" validate:"min=0"`
+	// AllowPrivateNetworks lets webhooks reach loopback, link-local and
+	// private addresses; never set it where those hold internal services.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" env:"WEBHOOKS_ALLOW_PRIVATE_NETWORKS" reload:"true"`
 }
 
-func LoadConfig() *Config {
-	err := godotenv.Load()
-	if err != nil {
-		slog.Warn("Error loading .env file")
+func (c WebhooksConfig) Options() webhooks.Options {
+	return webhooks.Options{
+		Timeout:              c.Timeout,
+		MaxAttempts:          c.MaxAttempts,
+		DisableAfter:         c.DisableAfter,
+		AllowPrivateNetworks: c.AllowPrivateNetworks,
 	}
+}
+
+// GraphQLConfig controls /graphql.
+type GraphQLConfig struct {
+	// GraphiQL serves an in-browser IDE at /graphql to browsers.
+	GraphiQL      bool `yaml:"graphiql" env:"GRAPHQL_GRAPHIQL" reload:"true"`
+	MaxDepth      int  `yaml:"max_depth" env:"GRAPHQL_MAX_DEPTH" reload:"true" validate:"min=0"`
+	MaxComplexity int  `yaml:"max_complexity" env:"GRAPHQL_MAX_COMPLEXITY" reload:"true" validate:"min=0"`
+}
 
-	port, _ := strconv.Atoi(os.Getenv("PORT"))
-	if port == 0 {
-		port = 8080
+func (c GraphQLConfig) Options() graph.Options {
+	return graph.Options{
+		MaxDepth:      c.MaxDepth,
+		MaxComplexity: c.MaxComplexity,
+		GraphiQL:      c.GraphiQL,
 	}
+}
 
-	dbHost := os.Getenv("DB_HOST")
-	dbPort := os.Getenv("DB_PORT")
-	dbUser := os.Getenv("DB_USER")
-	dbPassword := os.Getenv("DB_PASSWORD")
-	dbName := os.Getenv("DB_NAME")
-	dbSSLMode := os.Getenv("DB_SSLMODE")
+// StreamConfig controls the example change feed behind /examples/stream and
+// /examples/ws. Enabled is read at startup; the rest applies to new streams
+// and sockets.
+type StreamConfig struct {
+	Enabled          bool          `yaml:"enabled" env:"STREAM_ENABLED"`
+	Heartbeat        time.Duration `yaml:"heartbeat" env:"STREAM_HEARTBEAT" reload:"true" validate:"min=1s"`
+	WriteTimeout     time.Duration `yaml:"write_timeout" env:"STREAM_WRITE_TIMEOUT" reload:"true" validate:"min=1s"`
+	Retry            time.Duration `yaml:"retry" env:"STREAM_RETRY" reload:"true" validate:"min=0"`
+	Buffer           int           `yaml:"buffer" env:"STREAM_BUFFER" reload:"true" validate:"min=1"`
+	Retention        time.Duration `yaml:"retention" env:"STREAM_RETENTION" reload:"true" validate:"min=0"`
+	MaxSubscriptions int           `yaml:"max_subscriptions" env:"STREAM_MAX_SUBSCRIPTIONS" reload:"true" validate:"min=1"`
+}
 
-	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=%s",
-		dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode)
+// Options returns the stream options. Sockets accept the CORS origins,
+// which are added by the caller.
+func (c StreamConfig) Options() changefeed.Options {
+	return changefeed.Options{
+		Buffer:           c.Buffer,
+		Heartbeat:        c.Heartbeat,
+		WriteTimeout:     c.WriteTimeout,
+		Retry:            c.Retry,
+		MaxSubscriptions: c.MaxSubscriptions,
+	}
+}
+
+// NewProvider builds the configured secret provider.
+func (c SecretsConfig) NewProvider() (secrets.Provider, error) {
+	switch c.Provider {
+	case "file":
+		key, err := base64.StdEncoding.DecodeString(c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("secrets.key: must be base64: %w", err)
+		}
+		return secrets.NewEncryptedFileProvider(c.File, key)
+	case "vault":
+		return secrets.NewVaultProvider(c.VaultAddr, c.VaultToken, c.VaultMount), nil
+	default:
+		return nil, fmt.Errorf("secrets.provider: no provider configured")
+	}
+}
+
+// FeatureEnabled reports whether a feature flag is switched on.
+func (c *Config) FeatureEnabled(name string) bool {
+	for _, f := range c.Features {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretRef returns the secret name a setting was resolved from, e.g.
+// SecretRef("database.password").
+func (c *Config) SecretRef(path string) (string, bool) {
+	name, ok := c.secretRefs[path]
+	return name, ok
+}
+
+// SecretFile returns the file a setting was read from through its *_FILE
+// variable, e.g. SecretFile("database.password").
+func (c *Config) SecretFile(path string) (string, bool) {
+	file, ok := c.secretFiles[path]
+	return file, ok
+}
+
+func (c LogConfig) Options() logger.Options {
+	return logger.Options{
+		Format:           c.Format,
+		Level:            c.Level,
+		Output:           c.Output,
+		MaxSizeMB:        c.MaxSizeMB,
+		MaxBackups:       c.MaxBackups,
+		MaxAgeDays:       c.MaxAgeDays,
+		SampleInitial:    c.SampleInitial,
+		SampleThereafter: c.SampleThereafter,
+		SampleWindow:     c.SampleWindow,
+	}
+}
+
+// URL builds a connection string from the individual settings.
+func (c DatabaseConfig) URL() string {
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		Path:     "/" + c.Name,
+		RawQuery: url.Values{"sslmode": {c.SSLMode}}.Encode(),
+	}
+	return u.String()
+}
+
+// Defaults returns the baseline configuration for a profile.
+func Defaults(profile string) *Config {
+	cfg := &Config{
+		Environment:        profile,
+		Port:               8080,
+		APIDocs:            true,
+		ShutdownDrainDelay: 5 * time.Second,
+		MigrationsDir:      "./database/migrations",
+		HealthDiskPath:     "/",
+		ServiceName:        "go-chi-postgres",
+		TracingExporter:    "none",
+		TracingSampleRatio: 1,
+		Database: DatabaseConfig{
+			Port:    5432,
+			SSLMode: "disable",
+		},
+		Log: LogConfig{
+			Format:           logger.FormatTint,
+			Level:            "debug",
+			Output:           "stdout",
+			MaxSizeMB:        100,
+			MaxBackups:       5,
+			MaxAgeDays:       28,
+			SampleThereafter: 100,
+			SampleWindow:     time.Second,
+		},
+		Secrets: SecretsConfig{
+			Provider:        "none",
+			VaultMount:      "secret",
+			RefreshInterval: 5 * time.Minute,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:*", "http://127.0.0.1:*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Request-ID", "X-API-Key", "Idempotency-Key"},
+			ExposedHeaders: []string{"Link", "X-Request-ID", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After", "Idempotent-Replayed"},
+			MaxAge:         5 * time.Minute,
+		},
+		// The API serves JSON only, so nothing needs to be loaded or framed
+		SecurityHeaders: SecurityHeadersConfig{
+			Enabled:               true,
+			ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+			ReportOnly:            true,
+			ReferrerPolicy:        "no-referrer",
+			PermissionsPolicy:     "camera=(), microphone=(), geolocation=(), payment=()",
+			FrameOptions:          "DENY",
+		},
+		Request: RequestConfig{
+			MaxBodyBytes: 1 << 20,
+		},
+		Response: ResponseConfig{
+			Formats:              []string{response.FormatJSON, response.FormatMsgPack, response.FormatCBOR, response.FormatYAML},
+			Compression:          true,
+			CompressionMinSize:   1024,
+			CompressionEncodings: []string{response.EncodingZstd, response.EncodingBrotli, response.EncodingGzip},
+			CompressibleTypes:    []string{"application/json", "application/*+json", "application/yaml", "application/msgpack", "application/cbor", "text/*"},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:      true,
+			Backend:      "memory",
+			APIKeyHeader: "X-API-Key",
+			Policies: []ratelimit.Policy{{
+				Name:      "examples",
+				Path:      "/examples*",
+				Limit:     100,
+				Window:    time.Minute,
+				Key:       ratelimit.KeyIP,
+				Algorithm: ratelimit.AlgorithmTokenBucket,
+			}},
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled:     true,
+			TTL:         24 * time.Hour,
+			LockTimeout: time.Minute,
+		},
+		OpenAPI: OpenAPIConfig{
+			ValidateRequests:  true,
+			ValidateResponses: openapi.ResponsesLog,
+		},
+		Jobs: JobsConfig{
+			Enabled:      true,
+			Queues:       []string{jobs.DefaultQueue},
+			Concurrency:  10,
+			PollInterval: time.Second,
+			LockTimeout:  5 * time.Minute,
+			BackoffBase:  time.Second,
+			BackoffMax:   time.Hour,
+			Retention:    24 * time.Hour,
+		},
+		Scheduler: SchedulerConfig{
+			Enabled:  true,
+			LeaseTTL: 30 * time.Second,
+		},
+		Outbox: OutboxConfig{
+			Enabled:         true,
+			PollInterval:    time.Second,
+			BatchSize:       100,
+			DeliveryTimeout: 10 * time.Second,
+			MaxAttempts:     25,
+			BackoffBase:     time.Second,
+			BackoffMax:      time.Hour,
+			Retention:       7 * 24 * time.Hour,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:      10 * time.Second,
+			MaxAttempts:  10,
+			DisableAfter: 50,
+			Retention:    30 * 24 * time.Hour,
+		},
+		Stream: StreamConfig{
+			Enabled:          true,
+			Heartbeat:        15 * time.Second,
+			WriteTimeout:     10 * time.Second,
+			Retry:            3 * time.Second,
+			Buffer:           256,
+			Retention:        24 * time.Hour,
+			MaxSubscriptions: 20,
+		},
+		GraphQL: GraphQLConfig{
+			GraphiQL:      true,
+			MaxDepth:      10,
+			MaxComplexity: 1000,
+		},
+	}
+
+	switch profile {
+	case Test:
+		cfg.Log.Level = "warn"
+		cfg.ShutdownDrainDelay = 0
+		cfg.OpenAPI.ValidateResponses = openapi.ResponsesStrict
+		// Tests run jobs, scheduled tasks, the outbox relay and the change
+		// feed themselves
+		cfg.Jobs.Enabled = false
+		cfg.Scheduler.Enabled = false
+		cfg.Outbox.Enabled = false
+		cfg.Stream.Enabled = false
+	case Production:
+		cfg.APIDocs = false
+		cfg.GraphQL.GraphiQL = false
+		cfg.OpenAPI.ValidateResponses = openapi.ResponsesOff
+		// Origins must be listed explicitly and the CSP enforced
+		cfg.CORS.AllowedOrigins = nil
+		cfg.SecurityHeaders.ReportOnly = false
+		cfg.SecurityHeaders.HSTSMaxAge = 365 * 24 * time.Hour
+		cfg.SecurityHeaders.HSTSIncludeSubdomains = true
+		// Replicas must share limits
+		cfg.RateLimit.Backend = "postgres"
+		// Production log shippers expect JSON
+		cfg.Log.Format = logger.FormatJSON
+		cfg.Log.Level = "info"
+		cfg.Database.SSLMode = "require"
+		cfg.TracingSampleRatio = 0.1
+	}
+
+	return cfg
+}
 
-	return &Config{
-		Port:               port,
-		DatabaseURL:        databaseURL,
-		JWTSecret:          os.Getenv("JWT_SECRET"),
-		Environment:        os.Getenv("APP_ENV"),
-		CorsAllowedOrigins: os.Getenv("CORS_ALLOWED_ORIGINS"),
+// finalize derives computed settings once every layer has been applied and
+// validated.
+func (c *Config) finalize() {
+	if c.DatabaseURL == "" {
+		c.DatabaseURL = c.Database.URL()
 	}
 }
@@ -0,0 +1,438 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/secrets"
+	"github.com/joho/godotenv"
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// field is a leaf setting of Config addressed by its yaml path.
+type field struct {
+	path   string
+	env    string
+	flag   string
+	secret string
+	reload bool
+	value  reflect.Value
+}
+
+func fields(v reflect.Value, prefix string) []field {
+	var out []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		key := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if key == "-" {
+			key = strings.ToLower(sf.Name)
+		}
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		fv := v.Field(i)
+		if sf.Type.Kind() == reflect.Struct && sf.Type != reflect.TypeOf(time.Time{}) {
+			out = append(out, fields(fv, path)...)
+			continue
+		}
+
+		out = append(out, field{
+			path:   path,
+			env:    sf.Tag.Get("env"),
+			flag:   strings.NewReplacer(".", "-", "_", "-").Replace(path),
+			secret: sf.Tag.Get("secret"),
+			reload: sf.Tag.Get("reload") == "true",
+			value:  fv,
+		})
+	}
+	return out
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		// Lists of objects are written as JSON in env vars and flags
+		if v.Type().Elem().Kind() == reflect.Struct {
+			if strings.TrimSpace(s) == "" {
+				v.Set(reflect.Zero(v.Type()))
+				return nil
+			}
+			ptr := reflect.New(v.Type())
+			if err := json.Unmarshal([]byte(s), ptr.Interface()); err != nil {
+				return fmt.Errorf("invalid JSON list: %v", err)
+			}
+			v.Set(ptr.Elem())
+			return nil
+		}
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// Loader collects configuration flags on a FlagSet so commands can add their
+// own flags alongside the configuration overrides.
+type Loader struct {
+	fs         *flag.FlagSet
+	configFile *string
+	envFile    *string
+	overrides  map[string]*string
+
+	// processEnv records variables set before .env was first read, so that
+	// reloads can refresh values from .env without overriding real ones.
+	processEnv map[string]bool
+	files      []string
+}
+
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{
+		fs:         fs,
+		configFile: fs.String("config", "", "path to a YAML or TOML config file (env CONFIG_FILE)"),
+		envFile:    fs.String("env-file", ".env", "path to a .env file"),
+		overrides:  make(map[string]*string),
+	}
+
+	fs.String("env", "", "profile: development, test or production (env APP_ENV)")
+	for _, f := range fields(reflect.ValueOf(Defaults(Development)).Elem(), "") {
+		if f.flag == "environment" {
+			continue
+		}
+		usage := "override " + f.path
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		l.overrides[f.path] = fs.String(f.flag, "", usage)
+	}
+	return l
+}
+
+// Load applies every layer and validates the result. It must be called after
+// the FlagSet has been parsed.
+func (l *Loader) Load() (*Config, error) {
+	set := make(map[string]bool)
+	l.fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var problems []string
+
+	if err := l.loadEnvFile(); err != nil && (set["env-file"] || !errors.Is(err, os.ErrNotExist)) {
+		problems = append(problems, fmt.Sprintf("env file %s: %v", *l.envFile, err))
+	}
+	l.files = []string{*l.envFile}
+
+	profile := os.Getenv("APP_ENV")
+	if set["env"] {
+		profile = l.fs.Lookup("env").Value.String()
+	}
+	if profile == "" {
+		profile = Development
+	}
+
+	cfg := Defaults(profile)
+	all := fields(reflect.ValueOf(cfg).Elem(), "")
+	byPath := make(map[string]field, len(all))
+	for _, f := range all {
+		byPath[f.path] = f
+	}
+
+	configFile := os.Getenv("CONFIG_FILE")
+	if set["config"] {
+		configFile = *l.configFile
+	}
+	if configFile != "" {
+		problems = append(problems, applyFile(byPath, configFile, true)...)
+
+		// config.yaml -> config.production.yaml
+		ext := filepath.Ext(configFile)
+		profileFile := strings.TrimSuffix(configFile, ext) + "." + profile + ext
+		problems = append(problems, applyFile(byPath, profileFile, false)...)
+		l.files = append(l.files, configFile, profileFile)
+	}
+
+	for _, f := range all {
+		if f.env == "" || f.path == "environment" {
+			continue
+		}
+		v, source, err := lookupEnv(f)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", f.path, err))
+			continue
+		}
+		if v == "" {
+			continue
+		}
+		if err := setValue(f.value, v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v (from %s)", f.path, err, source))
+			continue
+		}
+		if file, ok := strings.CutPrefix(source, f.env+"_FILE "); ok {
+			if cfg.secretFiles == nil {
+				cfg.secretFiles = make(map[string]string)
+			}
+			cfg.secretFiles[f.path] = file
+		}
+	}
+
+	for path, v := range l.overrides {
+		f := byPath[path]
+		if !set[f.flag] {
+			continue
+		}
+		if err := setValue(f.value, *v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v (from flag --%s)", path, err, f.flag))
+		}
+		delete(cfg.secretFiles, path)
+	}
+
+	cfg.Environment = profile
+	problems = append(problems, cfg.resolveSecrets(all)...)
+	problems = append(problems, cfg.validate()...)
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, &ValidationError{Problems: problems}
+	}
+
+	cfg.finalize()
+	return cfg, nil
+}
+
+// Files returns the config and .env files read by the last Load, including
+// optional ones that did not exist.
+func (l *Loader) Files() []string {
+	return l.files
+}
+
+// loadEnvFile sets variables from the .env file. Variables present in the
+// process environment before the first load always take precedence.
+func (l *Loader) loadEnvFile() error {
+	if l.processEnv == nil {
+		l.processEnv = make(map[string]bool)
+		for _, kv := range os.Environ() {
+			key, _, _ := strings.Cut(kv, "=")
+			l.processEnv[key] = true
+		}
+	}
+
+	values, err := godotenv.Read(*l.envFile)
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if !l.processEnv[key] {
+			os.Setenv(key, value)
+		}
+	}
+	return nil
+}
+
+// lookupEnv reads a field from the environment. Secret fields may instead be
+// read from the file named by <ENV>_FILE, as used by Docker and Kubernetes
+// secrets.
+func lookupEnv(f field) (string, string, error) {
+	v := os.Getenv(f.env)
+	if f.secret == "" {
+		return v, "env " + f.env, nil
+	}
+
+	fileVar := f.env + "_FILE"
+	path := os.Getenv(fileVar)
+	if path == "" {
+		return v, "env " + f.env, nil
+	}
+	if v != "" {
+		return "", "", fmt.Errorf("both %s and %s are set", f.env, fileVar)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("%s: %v", fileVar, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), fileVar + " " + path, nil
+}
+
+// resolveSecrets replaces "secret:" references with values from the
+// configured provider.
+func (c *Config) resolveSecrets(all []field) []string {
+	var refs []field
+	for _, f := range all {
+		if f.value.Kind() != reflect.String {
+			continue
+		}
+		if _, ok := secrets.IsReference(f.value.String()); ok {
+			refs = append(refs, f)
+		}
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	provider, err := c.Secrets.NewProvider()
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var problems []string
+	c.secretRefs = make(map[string]string)
+	for _, f := range refs {
+		name, _ := secrets.IsReference(f.value.String())
+		value, err := provider.GetSecret(ctx, name)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: resolve %s: %v", f.path, name, err))
+			continue
+		}
+		f.value.SetString(value)
+		c.secretRefs[f.path] = name
+	}
+	return problems
+}
+
+func applyFile(byPath map[string]field, path string, required bool) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return []string{fmt.Sprintf("config file %s: %v", path, err)}
+	}
+
+	raw := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		err = toml.Unmarshal(data, &raw)
+	default:
+		err = fmt.Errorf("unsupported format, use .yaml, .yml or .toml")
+	}
+	if err != nil {
+		return []string{fmt.Sprintf("config file %s: %v", path, err)}
+	}
+
+	var problems []string
+	for key, value := range flatten(raw, "") {
+		f, ok := byPath[key]
+		if !ok || key == "environment" {
+			problems = append(problems, fmt.Sprintf("%s: unknown key in config file %s", key, path))
+			continue
+		}
+		if err := setValue(f.value, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v (from %s)", key, err, path))
+		}
+	}
+	return problems
+}
+
+func flatten(m map[string]interface{}, prefix string) map[string]string {
+	out := make(map[string]string)
+	for k, v := range m {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for nk, nv := range flatten(val, key) {
+				out[nk] = nv
+			}
+		case []interface{}:
+			if containsObjects(val) {
+				data, _ := json.Marshal(val)
+				out[key] = string(data)
+				continue
+			}
+			items := make([]string, len(val))
+			for i, item := range val {
+				items[i] = fmt.Sprint(item)
+			}
+			out[key] = strings.Join(items, ",")
+		default:
+			out[key] = fmt.Sprint(val)
+		}
+	}
+	return out
+}
+
+func containsObjects(items []interface{}) bool {
+	for _, item := range items {
+		if _, ok := item.(map[string]interface{}); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Load parses args as configuration flags and loads the configuration.
+func Load(args []string) (*Config, error) {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	loader := NewLoader(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return loader.Load()
+}
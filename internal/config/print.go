@@ -0,0 +1,36 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "[REDACTED]"
+
+// Redacted returns a copy of the config with secrets masked.
+func (c *Config) Redacted() *Config {
+	clone := *c
+	for _, f := range fields(reflect.ValueOf(&clone).Elem(), "") {
+		if f.value.Kind() != reflect.String || f.value.String() == "" {
+			continue
+		}
+		switch f.secret {
+		case "true":
+			f.value.SetString(redacted)
+		case "dsn":
+			f.value.SetString(logger.RedactString(f.value.String()))
+		}
+	}
+	return &clone
+}
+
+// YAML renders the config in the same format accepted by config files.
+func (c *Config) YAML() ([]byte, error) {
+	type printable struct {
+		Environment string `yaml:"environment"`
+		*Config     `yaml:",inline"`
+	}
+	return yaml.Marshal(printable{Environment: c.Environment, Config: c})
+}
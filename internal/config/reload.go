@@ -0,0 +1,73 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Manager holds the active configuration and swaps in reloadable settings
+// atomically, so readers never observe a partially applied reload.
+type Manager struct {
+	mu          sync.Mutex
+	current     atomic.Pointer[Config]
+	subscribers []func(old, new *Config)
+}
+
+func NewManager(cfg *Config) *Manager {
+	m := &Manager{}
+	m.current.Store(cfg)
+	return m
+}
+
+func (m *Manager) Current() *Config {
+	return m.current.Load()
+}
+
+// Subscribe registers fn to be called after every reload that changed at
+// least one setting.
+func (m *Manager) Subscribe(fn func(old, new *Config)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subscribers = append(m.subscribers, fn)
+}
+
+// Apply copies the reloadable settings of next into the active config.
+// Changed settings that require a restart are left untouched, logged and
+// returned in ignored.
+func (m *Manager) Apply(next *Config) (changed, ignored []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.Current()
+	merged := *old
+
+	oldFields := fields(reflect.ValueOf(old).Elem(), "")
+	nextFields := fields(reflect.ValueOf(next).Elem(), "")
+	mergedFields := fields(reflect.ValueOf(&merged).Elem(), "")
+
+	for i, f := range oldFields {
+		if reflect.DeepEqual(f.value.Interface(), nextFields[i].value.Interface()) {
+			continue
+		}
+		if !f.reload {
+			ignored = append(ignored, f.path)
+			slog.Warn("config setting changed but requires a restart, ignoring", "setting", f.path)
+			continue
+		}
+		mergedFields[i].value.Set(nextFields[i].value)
+		changed = append(changed, f.path)
+		slog.Info("config setting reloaded", "setting", f.path)
+	}
+
+	if len(changed) == 0 {
+		return changed, ignored
+	}
+
+	m.current.Store(&merged)
+	for _, fn := range m.subscribers {
+		fn(old, &merged)
+	}
+	return changed, ignored
+}
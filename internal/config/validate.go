@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/security"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationError lists every problem found while loading the configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+func (c *Config) validate() []string {
+	var problems []string
+
+	paths := make(map[string]field)
+	for _, f := range fields(reflect.ValueOf(c).Elem(), "") {
+		paths[f.path] = f
+	}
+
+	err := validator.New().Struct(c)
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		for _, fe := range verrs {
+			// Config.Database.Port -> database.port
+			path := yamlPath(reflect.TypeOf(*c), strings.Split(fe.StructNamespace(), ".")[1:])
+			msg := fmt.Sprintf("%s: failed %q", path, fe.Tag())
+			if fe.Param() != "" {
+				msg = fmt.Sprintf("%s: must satisfy %s=%s", path, fe.Tag(), fe.Param())
+			}
+			if f, ok := paths[path]; ok && f.secret == "" {
+				msg += fmt.Sprintf(" (got %v)", fe.Value())
+			}
+			problems = append(problems, msg)
+		}
+	}
+
+	if c.DatabaseURL == "" {
+		if c.Database.Host == "" {
+			problems = append(problems, "database.host: required unless database_url (DATABASE_URL) is set")
+		}
+		if c.Database.User == "" {
+			problems = append(problems, "database.user: required unless database_url (DATABASE_URL) is set")
+		}
+		if c.Database.Name == "" {
+			problems = append(problems, "database.name: required unless database_url (DATABASE_URL) is set")
+		}
+	} else if u, err := url.Parse(c.DatabaseURL); err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		problems = append(problems, "database_url: must be a postgres:// URL")
+	}
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		problems = append(problems, fmt.Sprintf("log.level: %v", err))
+	}
+	for _, rule := range append([]security.CORSRule{c.CORS.Options().Default}, c.CORS.Routes...) {
+		credentials := rule.AllowCredentials != nil && *rule.AllowCredentials
+		for _, origin := range rule.AllowedOrigins {
+			if origin == "*" && credentials {
+				problems = append(problems, "cors: allowing credentials from any origin (*) is not permitted")
+			}
+		}
+	}
+	for _, rule := range c.CORS.Routes {
+		if rule.Path == "" {
+			problems = append(problems, "cors.routes: path is required")
+		}
+	}
+
+	for _, l := range c.Request.BodyLimits {
+		if l.Path == "" || l.MaxBytes < 0 {
+			problems = append(problems, fmt.Sprintf("request.body_limits: path is required and max_bytes must not be negative (got %q, %d)", l.Path, l.MaxBytes))
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, p := range c.RateLimit.Policies {
+		if err := p.Validate(); err != nil {
+			problems = append(problems, fmt.Sprintf("rate_limit.policies: %v", err))
+		} else if names[p.Name] {
+			problems = append(problems, fmt.Sprintf("rate_limit.policies: duplicate policy %q", p.Name))
+		}
+		names[p.Name] = true
+	}
+	if c.AdminPort != 0 && c.AdminPort == c.Port {
+		problems = append(problems, "admin_port: must differ from port")
+	}
+
+	if c.Environment == Production {
+		if c.JWTSecret == "" {
+			problems = append(problems, "jwt_secret: required in production")
+		}
+		if len(c.CORS.AllowedOrigins) == 0 {
+			problems = append(problems, "cors.allowed_origins: required in production")
+		}
+		if c.AdminToken == "" && c.AdminPort == 0 {
+			problems = append(problems, "admin_token: required in production when admin_port is unset")
+		}
+		if c.Webhooks.AllowPrivateNetworks {
+			problems = append(problems, "webhooks.allow_private_networks: not allowed in production")
+		}
+	}
+
+	return problems
+}
+
+func yamlPath(t reflect.Type, names []string) string {
+	var parts []string
+	for _, name := range names {
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			parts = append(parts, strings.ToLower(name))
+			continue
+		}
+		key := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+		if key == "-" || key == "" {
+			key = strings.ToLower(sf.Name)
+		}
+		parts = append(parts, key)
+		t = sf.Type
+	}
+	return strings.Join(parts, ".")
+}
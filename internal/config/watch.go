@@ -0,0 +1,77 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const watchDebounce = 500 * time.Millisecond
+
+// Watch calls onChange whenever one of files is written, created or replaced,
+// until ctx is cancelled. Parent directories are watched so files replaced by
+// editors or Kubernetes ConfigMap symlink swaps are noticed. Bursts of events
+// are coalesced into one call.
+func Watch(ctx context.Context, files []string, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	targets := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			continue
+		}
+		targets[abs] = true
+		dirs[filepath.Dir(abs)] = true
+	}
+	for dir := range dirs {
+		if err := watcher.Add(dir); err != nil {
+			slog.Warn("cannot watch config directory", "dir", dir, "error", err)
+		}
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		var timer *time.Timer
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !targets[filepath.Clean(event.Name)] && !isConfigMapSwap(event.Name) {
+					continue
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(watchDebounce, onChange)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("config watcher error", "error", err)
+			}
+		}
+	}()
+	return nil
+}
+
+// isConfigMapSwap detects the "..data" symlink Kubernetes swaps when a
+// mounted ConfigMap or Secret is updated.
+func isConfigMapSwap(name string) bool {
+	return filepath.Base(name) == "..data"
+}
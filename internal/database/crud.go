@@ -0,0 +1,276 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+)
+
+var psql = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
+
+// ErrNotFound is returned when no row has the given key.
+var ErrNotFound = errors.New("record not found")
+
+// Query narrows and pages List. Filter is any squirrel condition, e.g.
+// squirrel.Eq{"is_premium": true}; its column names are written into the
+// SQL as is and must never come from user input.
+type Query struct {
+	Filter  squirrel.Sqlizer
+	OrderBy []string
+	Limit   uint64
+	Offset  uint64
+}
+
+// CRUDRepository implements the common queries for a table whose rows scan
+// into T. Columns come from the db tags of T; the key column is the field
+// tagged with the pk option, or "id".
+type CRUDRepository[T any, ID comparable] struct {
+	db      sqlx.ExtContext
+	table   string
+	columns []string
+	key     string
+	// touch is set when the table has an updated_at column
+	touch bool
+}
+
+func NewCRUDRepository[T any, ID comparable](db sqlx.ExtContext, table string) *CRUDRepository[T, ID] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("database: CRUDRepository needs a struct type, got %s", t))
+	}
+	columns, key := tableColumns(t)
+	if key == "" {
+		key = "id"
+	}
+	if !slices.Contains(columns, key) {
+		panic(fmt.Sprintf("database: %s has no %q column", t, key))
+	}
+
+	r := &CRUDRepository[T, ID]{db: db, table: table, columns: columns, key: key}
+	for _, c := range columns {
+		if c == "updated_at" {
+			r.touch = true
+		}
+	}
+	return r
+}
+
+// WithDB returns a copy of the repository running its queries on db,
+// usually a transaction.
+func (r *CRUDRepository[T, ID]) WithDB(db sqlx.ExtContext) *CRUDRepository[T, ID] {
+	c := *r
+	c.db = db
+	return &c
+}
+
+func (r *CRUDRepository[T, ID]) Table() string {
+	return r.table
+}
+
+func (r *CRUDRepository[T, ID]) Columns() []string {
+	return r.columns
+}
+
+// Create inserts a row with the given column values and returns it as
+// stored, including generated columns.
+func (r *CRUDRepository[T, ID]) Create(ctx context.Context, values map[string]interface{}) (*T, error) {
+	columns, args, err := r.ordered(values)
+	if err != nil {
+		return nil, err
+	}
+
+	query := psql.Insert(r.table).
+		Columns(columns...).
+		Values(args...).
+		Suffix("RETURNING " + strings.Join(r.columns, ", "))
+	return r.get(ctx, query)
+}
+
+func (r *CRUDRepository[T, ID]) GetByID(ctx context.Context, id ID) (*T, error) {
+	return r.get(ctx, r.selectBuilder().Where(squirrel.Eq{r.key: id}))
+}
+
+func (r *CRUDRepository[T, ID]) List(ctx context.Context, q Query) ([]T, error) {
+	query := r.selectBuilder()
+	if q.Filter != nil {
+		query = query.Where(q.Filter)
+	}
+	if len(q.OrderBy) > 0 {
+		query = query.OrderBy(q.OrderBy...)
+	}
+	if q.Limit > 0 {
+		query = query.Limit(q.Limit)
+	}
+	if q.Offset > 0 || q.Limit > 0 {
+		query = query.Offset(q.Offset)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	// An empty page is encoded as [] rather than null
+	rows := []T{}
+	if err := selectContext(ctx, conn(ctx, r.db), &rows, sql, args...); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Update sets the given columns and returns the updated row. updated_at is
+// set to the current time when the table has it.
+func (r *CRUDRepository[T, ID]) Update(ctx context.Context, id ID, values map[string]interface{}) (*T, error) {
+	query := psql.Update(r.table).Where(squirrel.Eq{r.key: id})
+	if r.touch {
+		if _, ok := values["updated_at"]; !ok {
+			query = query.Set("updated_at", time.Now())
+		}
+	}
+
+	columns, args, err := r.ordered(values)
+	if err != nil {
+		return nil, err
+	}
+	for i, c := range columns {
+		query = query.Set(c, args[i])
+	}
+	if len(columns) == 0 && !r.touch {
+		return r.GetByID(ctx, id)
+	}
+
+	return r.get(ctx, query.Suffix("RETURNING "+strings.Join(r.columns, ", ")))
+}
+
+func (r *CRUDRepository[T, ID]) Delete(ctx context.Context, id ID) error {
+	sql, args, err := psql.Delete(r.table).Where(squirrel.Eq{r.key: id}).ToSql()
+	if err != nil {
+		return err
+	}
+
+	result, err := execContext(ctx, conn(ctx, r.db), sql, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s %v: %w", r.table, id, ErrNotFound)
+	}
+	return nil
+}
+
+// Count returns the number of rows matching filter, all rows when nil.
+func (r *CRUDRepository[T, ID]) Count(ctx context.Context, filter squirrel.Sqlizer) (uint64, error) {
+	query := psql.Select("COUNT(*)").From(r.table)
+	if filter != nil {
+		query = query.Where(filter)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var n uint64
+	err = getContext(ctx, conn(ctx, r.db), &n, sql, args...)
+	return n, err
+}
+
+// Exists reports whether any row matches filter.
+func (r *CRUDRepository[T, ID]) Exists(ctx context.Context, filter squirrel.Sqlizer) (bool, error) {
+	query := psql.Select("1").From(r.table).Prefix("SELECT EXISTS(").Suffix(")")
+	if filter != nil {
+		query = query.Where(filter)
+	}
+
+	sql, args, err := query.ToSql()
+	if err != nil {
+		return false, err
+	}
+
+	var exists bool
+	err = getContext(ctx, conn(ctx, r.db), &exists, sql, args...)
+	return exists, err
+}
+
+func (r *CRUDRepository[T, ID]) selectBuilder() squirrel.SelectBuilder {
+	return psql.Select(r.columns...).From(r.table)
+}
+
+func (r *CRUDRepository[T, ID]) get(ctx context.Context, query squirrel.Sqlizer) (*T, error) {
+	stmt, args, err := query.ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	var row T
+	if err := getContext(ctx, conn(ctx, r.db), &row, stmt, args...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", r.table, ErrNotFound)
+		}
+		return nil, err
+	}
+	return &row, nil
+}
+
+// ordered returns values in the column order of T so statements, and the
+// position of their arguments, do not depend on map iteration.
+func (r *CRUDRepository[T, ID]) ordered(values map[string]interface{}) ([]string, []interface{}, error) {
+	columns := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values))
+	for _, c := range r.columns {
+		if v, ok := values[c]; ok {
+			columns = append(columns, c)
+			args = append(args, v)
+		}
+	}
+	if len(columns) != len(values) {
+		for c := range values {
+			if !slices.Contains(r.columns, c) {
+				return nil, nil, fmt.Errorf("%s: unknown column %q", r.table, c)
+			}
+		}
+	}
+	return columns, args, nil
+}
+
+// tableColumns lists the db tags of a struct, including embedded structs,
+// in field order and returns the column tagged pk, if any.
+func tableColumns(t reflect.Type) (columns []string, key string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, opts, _ := strings.Cut(f.Tag.Get("db"), ",")
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			embedded, embeddedKey := tableColumns(f.Type)
+			columns = append(columns, embedded...)
+			if key == "" {
+				key = embeddedKey
+			}
+			continue
+		}
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		if tag == "" {
+			// sqlx maps untagged fields to their lower case name
+			tag = strings.ToLower(f.Name)
+		}
+		columns = append(columns, tag)
+		if slices.Contains(strings.Split(opts, ","), "pk") {
+			key = tag
+		}
+	}
+	return columns, key
+}
@@ -6,7 +6,8 @@ import (
 	"log/slog"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -24,14 +25,49 @@ var (
 	dbInstance *service
 )
 
-func New(connectionString string) Service {
+type options struct {
+	password func() string
+}
+
+type Option func(*options)
+
+// WithPassword makes every new connection use the password returned by fn
+// instead of the one in the connection string, so rotated credentials are
+// picked up without a restart. Existing connections are unaffected.
+func WithPassword(fn func() string) Option {
+	return func(o *options) {
+		o.password = fn
+	}
+}
+
+func New(connectionString string, opts ...Option) Service {
 	// Reuse Connection
 	if dbInstance != nil {
 		return dbInstance
 	}
 
-	db, err := sqlx.Connect("pgx", connectionString)
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	connConfig, err := pgx.ParseConfig(connectionString)
 	if err != nil {
+		slog.Error("invalid database connection string", "error", err)
+		panic(err)
+	}
+
+	sqlDB := stdlib.OpenDB(*connConfig, stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+		if o.password != nil {
+			cc.Password = o.password()
+		}
+		return nil
+	}))
+	db := sqlx.NewDb(sqlDB, "pgx")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		// It's better to return error here, but for now we keep the panic behavior to match previous logic
 		panic(err)
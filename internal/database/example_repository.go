@@ -2,10 +2,13 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-	"time"
+	"slices"
 
 	"github.com/Masterminds/squirrel"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
 	"github.com/ctrixcode/go-chi-postgres/internal/models"
 	"github.com/ctrixcode/go-chi-postgres/internal/repository"
 	"github.com/google/uuid"
@@ -13,119 +16,116 @@ import (
 )
 
 type exampleRepository struct {
-	db *sqlx.DB
+	crud *CRUDRepository[models.Example, uuid.UUID]
+	// queries are generated from database/queries/examples.sql
+	queries *queries.Queries
 }
 
 func NewExampleRepository(db *sqlx.DB) repository.ExampleRepository {
-	return &exampleRepository{db: db}
+	return &exampleRepository{
+		crud:    NewCRUDRepository[models.Example, uuid.UUID](db, "examples"),
+		queries: NewQueries(db),
+	}
 }
 
-var psql = squirrel.StatementBuilder.PlaceholderFormat(squirrel.Dollar)
-
 func (r *exampleRepository) Create(ctx context.Context, req models.CreateExampleRequest) (*models.Example, error) {
-	query := psql.Insert("examples").
-		Columns("name", "lucky_number", "is_premium").
-		Values(req.Name, req.LuckyNumber, req.IsPremium).
-		Suffix("RETURNING id, name, lucky_number, is_premium, created_at, updated_at")
-
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
-	}
-
-	var example models.Example
-	err = r.db.GetContext(ctx, &example, sql, args...)
-	if err != nil {
-		return nil, err
-	}
-
-	return &example, nil
+	return r.crud.Create(ctx, map[string]interface{}{
+		"name":         req.Name,
+		"lucky_number": req.LuckyNumber,
+		"is_premium":   req.IsPremium,
+	})
 }
 
 func (r *exampleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Example, error) {
-	query := psql.Select("*").From("examples").Where(squirrel.Eq{"id": id})
-
-	sql, args, err := query.ToSql()
+	row, err := r.queries.GetExample(ctx, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("examples: %w", ErrNotFound)
+		}
 		return nil, err
 	}
+	example := exampleFromRow(row)
+	return &example, nil
+}
 
-	var example models.Example
-	err = r.db.GetContext(ctx, &example, sql, args...)
-	if err != nil {
-		return nil, err
+// exampleFromRow converts a generated row to the model. The timestamps are
+// nullable in the schema but always set by their defaults.
+func exampleFromRow(row queries.Example) models.Example {
+	return models.Example{
+		ID:          row.ID,
+		Name:        row.Name,
+		LuckyNumber: row.LuckyNumber,
+		IsPremium:   row.IsPremium,
+		CreatedAt:   row.CreatedAt.V,
+		UpdatedAt:   row.UpdatedAt.V,
 	}
-
-	return &example, nil
 }
 
 func (r *exampleRepository) List(ctx context.Context, limit, offset uint64) ([]models.Example, error) {
-	query := psql.Select("*").From("examples").Limit(limit).Offset(offset)
+	return r.crud.List(ctx, Query{Limit: limit, Offset: offset})
+}
 
-	sql, args, err := query.ToSql()
-	if err != nil {
-		return nil, err
+func (r *exampleRepository) Search(ctx context.Context, q models.ExampleQuery) ([]models.Example, error) {
+	sort := q.Sort
+	if sort == "" {
+		sort = "created_at"
+	} else if !slices.Contains(models.ExampleSortColumns, sort) {
+		return nil, fmt.Errorf("examples: cannot sort by %q", sort)
+	}
+	if q.Desc {
+		sort += " DESC"
 	}
 
-	var examples []models.Example
-	err = r.db.SelectContext(ctx, &examples, sql, args...)
-	if err != nil {
-		return nil, err
+	var filter squirrel.And
+	if q.Filter.Name != nil {
+		filter = append(filter, squirrel.Eq{"name": *q.Filter.Name})
+	}
+	if q.Filter.IsPremium != nil {
+		filter = append(filter, squirrel.Eq{"is_premium": *q.Filter.IsPremium})
+	}
+	if q.Filter.MinLuckyNumber != nil {
+		filter = append(filter, squirrel.GtOrEq{"lucky_number": *q.Filter.MinLuckyNumber})
+	}
+	if q.Filter.MaxLuckyNumber != nil {
+		filter = append(filter, squirrel.LtOrEq{"lucky_number": *q.Filter.MaxLuckyNumber})
 	}
 
-	return examples, nil
+	// id breaks ties so pages do not overlap
+	query := Query{OrderBy: []string{sort, "id"}, Limit: q.Limit, Offset: q.Offset}
+	if len(filter) > 0 {
+		query.Filter = filter
+	}
+	return r.crud.List(ctx, query)
 }
 
-func (r *exampleRepository) Update(ctx context.Context, id uuid.UUID, req models.UpdateExampleRequest) (*models.Example, error) {
-	updateBuilder := psql.Update("examples").Where(squirrel.Eq{"id": id}).Set("updated_at", time.Now())
+func (r *exampleRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Example, error) {
+	if len(ids) == 0 {
+		return []models.Example{}, nil
+	}
+	return r.crud.List(ctx, Query{Filter: squirrel.Eq{"id": ids}})
+}
 
+func (r *exampleRepository) Update(ctx context.Context, id uuid.UUID, req models.UpdateExampleRequest) (*models.Example, error) {
+	values := make(map[string]interface{})
 	if req.Name != nil {
-		updateBuilder = updateBuilder.Set("name", *req.Name)
+		values["name"] = *req.Name
 	}
 	if req.LuckyNumber != nil {
-		updateBuilder = updateBuilder.Set("lucky_number", *req.LuckyNumber)
+		values["lucky_number"] = *req.LuckyNumber
 	}
 	if req.IsPremium != nil {
-		updateBuilder = updateBuilder.Set("is_premium", *req.IsPremium)
-	}
-
-	updateBuilder = updateBuilder.Suffix("RETURNING id, name, lucky_number, is_premium, created_at, updated_at")
-
-	sql, args, err := updateBuilder.ToSql()
-	if err != nil {
-		return nil, err
-	}
-
-	var example models.Example
-	err = r.db.GetContext(ctx, &example, sql, args...)
-	if err != nil {
-		return nil, err
+		values["is_premium"] = *req.IsPremium
 	}
-
-	return &example, nil
+	return r.crud.Update(ctx, id, values)
 }
 
 func (r *exampleRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := psql.Delete("examples").Where(squirrel.Eq{"id": id})
-
-	sql, args, err := query.ToSql()
+	deleted, err := r.queries.DeleteExample(ctx, id)
 	if err != nil {
 		return err
 	}
-
-	result, err := r.db.ExecContext(ctx, sql, args...)
-	if err != nil {
-		return err
+	if deleted == 0 {
+		return fmt.Errorf("examples %v: %w", id, ErrNotFound)
 	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-
-	if rowsAffected == 0 {
-		return fmt.Errorf("example not found")
-	}
-
 	return nil
 }
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// unlistenTimeout bounds clearing the subscriptions of a listening
+// connection before it goes back to the pool.
+const unlistenTimeout = 5 * time.Second
+
+// Listen subscribes to a notification channel on a connection of its own
+// and calls fn with the payload of every notification, in the order they
+// were sent, until ctx is done or the connection fails. listening is called
+// once the subscription is in place; notifications sent after that are not
+// missed.
+func Listen(ctx context.Context, db *sqlx.DB, channel string, listening func(), fn func(payload string)) error {
+	c, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	return c.Raw(func(driverConn any) error {
+		sc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("listen: unsupported connection type %T", driverConn)
+		}
+		conn := sc.Conn()
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+		listening()
+
+		for {
+			n, err := conn.WaitForNotification(ctx)
+			if err != nil {
+				// A connection still subscribed must not go back to the pool
+				uctx, cancel := context.WithTimeout(context.Background(), unlistenTimeout)
+				defer cancel()
+				if _, uerr := conn.Exec(uctx, "UNLISTEN *"); uerr != nil {
+					return errors.Join(err, driver.ErrBadConn)
+				}
+				return err
+			}
+			fn(n.Payload)
+		}
+	})
+}
@@ -0,0 +1,76 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is satisfied by *sql.DB, *sql.Tx, *sqlx.DB and *sqlx.Tx.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Conn runs the statements of the queries. Wrappers implement it rather than
+// DBTX to see the rows as they are read, e.g. to trace reading them.
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) Row
+}
+
+// Row is a row being read, as *sql.Row.
+type Row interface {
+	Scan(dest ...interface{}) error
+}
+
+// Rows are rows being read, as *sql.Rows.
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Close() error
+	Err() error
+}
+
+// Queries runs the queries in database/queries.
+type Queries struct {
+	db Conn
+}
+
+func New(db DBTX) *Queries {
+	return &Queries{db: conn{db: db}}
+}
+
+// NewConn returns queries running on c.
+func NewConn(c Conn) *Queries {
+	return &Queries{db: c}
+}
+
+// WithTx returns a copy of q running its queries on tx.
+func (q *Queries) WithTx(tx DBTX) *Queries {
+	return New(tx)
+}
+
+// conn runs the statements on a DBTX.
+type conn struct {
+	db DBTX
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	return c.db.QueryRowContext(ctx, query, args...)
+}
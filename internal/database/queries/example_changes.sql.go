@@ -0,0 +1,66 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"context"
+	"time"
+)
+
+const getExampleChange = `SELECT id, type, example_id, data, created_at, previous FROM example_changes WHERE id = $1`
+
+func (q *Queries) GetExampleChange(ctx context.Context, id int64) (ExampleChange, error) {
+	row := q.db.QueryRowContext(ctx, getExampleChange, id)
+	var i ExampleChange
+	err := row.Scan(&i.ID, &i.Type, &i.ExampleID, &i.Data, &i.CreatedAt, &i.Previous)
+	return i, err
+}
+
+const getLatestExampleChangeID = `SELECT id FROM example_changes ORDER BY id DESC LIMIT 1`
+
+func (q *Queries) GetLatestExampleChangeID(ctx context.Context) (int64, error) {
+	row := q.db.QueryRowContext(ctx, getLatestExampleChangeID)
+	var i int64
+	err := row.Scan(&i)
+	return i, err
+}
+
+const listExampleChangesAfter = `SELECT id, type, example_id, data, created_at, previous FROM example_changes
+WHERE id > $1
+ORDER BY id
+LIMIT $2`
+
+type ListExampleChangesAfterParams struct {
+	After int64
+	Max   int64
+}
+
+func (q *Queries) ListExampleChangesAfter(ctx context.Context, arg ListExampleChangesAfterParams) ([]ExampleChange, error) {
+	rows, err := q.db.QueryContext(ctx, listExampleChangesAfter, arg.After, arg.Max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ExampleChange{}
+	for rows.Next() {
+		var i ExampleChange
+		if err := rows.Scan(&i.ID, &i.Type, &i.ExampleID, &i.Data, &i.CreatedAt, &i.Previous); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteExampleChanges = `DELETE FROM example_changes WHERE created_at < $1`
+
+func (q *Queries) DeleteExampleChanges(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExampleChanges, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,29 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const getExample = `SELECT id, name, lucky_number, is_premium, created_at, updated_at FROM examples WHERE id = $1`
+
+// GetExample returns the example with the given id, or sql.ErrNoRows.
+func (q *Queries) GetExample(ctx context.Context, id uuid.UUID) (Example, error) {
+	row := q.db.QueryRowContext(ctx, getExample, id)
+	var i Example
+	err := row.Scan(&i.ID, &i.Name, &i.LuckyNumber, &i.IsPremium, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
+const deleteExample = `DELETE FROM examples WHERE id = $1`
+
+func (q *Queries) DeleteExample(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExample, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,166 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const insertJob = `INSERT INTO jobs (kind, queue, payload, priority, run_at, max_attempts, unique_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (kind, unique_key) WHERE state IN ('available', 'running') DO NOTHING
+RETURNING id, kind, queue, payload, priority, state, attempts, max_attempts, run_at, unique_key, locked_by, locked_until, last_error, created_at, updated_at, finished_at`
+
+type InsertJobParams struct {
+	Kind        string
+	Queue       string
+	Payload     json.RawMessage
+	Priority    int16
+	RunAt       time.Time
+	MaxAttempts int32
+	UniqueKey   sql.Null[string]
+}
+
+// InsertJob returns sql.ErrNoRows when a pending job has the same kind and
+// unique key.
+func (q *Queries) InsertJob(ctx context.Context, arg InsertJobParams) (Job, error) {
+	row := q.db.QueryRowContext(ctx, insertJob, arg.Kind, arg.Queue, arg.Payload, arg.Priority, arg.RunAt, arg.MaxAttempts, arg.UniqueKey)
+	var i Job
+	err := row.Scan(&i.ID, &i.Kind, &i.Queue, &i.Payload, &i.Priority, &i.State, &i.Attempts, &i.MaxAttempts, &i.RunAt, &i.UniqueKey, &i.LockedBy, &i.LockedUntil, &i.LastError, &i.CreatedAt, &i.UpdatedAt, &i.FinishedAt)
+	return i, err
+}
+
+const claimJobs = `UPDATE jobs
+SET state = 'running', attempts = attempts + 1, locked_by = $1::text,
+    locked_until = now() + $2::bigint * interval '1 millisecond', updated_at = now()
+WHERE id IN (
+    SELECT id FROM jobs
+    WHERE queue = ANY($3) AND kind = ANY($4)
+      AND ((state = 'available' AND run_at <= now()) OR (state = 'running' AND locked_until < now()))
+    ORDER BY priority DESC, run_at, id
+    LIMIT $5
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, kind, queue, payload, priority, state, attempts, max_attempts, run_at, unique_key, locked_by, locked_until, last_error, created_at, updated_at, finished_at`
+
+type ClaimJobsParams struct {
+	Worker string
+	LockMs int64
+	Queues []string
+	Kinds  []string
+	Max    int64
+}
+
+// ClaimJobs leases the next jobs due on the given queues, skipping rows other
+// workers have locked. Running jobs whose lease expired are claimed again.
+// Leases are on the database clock, so a replica whose clock runs ahead does
+// not take over jobs that are still running.
+func (q *Queries) ClaimJobs(ctx context.Context, arg ClaimJobsParams) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, claimJobs, arg.Worker, arg.LockMs, arg.Queues, arg.Kinds, arg.Max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Job{}
+	for rows.Next() {
+		var i Job
+		if err := rows.Scan(&i.ID, &i.Kind, &i.Queue, &i.Payload, &i.Priority, &i.State, &i.Attempts, &i.MaxAttempts, &i.RunAt, &i.UniqueKey, &i.LockedBy, &i.LockedUntil, &i.LastError, &i.CreatedAt, &i.UpdatedAt, &i.FinishedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const completeJob = `UPDATE jobs
+SET state = 'completed', finished_at = $1::timestamptz, locked_by = NULL, locked_until = NULL, updated_at = $1
+WHERE id = $2 AND attempts = $3 AND state = 'running'`
+
+type CompleteJobParams struct {
+	Now      time.Time
+	ID       int64
+	Attempts int32
+}
+
+// CompleteJob, RetryJob and KillJob only update the attempt that claimed the
+// job, so a worker whose lease expired cannot overwrite a newer attempt.
+func (q *Queries) CompleteJob(ctx context.Context, arg CompleteJobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, completeJob, arg.Now, arg.ID, arg.Attempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const retryJob = `UPDATE jobs
+SET state = 'available', run_at = $1, last_error = $2::text, locked_by = NULL, locked_until = NULL, updated_at = $3
+WHERE id = $4 AND attempts = $5 AND state = 'running'`
+
+type RetryJobParams struct {
+	RunAt    time.Time
+	Error    string
+	Now      time.Time
+	ID       int64
+	Attempts int32
+}
+
+func (q *Queries) RetryJob(ctx context.Context, arg RetryJobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, retryJob, arg.RunAt, arg.Error, arg.Now, arg.ID, arg.Attempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const killJob = `UPDATE jobs
+SET state = 'dead', finished_at = $1::timestamptz, last_error = $2::text, locked_by = NULL, locked_until = NULL, updated_at = $1
+WHERE id = $3 AND attempts = $4 AND state = 'running'`
+
+type KillJobParams struct {
+	Now      time.Time
+	Error    string
+	ID       int64
+	Attempts int32
+}
+
+func (q *Queries) KillJob(ctx context.Context, arg KillJobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, killJob, arg.Now, arg.Error, arg.ID, arg.Attempts)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const requeueDeadJob = `UPDATE jobs
+SET state = 'available', attempts = 0, run_at = $1, finished_at = NULL, updated_at = $1
+WHERE id = $2 AND state = 'dead'`
+
+type RequeueDeadJobParams struct {
+	Now time.Time
+	ID  int64
+}
+
+// RequeueDeadJob gives a dead job a fresh set of attempts.
+func (q *Queries) RequeueDeadJob(ctx context.Context, arg RequeueDeadJobParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, requeueDeadJob, arg.Now, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteCompletedJobs = `DELETE FROM jobs WHERE state = 'completed' AND finished_at < $1`
+
+func (q *Queries) DeleteCompletedJobs(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCompletedJobs, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,134 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExampleChange is a row of the example_changes table.
+type ExampleChange struct {
+	ID        int64                     `db:"id" json:"id"`
+	Type      string                    `db:"type" json:"type"`
+	ExampleID uuid.UUID                 `db:"example_id" json:"example_id"`
+	Data      json.RawMessage           `db:"data" json:"data"`
+	CreatedAt time.Time                 `db:"created_at" json:"created_at"`
+	Previous  sql.Null[json.RawMessage] `db:"previous" json:"previous"`
+}
+
+// Example is a row of the examples table.
+type Example struct {
+	ID          uuid.UUID           `db:"id" json:"id"`
+	Name        string              `db:"name" json:"name"`
+	LuckyNumber float64             `db:"lucky_number" json:"lucky_number"`
+	IsPremium   bool                `db:"is_premium" json:"is_premium"`
+	CreatedAt   sql.Null[time.Time] `db:"created_at" json:"created_at"`
+	UpdatedAt   sql.Null[time.Time] `db:"updated_at" json:"updated_at"`
+}
+
+// IdempotencyKey is a row of the idempotency_keys table.
+type IdempotencyKey struct {
+	Scope           string                    `db:"scope" json:"scope"`
+	Key             string                    `db:"key" json:"key"`
+	Fingerprint     string                    `db:"fingerprint" json:"fingerprint"`
+	ResponseStatus  sql.Null[int32]           `db:"response_status" json:"response_status"`
+	ResponseHeaders sql.Null[json.RawMessage] `db:"response_headers" json:"response_headers"`
+	ResponseBody    []byte                    `db:"response_body" json:"response_body"`
+	LockedUntil     sql.Null[time.Time]       `db:"locked_until" json:"locked_until"`
+	CreatedAt       time.Time                 `db:"created_at" json:"created_at"`
+	ExpiresAt       time.Time                 `db:"expires_at" json:"expires_at"`
+}
+
+// Job is a row of the jobs table.
+type Job struct {
+	ID          int64               `db:"id" json:"id"`
+	Kind        string              `db:"kind" json:"kind"`
+	Queue       string              `db:"queue" json:"queue"`
+	Payload     json.RawMessage     `db:"payload" json:"payload"`
+	Priority    int16               `db:"priority" json:"priority"`
+	State       string              `db:"state" json:"state"`
+	Attempts    int32               `db:"attempts" json:"attempts"`
+	MaxAttempts int32               `db:"max_attempts" json:"max_attempts"`
+	RunAt       time.Time           `db:"run_at" json:"run_at"`
+	UniqueKey   sql.Null[string]    `db:"unique_key" json:"unique_key"`
+	LockedBy    sql.Null[string]    `db:"locked_by" json:"locked_by"`
+	LockedUntil sql.Null[time.Time] `db:"locked_until" json:"locked_until"`
+	LastError   sql.Null[string]    `db:"last_error" json:"last_error"`
+	CreatedAt   time.Time           `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `db:"updated_at" json:"updated_at"`
+	FinishedAt  sql.Null[time.Time] `db:"finished_at" json:"finished_at"`
+}
+
+// Outbox is a row of the outbox table.
+type Outbox struct {
+	ID            int64               `db:"id" json:"id"`
+	Type          string              `db:"type" json:"type"`
+	AggregateType string              `db:"aggregate_type" json:"aggregate_type"`
+	AggregateID   string              `db:"aggregate_id" json:"aggregate_id"`
+	Payload       json.RawMessage     `db:"payload" json:"payload"`
+	RequestID     sql.Null[string]    `db:"request_id" json:"request_id"`
+	Attempts      int32               `db:"attempts" json:"attempts"`
+	LastError     sql.Null[string]    `db:"last_error" json:"last_error"`
+	NextAttemptAt time.Time           `db:"next_attempt_at" json:"next_attempt_at"`
+	DispatchedAt  sql.Null[time.Time] `db:"dispatched_at" json:"dispatched_at"`
+	FailedAt      sql.Null[time.Time] `db:"failed_at" json:"failed_at"`
+	CreatedAt     time.Time           `db:"created_at" json:"created_at"`
+}
+
+// RateLimit is a row of the rate_limits table.
+type RateLimit struct {
+	Key       string    `db:"key" json:"key"`
+	Tokens    float64   `db:"tokens" json:"tokens"`
+	PrevCount int32     `db:"prev_count" json:"prev_count"`
+	CurrCount int32     `db:"curr_count" json:"curr_count"`
+	Stamp     time.Time `db:"stamp" json:"stamp"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// SchedulerLease is a row of the scheduler_leases table.
+type SchedulerLease struct {
+	Name      string    `db:"name" json:"name"`
+	Holder    string    `db:"holder" json:"holder"`
+	ExpiresAt time.Time `db:"expires_at" json:"expires_at"`
+}
+
+// Schedule is a row of the schedules table.
+type Schedule struct {
+	Name        string              `db:"name" json:"name"`
+	ScheduledAt sql.Null[time.Time] `db:"scheduled_at" json:"scheduled_at"`
+	StartedAt   sql.Null[time.Time] `db:"started_at" json:"started_at"`
+	FinishedAt  sql.Null[time.Time] `db:"finished_at" json:"finished_at"`
+	LastError   sql.Null[string]    `db:"last_error" json:"last_error"`
+	CreatedAt   time.Time           `db:"created_at" json:"created_at"`
+}
+
+// WebhookDelivery is a row of the webhook_deliveries table.
+type WebhookDelivery struct {
+	ID             int64            `db:"id" json:"id"`
+	WebhookID      uuid.UUID        `db:"webhook_id" json:"webhook_id"`
+	EventID        int64            `db:"event_id" json:"event_id"`
+	EventType      string           `db:"event_type" json:"event_type"`
+	Payload        json.RawMessage  `db:"payload" json:"payload"`
+	Attempt        int32            `db:"attempt" json:"attempt"`
+	ResponseStatus sql.Null[int32]  `db:"response_status" json:"response_status"`
+	Error          sql.Null[string] `db:"error" json:"error"`
+	DurationMs     int32            `db:"duration_ms" json:"duration_ms"`
+	CreatedAt      time.Time        `db:"created_at" json:"created_at"`
+}
+
+// Webhook is a row of the webhooks table.
+type Webhook struct {
+	ID                  uuid.UUID           `db:"id" json:"id"`
+	URL                 string              `db:"url" json:"url"`
+	Events              json.RawMessage     `db:"events" json:"events"`
+	Secret              string              `db:"secret" json:"secret"`
+	Enabled             bool                `db:"enabled" json:"enabled"`
+	ConsecutiveFailures int32               `db:"consecutive_failures" json:"consecutive_failures"`
+	DisabledAt          sql.Null[time.Time] `db:"disabled_at" json:"disabled_at"`
+	CreatedAt           time.Time           `db:"created_at" json:"created_at"`
+	UpdatedAt           time.Time           `db:"updated_at" json:"updated_at"`
+}
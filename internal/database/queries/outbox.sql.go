@@ -0,0 +1,108 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const insertOutboxEvent = `INSERT INTO outbox (type, aggregate_type, aggregate_id, payload, request_id, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id`
+
+type InsertOutboxEventParams struct {
+	Type          string
+	AggregateType string
+	AggregateID   string
+	Payload       json.RawMessage
+	RequestID     sql.Null[string]
+	CreatedAt     time.Time
+}
+
+func (q *Queries) InsertOutboxEvent(ctx context.Context, arg InsertOutboxEventParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertOutboxEvent, arg.Type, arg.AggregateType, arg.AggregateID, arg.Payload, arg.RequestID, arg.CreatedAt)
+	var i int64
+	err := row.Scan(&i)
+	return i, err
+}
+
+const listPendingOutboxEvents = `SELECT id, type, aggregate_type, aggregate_id, payload, request_id, attempts, last_error, next_attempt_at, dispatched_at, failed_at, created_at FROM outbox
+WHERE dispatched_at IS NULL AND failed_at IS NULL
+ORDER BY id
+LIMIT $1`
+
+// ListPendingOutboxEvents returns undelivered events oldest first, including
+// ones waiting to be retried so the relay keeps their order.
+func (q *Queries) ListPendingOutboxEvents(ctx context.Context, max int64) ([]Outbox, error) {
+	rows, err := q.db.QueryContext(ctx, listPendingOutboxEvents, max)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Outbox{}
+	for rows.Next() {
+		var i Outbox
+		if err := rows.Scan(&i.ID, &i.Type, &i.AggregateType, &i.AggregateID, &i.Payload, &i.RequestID, &i.Attempts, &i.LastError, &i.NextAttemptAt, &i.DispatchedAt, &i.FailedAt, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markOutboxEventDispatched = `UPDATE outbox SET dispatched_at = $1::timestamptz, attempts = attempts + 1, last_error = NULL
+WHERE id = $2`
+
+type MarkOutboxEventDispatchedParams struct {
+	Now time.Time
+	ID  int64
+}
+
+func (q *Queries) MarkOutboxEventDispatched(ctx context.Context, arg MarkOutboxEventDispatchedParams) error {
+	_, err := q.db.ExecContext(ctx, markOutboxEventDispatched, arg.Now, arg.ID)
+	return err
+}
+
+const retryOutboxEvent = `UPDATE outbox SET attempts = attempts + 1, last_error = $1::text, next_attempt_at = $2
+WHERE id = $3`
+
+type RetryOutboxEventParams struct {
+	Error         string
+	NextAttemptAt time.Time
+	ID            int64
+}
+
+func (q *Queries) RetryOutboxEvent(ctx context.Context, arg RetryOutboxEventParams) error {
+	_, err := q.db.ExecContext(ctx, retryOutboxEvent, arg.Error, arg.NextAttemptAt, arg.ID)
+	return err
+}
+
+const failOutboxEvent = `UPDATE outbox SET attempts = attempts + 1, last_error = $1::text, failed_at = $2::timestamptz
+WHERE id = $3`
+
+type FailOutboxEventParams struct {
+	Error string
+	Now   time.Time
+	ID    int64
+}
+
+func (q *Queries) FailOutboxEvent(ctx context.Context, arg FailOutboxEventParams) error {
+	_, err := q.db.ExecContext(ctx, failOutboxEvent, arg.Error, arg.Now, arg.ID)
+	return err
+}
+
+const deleteDispatchedOutboxEvents = `DELETE FROM outbox WHERE dispatched_at < $1`
+
+func (q *Queries) DeleteDispatchedOutboxEvents(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteDispatchedOutboxEvents, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,141 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const acquireSchedulerLease = `INSERT INTO scheduler_leases (name, holder, expires_at)
+VALUES ($1, $2, now() + $3::bigint * interval '1 millisecond')
+ON CONFLICT (name) DO UPDATE SET holder = $2, expires_at = now() + $3::bigint * interval '1 millisecond'
+WHERE scheduler_leases.holder = $2 OR scheduler_leases.expires_at < now()`
+
+type AcquireSchedulerLeaseParams struct {
+	Name   string
+	Holder string
+	TTLMs  int64
+}
+
+// AcquireSchedulerLease takes the lease when it is free or expired, or
+// extends it for its current holder. Expiry is on the database clock, so
+// replicas with skewed clocks agree on when the lease is free.
+func (q *Queries) AcquireSchedulerLease(ctx context.Context, arg AcquireSchedulerLeaseParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, acquireSchedulerLease, arg.Name, arg.Holder, arg.TTLMs)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const releaseSchedulerLease = `DELETE FROM scheduler_leases WHERE name = $1 AND holder = $2`
+
+type ReleaseSchedulerLeaseParams struct {
+	Name   string
+	Holder string
+}
+
+func (q *Queries) ReleaseSchedulerLease(ctx context.Context, arg ReleaseSchedulerLeaseParams) error {
+	_, err := q.db.ExecContext(ctx, releaseSchedulerLease, arg.Name, arg.Holder)
+	return err
+}
+
+const getSchedulerLease = `SELECT name, holder, expires_at FROM scheduler_leases WHERE name = $1 AND expires_at > now()`
+
+// GetSchedulerLease returns the lease unless it has expired.
+func (q *Queries) GetSchedulerLease(ctx context.Context, name string) (SchedulerLease, error) {
+	row := q.db.QueryRowContext(ctx, getSchedulerLease, name)
+	var i SchedulerLease
+	err := row.Scan(&i.Name, &i.Holder, &i.ExpiresAt)
+	return i, err
+}
+
+const ensureSchedule = `INSERT INTO schedules (name, created_at) VALUES ($1, $2)
+ON CONFLICT (name) DO NOTHING`
+
+type EnsureScheduleParams struct {
+	Name string
+	Now  time.Time
+}
+
+func (q *Queries) EnsureSchedule(ctx context.Context, arg EnsureScheduleParams) error {
+	_, err := q.db.ExecContext(ctx, ensureSchedule, arg.Name, arg.Now)
+	return err
+}
+
+const listSchedules = `SELECT name, scheduled_at, started_at, finished_at, last_error, created_at FROM schedules ORDER BY name`
+
+func (q *Queries) ListSchedules(ctx context.Context) ([]Schedule, error) {
+	rows, err := q.db.QueryContext(ctx, listSchedules)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Schedule{}
+	for rows.Next() {
+		var i Schedule
+		if err := rows.Scan(&i.Name, &i.ScheduledAt, &i.StartedAt, &i.FinishedAt, &i.LastError, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const startScheduledRun = `UPDATE schedules SET scheduled_at = $1::timestamptz, started_at = $2::timestamptz
+WHERE name = $3 AND (scheduled_at IS NULL OR scheduled_at < $1)`
+
+type StartScheduledRunParams struct {
+	ScheduledAt time.Time
+	Now         time.Time
+	Name        string
+}
+
+// StartScheduledRun records a run of the given slot. It affects no rows when
+// the slot already ran, so a slot runs once even across a leader change.
+func (q *Queries) StartScheduledRun(ctx context.Context, arg StartScheduledRunParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, startScheduledRun, arg.ScheduledAt, arg.Now, arg.Name)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const finishScheduledRun = `UPDATE schedules SET finished_at = $1::timestamptz, last_error = $2
+WHERE name = $3`
+
+type FinishScheduledRunParams struct {
+	Now   time.Time
+	Error sql.Null[string]
+	Name  string
+}
+
+func (q *Queries) FinishScheduledRun(ctx context.Context, arg FinishScheduledRunParams) error {
+	_, err := q.db.ExecContext(ctx, finishScheduledRun, arg.Now, arg.Error, arg.Name)
+	return err
+}
+
+const deleteExpiredIdempotencyKeys = `DELETE FROM idempotency_keys WHERE expires_at < $1`
+
+func (q *Queries) DeleteExpiredIdempotencyKeys(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredIdempotencyKeys, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const deleteExpiredRateLimits = `DELETE FROM rate_limits WHERE expires_at < $1`
+
+func (q *Queries) DeleteExpiredRateLimits(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteExpiredRateLimits, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,228 @@
+// Code generated by cmd/tools/sqlgen. DO NOT EDIT.
+
+package queries
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const insertWebhook = `INSERT INTO webhooks (url, events, secret, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $4)
+RETURNING id, url, events, secret, enabled, consecutive_failures, disabled_at, created_at, updated_at`
+
+type InsertWebhookParams struct {
+	URL    string
+	Events json.RawMessage
+	Secret string
+	Now    time.Time
+}
+
+func (q *Queries) InsertWebhook(ctx context.Context, arg InsertWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, insertWebhook, arg.URL, arg.Events, arg.Secret, arg.Now)
+	var i Webhook
+	err := row.Scan(&i.ID, &i.URL, &i.Events, &i.Secret, &i.Enabled, &i.ConsecutiveFailures, &i.DisabledAt, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
+const getWebhook = `SELECT id, url, events, secret, enabled, consecutive_failures, disabled_at, created_at, updated_at FROM webhooks WHERE id = $1`
+
+func (q *Queries) GetWebhook(ctx context.Context, id uuid.UUID) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, getWebhook, id)
+	var i Webhook
+	err := row.Scan(&i.ID, &i.URL, &i.Events, &i.Secret, &i.Enabled, &i.ConsecutiveFailures, &i.DisabledAt, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
+const listWebhooks = `SELECT id, url, events, secret, enabled, consecutive_failures, disabled_at, created_at, updated_at FROM webhooks ORDER BY created_at, id`
+
+func (q *Queries) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(&i.ID, &i.URL, &i.Events, &i.Secret, &i.Enabled, &i.ConsecutiveFailures, &i.DisabledAt, &i.CreatedAt, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listEnabledWebhooks = `SELECT id, url, events, secret, enabled, consecutive_failures, disabled_at, created_at, updated_at FROM webhooks WHERE enabled ORDER BY created_at, id`
+
+func (q *Queries) ListEnabledWebhooks(ctx context.Context) ([]Webhook, error) {
+	rows, err := q.db.QueryContext(ctx, listEnabledWebhooks)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Webhook{}
+	for rows.Next() {
+		var i Webhook
+		if err := rows.Scan(&i.ID, &i.URL, &i.Events, &i.Secret, &i.Enabled, &i.ConsecutiveFailures, &i.DisabledAt, &i.CreatedAt, &i.UpdatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateWebhook = `UPDATE webhooks
+SET url = $1, events = $2, enabled = $3, consecutive_failures = $4,
+    disabled_at = $5, updated_at = $6
+WHERE id = $7
+RETURNING id, url, events, secret, enabled, consecutive_failures, disabled_at, created_at, updated_at`
+
+type UpdateWebhookParams struct {
+	URL                 string
+	Events              json.RawMessage
+	Enabled             bool
+	ConsecutiveFailures int32
+	DisabledAt          sql.Null[time.Time]
+	Now                 time.Time
+	ID                  uuid.UUID
+}
+
+func (q *Queries) UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error) {
+	row := q.db.QueryRowContext(ctx, updateWebhook, arg.URL, arg.Events, arg.Enabled, arg.ConsecutiveFailures, arg.DisabledAt, arg.Now, arg.ID)
+	var i Webhook
+	err := row.Scan(&i.ID, &i.URL, &i.Events, &i.Secret, &i.Enabled, &i.ConsecutiveFailures, &i.DisabledAt, &i.CreatedAt, &i.UpdatedAt)
+	return i, err
+}
+
+const deleteWebhook = `DELETE FROM webhooks WHERE id = $1`
+
+func (q *Queries) DeleteWebhook(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebhook, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resetWebhookFailures = `UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0`
+
+func (q *Queries) ResetWebhookFailures(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, resetWebhookFailures, id)
+	return err
+}
+
+const countWebhookFailure = `UPDATE webhooks SET consecutive_failures = consecutive_failures + 1 WHERE id = $1
+RETURNING consecutive_failures`
+
+func (q *Queries) CountWebhookFailure(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRowContext(ctx, countWebhookFailure, id)
+	var i int32
+	err := row.Scan(&i)
+	return i, err
+}
+
+const disableWebhook = `UPDATE webhooks SET enabled = false, disabled_at = $1::timestamptz, updated_at = $1
+WHERE id = $2 AND enabled`
+
+type DisableWebhookParams struct {
+	Now time.Time
+	ID  uuid.UUID
+}
+
+func (q *Queries) DisableWebhook(ctx context.Context, arg DisableWebhookParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, disableWebhook, arg.Now, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const insertWebhookDelivery = `INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, attempt, response_status, error, duration_ms, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id`
+
+type InsertWebhookDeliveryParams struct {
+	WebhookID      uuid.UUID
+	EventID        int64
+	EventType      string
+	Payload        json.RawMessage
+	Attempt        int32
+	ResponseStatus sql.Null[int32]
+	Error          sql.Null[string]
+	DurationMs     int32
+	CreatedAt      time.Time
+}
+
+func (q *Queries) InsertWebhookDelivery(ctx context.Context, arg InsertWebhookDeliveryParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, insertWebhookDelivery, arg.WebhookID, arg.EventID, arg.EventType, arg.Payload, arg.Attempt, arg.ResponseStatus, arg.Error, arg.DurationMs, arg.CreatedAt)
+	var i int64
+	err := row.Scan(&i)
+	return i, err
+}
+
+const getWebhookDelivery = `SELECT id, webhook_id, event_id, event_type, payload, attempt, response_status, error, duration_ms, created_at FROM webhook_deliveries WHERE id = $1 AND webhook_id = $2`
+
+type GetWebhookDeliveryParams struct {
+	ID        int64
+	WebhookID uuid.UUID
+}
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, arg GetWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookDelivery, arg.ID, arg.WebhookID)
+	var i WebhookDelivery
+	err := row.Scan(&i.ID, &i.WebhookID, &i.EventID, &i.EventType, &i.Payload, &i.Attempt, &i.ResponseStatus, &i.Error, &i.DurationMs, &i.CreatedAt)
+	return i, err
+}
+
+const listWebhookDeliveries = `SELECT id, webhook_id, event_id, event_type, payload, attempt, response_status, error, duration_ms, created_at FROM webhook_deliveries
+WHERE webhook_id = $1
+ORDER BY id DESC
+LIMIT $2 OFFSET $3`
+
+type ListWebhookDeliveriesParams struct {
+	WebhookID uuid.UUID
+	Max       int64
+	Skip      int64
+}
+
+// ListWebhookDeliveries returns the newest attempts first.
+func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error) {
+	rows, err := q.db.QueryContext(ctx, listWebhookDeliveries, arg.WebhookID, arg.Max, arg.Skip)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookDelivery{}
+	for rows.Next() {
+		var i WebhookDelivery
+		if err := rows.Scan(&i.ID, &i.WebhookID, &i.EventID, &i.EventType, &i.Payload, &i.Attempt, &i.ResponseStatus, &i.Error, &i.DurationMs, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteWebhookDeliveries = `DELETE FROM webhook_deliveries WHERE created_at < $1`
+
+func (q *Queries) DeleteWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteWebhookDeliveries, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/jmoiron/sqlx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ctrixcode/go-chi-postgres/internal/database")
+
+var (
+	stringLiteralPattern  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	numericLiteralPattern = regexp.MustCompile(`(^|[^\w$])\d+(?:\.\d+)?`)
+	tablePattern          = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+"?([\w.]+)"?`)
+)
+
+// sanitizeSQL replaces inline literals with placeholders so span attributes
+// never contain values. Bind parameters ($1, $2, ...) are kept as is.
+func sanitizeSQL(query string) string {
+	query = stringLiteralPattern.ReplaceAllString(query, "?")
+	return numericLiteralPattern.ReplaceAllString(query, "${1}?")
+}
+
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0])
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(sanitizeSQL(query)),
+	}
+
+	name := operation
+	if m := tablePattern.FindStringSubmatch(query); m != nil {
+		name += " " + m[1]
+		attrs = append(attrs, semconv.DBCollectionName(m[1]))
+	}
+
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+}
+
+// endQuerySpan finishes a query span once its rows have been read.
+func endQuerySpan(ctx context.Context, span trace.Span, start time.Time, rows int, err error) {
+	span.SetAttributes(semconv.DBResponseReturnedRows(rows))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		logger.FromContext(ctx).Error("query failed", "duration", time.Since(start), "error", err)
+	} else {
+		logger.FromContext(ctx).Debug("query executed", "duration", time.Since(start), "rows", rows)
+	}
+	span.End()
+}
+
+func getContext(ctx context.Context, db sqlx.QueryerContext, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, query)
+	err := sqlx.GetContext(ctx, db, dest, query, args...)
+
+	rows := 1
+	if err != nil {
+		rows = 0
+	}
+	endQuerySpan(ctx, span, start, rows, err)
+	return err
+}
+
+func selectContext(ctx context.Context, db sqlx.QueryerContext, dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, query)
+	err := sqlx.SelectContext(ctx, db, dest, query, args...)
+
+	rows := 0
+	if err == nil {
+		rows = reflect.Indirect(reflect.ValueOf(dest)).Len()
+	}
+	endQuerySpan(ctx, span, start, rows, err)
+	return err
+}
+
+func execContext(ctx context.Context, db sqlx.ExecerContext, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, query)
+	result, err := db.ExecContext(ctx, query, args...)
+
+	rows := 0
+	if err == nil {
+		if affected, rerr := result.RowsAffected(); rerr == nil {
+			rows = int(affected)
+		}
+	}
+	endQuerySpan(ctx, span, start, rows, err)
+	return result, err
+}
+
+// tracedDB runs the generated queries with the same spans and query logs as
+// the sqlx helpers above. Spans of queries returning rows end once the rows
+// have been read, so they include reading them and errors met doing so.
+type tracedDB struct {
+	db queries.DBTX
+}
+
+// NewQueries returns the generated queries running on db, usually *sqlx.DB
+// or a transaction, traced like the rest of the package. On *sqlx.DB they
+// join the transaction a Transactor put in the context.
+func NewQueries(db queries.DBTX) *queries.Queries {
+	return queries.NewConn(tracedDB{db: db})
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return execContext(ctx, dbtx(ctx, t.db), query, args...)
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (queries.Rows, error) {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, query)
+	rows, err := dbtx(ctx, t.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		endQuerySpan(ctx, span, start, 0, err)
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, ctx: ctx, span: span, start: start}, nil
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) queries.Row {
+	start := time.Now()
+	ctx, span := startQuerySpan(ctx, query)
+	row := dbtx(ctx, t.db).QueryRowContext(ctx, query, args...)
+	return &tracedRow{row: row, ctx: ctx, span: span, start: start}
+}
+
+// tracedRow ends its query's span when it is scanned.
+type tracedRow struct {
+	row   *sql.Row
+	ctx   context.Context
+	span  trace.Span
+	start time.Time
+}
+
+func (r *tracedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	rows := 1
+	if err != nil {
+		rows = 0
+	}
+	endQuerySpan(r.ctx, r.span, r.start, rows, err)
+	return err
+}
+
+// tracedRows ends its query's span when closed, counting the rows read.
+type tracedRows struct {
+	*sql.Rows
+	ctx    context.Context
+	span   trace.Span
+	start  time.Time
+	read   int
+	err    error
+	closed bool
+}
+
+func (r *tracedRows) Next() bool {
+	if !r.Rows.Next() {
+		return false
+	}
+	r.read++
+	return true
+}
+
+func (r *tracedRows) Scan(dest ...interface{}) error {
+	err := r.Rows.Scan(dest...)
+	if err != nil && r.err == nil {
+		r.err = err
+	}
+	return err
+}
+
+func (r *tracedRows) Close() error {
+	if r.closed {
+		return r.Rows.Close()
+	}
+	r.closed = true
+	if r.err == nil {
+		r.err = r.Rows.Err()
+	}
+	err := r.Rows.Close()
+	if r.err == nil {
+		r.err = err
+	}
+	endQuerySpan(r.ctx, r.span, r.start, r.read, r.err)
+	return err
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/internal/repository"
+	"github.com/jmoiron/sqlx"
+)
+
+type txKey struct{}
+
+type transactor struct {
+	db *sqlx.DB
+}
+
+// NewTransactor returns a repository.Transactor beginning transactions on
+// db. Repositories and generated queries built on db run their queries on
+// the transaction carried by the context they are called with.
+func NewTransactor(db *sqlx.DB) repository.Transactor {
+	return &transactor{db: db}
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	// Nested calls join the outer transaction
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// conn returns the transaction carried by ctx in place of the pool. A
+// transaction passed in explicitly, e.g. through WithDB, is kept.
+func conn(ctx context.Context, db sqlx.ExtContext) sqlx.ExtContext {
+	if tx := poolTx(ctx, db); tx != nil {
+		return tx
+	}
+	return db
+}
+
+// dbtx is conn for the generated queries.
+func dbtx(ctx context.Context, db queries.DBTX) queries.DBTX {
+	if tx := poolTx(ctx, db); tx != nil {
+		return tx
+	}
+	return db
+}
+
+func poolTx(ctx context.Context, db interface{}) *sqlx.Tx {
+	if _, pooled := db.(*sqlx.DB); !pooled {
+		return nil
+	}
+	tx, _ := ctx.Value(txKey{}).(*sqlx.Tx)
+	return tx
+}
@@ -0,0 +1,112 @@
+// Package events records domain events in a transactional outbox and relays
+// them to sinks. An event is written in the same transaction as the change
+// it describes, so it is published if and only if the change commits:
+//
+//	err := transactor.WithinTx(ctx, func(ctx context.Context) error {
+//		example, err := repo.Create(ctx, req)
+//		if err != nil {
+//			return err
+//		}
+//		event, err := events.New(models.EventExampleCreated, "example", example.ID.String(), example)
+//		if err != nil {
+//			return err
+//		}
+//		return outbox.Publish(ctx, event)
+//	})
+//
+// The Relay then delivers events to every sink at least once, in the order
+// they were written, so sinks must tolerate duplicates; Event.ID identifies
+// them.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/pkg/requestid"
+)
+
+type Event struct {
+	// ID is assigned when the event is published.
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AggregateType string          `json:"aggregate_type"`
+	AggregateID   string          `json:"aggregate_id"`
+	Payload       json.RawMessage `json:"payload"`
+	// RequestID is the request that caused the event, if any.
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// New returns an event with payload encoded as JSON.
+func New(eventType, aggregateType, aggregateID string, payload interface{}) (Event, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return Event{}, fmt.Errorf("events: encoding %s payload: %w", eventType, err)
+	}
+	return Event{
+		Type:          eventType,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		Payload:       data,
+	}, nil
+}
+
+// Publisher records events for delivery.
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
+
+// Outbox is the Publisher writing to the outbox table.
+type Outbox struct {
+	queries *queries.Queries
+}
+
+func NewOutbox(db queries.DBTX) *Outbox {
+	return &Outbox{queries: database.NewQueries(db)}
+}
+
+// Publish appends events to the outbox. Called with a context from
+// Transactor.WithinTx it joins that transaction, so the events are only
+// relayed if it commits.
+func (o *Outbox) Publish(ctx context.Context, events ...Event) error {
+	requestID := requestid.FromContext(ctx)
+	for _, e := range events {
+		createdAt := e.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		if e.RequestID == "" {
+			e.RequestID = requestID
+		}
+		_, err := o.queries.InsertOutboxEvent(ctx, queries.InsertOutboxEventParams{
+			Type:          e.Type,
+			AggregateType: e.AggregateType,
+			AggregateID:   e.AggregateID,
+			Payload:       e.Payload,
+			RequestID:     sql.Null[string]{V: e.RequestID, Valid: e.RequestID != ""},
+			CreatedAt:     createdAt,
+		})
+		if err != nil {
+			return fmt.Errorf("events: publishing %s: %w", e.Type, err)
+		}
+	}
+	return nil
+}
+
+func fromRow(row queries.Outbox) Event {
+	return Event{
+		ID:            row.ID,
+		Type:          row.Type,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		Payload:       row.Payload,
+		RequestID:     row.RequestID.V,
+		CreatedAt:     row.CreatedAt,
+	}
+}
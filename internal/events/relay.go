@@ -0,0 +1,262 @@
+package events
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/requestid"
+	"github.com/jmoiron/sqlx"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ctrixcode/go-chi-postgres/internal/events")
+
+// relayLockKey is the advisory lock held by the replica relaying a batch
+// ("outbox" in ASCII).
+const relayLockKey = 0x6f7574626f78
+
+// unlockTimeout bounds releasing the lock, which also runs after the relay
+// is cancelled.
+const unlockTimeout = 5 * time.Second
+
+// Options are read before every batch, so they can change at runtime.
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// DeliveryTimeout bounds delivering one event to every sink.
+	DeliveryTimeout time.Duration
+	// An event failing delivery is retried after BackoffBase, doubled for
+	// every earlier attempt and capped at BackoffMax, until MaxAttempts
+	// attempts have failed; 0 retries forever.
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Relay delivers events from the outbox to its sinks in the order they were
+// published. One replica relays at a time, holding a session advisory lock
+// while it works through a batch. Each event is delivered outside any
+// transaction and its outcome recorded right after; if the relay dies in
+// between, the event is delivered again.
+//
+// An event that fails delivery holds back the events after it until it is
+// delivered or runs out of attempts, which keeps the changes sinks see in
+// order.
+type Relay struct {
+	db      *sqlx.DB
+	sinks   []Sink
+	options func() Options
+
+	relayed *prometheus.CounterVec
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+func NewRelay(db *sqlx.DB, sinks []Sink, options func() Options, m *metrics.Metrics) *Relay {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Relay{
+		db:      db,
+		sinks:   sinks,
+		options: options,
+		relayed: m.Counter("outbox_events_relayed_total", "Outbox delivery attempts by event type and outcome.", "type", "outcome"),
+		ctx:     ctx,
+		cancel:  cancel,
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start begins relaying events in the background.
+func (r *Relay) Start() {
+	if r.started.CompareAndSwap(false, true) {
+		names := make([]string, len(r.sinks))
+		for i, s := range r.sinks {
+			names[i] = s.Name()
+		}
+		slog.Info("outbox relay starting", "sinks", names)
+		go r.run()
+	}
+}
+
+// Shutdown stops relaying after the batch in progress. If ctx ends first the
+// batch is cancelled, leaving its unrecorded events to be delivered again,
+// and ctx.Err() is returned.
+func (r *Relay) Shutdown(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	if !r.started.Load() {
+		return nil
+	}
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		r.cancel()
+		<-r.done
+		return ctx.Err()
+	}
+}
+
+func (r *Relay) run() {
+	defer close(r.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-timer.C:
+		}
+
+		opts := r.options()
+		// A full batch means more events may be waiting
+		delay := opts.PollInterval
+		if r.relay(opts) {
+			delay = 0
+		}
+		timer.Reset(delay)
+	}
+}
+
+// relay delivers a batch of pending events and reports whether all of a full
+// batch was delivered.
+func (r *Relay) relay(opts Options) bool {
+	ctx := r.ctx
+	// The lock is held by the session rather than a transaction, so events
+	// are delivered outside any transaction and recorded one at a time
+	conn, err := r.db.Connx(ctx)
+	if err != nil {
+		slog.Error("connecting outbox relay failed", "error", err)
+		return false
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", relayLockKey).Scan(&locked); err != nil {
+		slog.Error("locking outbox failed", "error", err)
+		return false
+	}
+	if !locked {
+		// Another replica is relaying
+		return false
+	}
+	defer unlock(conn)
+
+	q := database.NewQueries(conn)
+	batchSize := max(opts.BatchSize, 1)
+	rows, err := q.ListPendingOutboxEvents(ctx, int64(batchSize))
+	if err != nil {
+		slog.Error("listing outbox events failed", "error", err)
+		return false
+	}
+
+	now := time.Now()
+	relayed := 0
+	for _, row := range rows {
+		if row.NextAttemptAt.After(now) || !r.dispatch(ctx, q, row, opts) {
+			break
+		}
+		relayed++
+	}
+	return relayed == batchSize
+}
+
+// unlock releases the relay lock. A connection that may still hold it is
+// closed instead of going back to the pool, which releases it as well.
+func unlock(conn *sqlx.Conn) {
+	ctx, cancel := context.WithTimeout(context.Background(), unlockTimeout)
+	defer cancel()
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", relayLockKey); err != nil {
+		slog.Error("unlocking outbox failed", "error", err)
+		conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+}
+
+// dispatch delivers an event to every sink and records the outcome. It
+// reports whether the events after it may be delivered.
+func (r *Relay) dispatch(ctx context.Context, q *queries.Queries, row queries.Outbox, opts Options) bool {
+	e := fromRow(row)
+	attempt := int(row.Attempts) + 1
+	ctx = requestid.NewContext(ctx, e.RequestID)
+	ctx, span := tracer.Start(ctx, "event "+e.Type, trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+		attribute.Int64("event.id", e.ID),
+		attribute.String("event.type", e.Type),
+		attribute.Int("event.attempt", attempt),
+	))
+	defer span.End()
+	log := logger.FromContext(ctx).With("event_id", e.ID, "event_type", e.Type, "attempt", attempt)
+
+	err := r.deliver(ctx, e, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	var outcome string
+	var qerr error
+	now := time.Now()
+	switch {
+	case err == nil:
+		outcome = "dispatched"
+		qerr = q.MarkOutboxEventDispatched(ctx, queries.MarkOutboxEventDispatchedParams{Now: now, ID: e.ID})
+	case opts.MaxAttempts > 0 && attempt >= opts.MaxAttempts:
+		outcome = "failed"
+		qerr = q.FailOutboxEvent(ctx, queries.FailOutboxEventParams{Error: err.Error(), Now: now, ID: e.ID})
+	default:
+		outcome = "retried"
+		next := now.Add(jobs.Backoff(attempt, opts.BackoffBase, opts.BackoffMax))
+		qerr = q.RetryOutboxEvent(ctx, queries.RetryOutboxEventParams{Error: err.Error(), NextAttemptAt: next, ID: e.ID})
+	}
+	r.relayed.WithLabelValues(e.Type, outcome).Inc()
+
+	switch {
+	case qerr != nil:
+		log.Error("recording event delivery failed", "outcome", outcome, "error", qerr, "delivery_error", err)
+		return false
+	case outcome == "failed":
+		log.Error("event delivery failed permanently", "error", err)
+		return true
+	case outcome == "retried":
+		log.Warn("event delivery failed, retrying", "error", err)
+		return false
+	}
+	return true
+}
+
+// deliver hands e to every sink, even when an earlier one fails. A retry
+// delivers it to all of them again.
+func (r *Relay) deliver(ctx context.Context, e Event, opts Options) error {
+	if opts.DeliveryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.DeliveryTimeout)
+		defer cancel()
+	}
+	var errs []error
+	for _, s := range r.sinks {
+		if err := s.Deliver(ctx, e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
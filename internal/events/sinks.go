@@ -0,0 +1,153 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+)
+
+// Sink receives relayed events. Deliver may be called again with an event it
+// has already received, e.g. when another sink failed to take it.
+type Sink interface {
+	Name() string
+	Deliver(ctx context.Context, e Event) error
+}
+
+// Handler handles an event delivered to in-process subscribers.
+type Handler func(ctx context.Context, e Event) error
+
+type subscription struct {
+	pattern string
+	handler Handler
+}
+
+// Subscribers is the Sink dispatching to handlers in this process.
+type Subscribers struct {
+	mu            sync.RWMutex
+	subscriptions []subscription
+}
+
+func NewSubscribers() *Subscribers {
+	return &Subscribers{}
+}
+
+// Subscribe calls handler with every event whose type matches pattern: an
+// exact type such as "example.created", a prefix such as "example.*", or
+// "*" for all events.
+func (s *Subscribers) Subscribe(pattern string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions = append(s.subscriptions, subscription{pattern: pattern, handler: handler})
+}
+
+func (s *Subscribers) Name() string { return "subscribers" }
+
+// Deliver calls every matching handler, even when an earlier one fails, and
+// returns their errors joined.
+func (s *Subscribers) Deliver(ctx context.Context, e Event) error {
+	s.mu.RLock()
+	subscriptions := s.subscriptions
+	s.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subscriptions {
+		if !Match(sub.pattern, e.Type) {
+			continue
+		}
+		if err := call(ctx, sub.handler, e); err != nil {
+			errs = append(errs, fmt.Errorf("subscriber %s: %w", sub.pattern, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// call runs handler, turning a panic into an error.
+func call(ctx context.Context, handler Handler, e Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return handler(ctx, e)
+}
+
+// Match reports whether eventType matches pattern as described for
+// Subscribers.Subscribe.
+func Match(pattern, eventType string) bool {
+	if pattern == "*" || pattern == eventType {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(pattern, "*")
+	return ok && strings.HasPrefix(eventType, prefix)
+}
+
+// LogSink logs every event, which helps when developing against the outbox.
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Deliver(ctx context.Context, e Event) error {
+	logger.FromContext(ctx).Info("event",
+		"event_id", e.ID,
+		"event_type", e.Type,
+		"aggregate_type", e.AggregateType,
+		"aggregate_id", e.AggregateID,
+		"payload", string(e.Payload),
+	)
+	return nil
+}
+
+// WebhookSink POSTs every event as JSON to a URL. Any response other than
+// 2xx fails the delivery.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink returns a sink posting to rawURL with client, which should
+// have a timeout as the relay waits for the response.
+func NewWebhookSink(rawURL string, client *http.Client) (*WebhookSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("events: invalid webhook URL %q", rawURL)
+	}
+	return &WebhookSink{url: rawURL, client: client}, nil
+}
+
+func (w *WebhookSink) Name() string { return "webhook " + w.url }
+
+func (w *WebhookSink) Deliver(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", strconv.FormatInt(e.ID, 10))
+	req.Header.Set("X-Event-Type", e.Type)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,197 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/security"
+	"github.com/ctrixcode/go-chi-postgres/internal/services"
+	"github.com/ctrixcode/go-chi-postgres/internal/webui"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/request"
+	"github.com/go-playground/validator/v10"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+)
+
+// Options are read for every request, so they can change at runtime.
+type Options struct {
+	// MaxDepth bounds how deeply fields may be nested and MaxComplexity the
+	// cost of an operation, see measure; 0 leaves them unbounded.
+	MaxDepth      int
+	MaxComplexity int
+	// GraphiQL serves an in-browser IDE to GET requests from browsers.
+	GraphiQL bool
+}
+
+// Request is a GraphQL request, from a JSON body or the query string.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler serves GraphQL over HTTP: queries by GET or POST, mutations by
+// POST only.
+type Handler struct {
+	schema   graphql.Schema
+	resolver *resolver
+	options  func() Options
+}
+
+func NewHandler(service services.ExampleService, options func() Options) *Handler {
+	r := &resolver{service: service, validator: validator.New()}
+	schema, err := newSchema(r)
+	if err != nil {
+		panic(fmt.Sprintf("graph: invalid schema: %v", err))
+	}
+	return &Handler{schema: schema, resolver: r, options: options}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	opts := h.options()
+
+	var req Request
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		if q.Get("query") == "" && opts.GraphiQL && strings.Contains(r.Header.Get("Accept"), "text/html") {
+			graphiQLHandler(w, r)
+			return
+		}
+		req.Query, req.OperationName = q.Get("query"), q.Get("operationName")
+		if v := q.Get("variables"); v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				writeResult(w, http.StatusBadRequest, failed(errors.BadRequestError(errors.ErrBadRequest, "variables must be a JSON object")))
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := request.DecodeJSON(r, &req); err != nil {
+			apiErr := err.(*errors.APIError)
+			writeResult(w, apiErr.StatusCode, failed(apiErr))
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeResult(w, http.StatusMethodNotAllowed, failed(errors.NewAPIError(http.StatusMethodNotAllowed, errors.ErrMethodNotAllowed, "Use GET or POST", true)))
+		return
+	}
+
+	status, result := h.execute(r, req, opts)
+	writeResult(w, status, result)
+}
+
+// execute runs req. Requests rejected before running, as malformed,
+// invalid or over the limits, get a client error status and no data.
+func (h *Handler) execute(r *http.Request, req Request, opts Options) (int, *graphql.Result) {
+	if req.Query == "" {
+		return http.StatusBadRequest, failed(errors.BadRequestError(errors.ErrBadRequest, "query is required"))
+	}
+
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if validation := graphql.ValidateDocument(&h.schema, doc, graphql.SpecifiedRules); !validation.IsValid {
+		return http.StatusBadRequest, &graphql.Result{Errors: validation.Errors}
+	}
+
+	op := operation(doc, req.OperationName)
+	if op == nil {
+		return http.StatusBadRequest, failed(errors.BadRequestError(errors.ErrBadRequest, "operation not found"))
+	}
+	if op.Operation == ast.OperationTypeMutation && r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, failed(errors.NewAPIError(http.StatusMethodNotAllowed, errors.ErrMethodNotAllowed, "Mutations must be sent with POST", true))
+	}
+
+	depth, complexity := measure(doc, op, req.Variables)
+	if opts.MaxDepth > 0 && depth > opts.MaxDepth {
+		return http.StatusBadRequest, failed(errors.BadRequestError(errors.ErrQueryTooComplex, fmt.Sprintf("query depth %d exceeds the maximum of %d", depth, opts.MaxDepth)))
+	}
+	if opts.MaxComplexity > 0 && complexity > opts.MaxComplexity {
+		return http.StatusBadRequest, failed(errors.BadRequestError(errors.ErrQueryTooComplex, fmt.Sprintf("query complexity %d exceeds the maximum of %d", complexity, opts.MaxComplexity)))
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       h.resolver.loaders(r.Context()),
+	})
+	return http.StatusOK, result
+}
+
+// operation returns the operation named name, or the only one when name is
+// empty.
+func operation(doc *ast.Document, name string) *ast.OperationDefinition {
+	var found *ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		if op, ok := def.(*ast.OperationDefinition); ok {
+			if name == "" && found != nil {
+				return nil
+			}
+			if name == "" || op.Name != nil && op.Name.Value == name {
+				found = op
+			}
+		}
+	}
+	return found
+}
+
+// failed returns a result holding only err.
+func failed(err *errors.APIError) *graphql.Result {
+	e := apiError{err}
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{{
+		Message:    e.Error(),
+		Locations:  []location.SourceLocation{},
+		Extensions: e.Extensions(),
+	}}}
+}
+
+func writeResult(w http.ResponseWriter, status int, result *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+var graphiQLPage = template.Must(template.New("graphiql").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>GraphiQL</title>
+  <link rel="stylesheet" href="{{.CSS}}">
+</head>
+<body style="margin: 0; height: 100vh">
+  <div id="graphiql" style="height: 100vh"></div>
+  <script src="{{.React}}"></script>
+  <script src="{{.ReactDOM}}"></script>
+  <script src="{{.GraphiQL}}"></script>
+  <script nonce="{{.Nonce}}">
+    const fetcher = GraphiQL.createFetcher({ url: window.location.pathname });
+    ReactDOM.createRoot(document.getElementById("graphiql")).render(React.createElement(GraphiQL, { fetcher }));
+  </script>
+</body>
+</html>
+`))
+
+// graphiQLHandler serves GraphiQL.
+func graphiQLHandler(w http.ResponseWriter, r *http.Request) {
+	nonce := security.PagePolicy(w, webui.Sources(webui.GraphiQL, webui.React, webui.ReactDOM))
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	graphiQLPage.Execute(w, map[string]string{
+		"Nonce":    nonce,
+		"CSS":      webui.GraphiQL.URL("graphiql.min.css"),
+		"GraphiQL": webui.GraphiQL.URL("graphiql.min.js"),
+		"React":    webui.React.URL("umd/react.production.min.js"),
+		"ReactDOM": webui.ReactDOM.URL("umd/react-dom.production.min.js"),
+	})
+}
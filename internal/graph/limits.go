@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// cost measures an operation before it runs. Every field costs 1, and the
+// fields below one taking a limit argument count once per item it may
+// return. Introspection fields are free so GraphiQL and code generators
+// work under any limits.
+type cost struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// defaults are the default values of the operation's variables.
+	defaults map[string]ast.Value
+}
+
+// measure returns the depth and complexity of op, an operation of doc.
+// The document must have passed validation, which rules out fragment
+// cycles.
+func measure(doc *ast.Document, op *ast.OperationDefinition, variables map[string]interface{}) (depth, complexity int) {
+	c := cost{fragments: make(map[string]*ast.FragmentDefinition), variables: variables, defaults: make(map[string]ast.Value)}
+	for _, def := range op.VariableDefinitions {
+		if def.DefaultValue != nil {
+			c.defaults[def.Variable.Name.Value] = def.DefaultValue
+		}
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+	return c.selections(op.SelectionSet, 1)
+}
+
+func (c cost) selections(set *ast.SelectionSet, multiplier int) (depth, complexity int) {
+	if set == nil {
+		return 0, 0
+	}
+	for _, selection := range set.Selections {
+		var d, n int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			d, n = c.selections(s.SelectionSet, multiplier*c.items(s))
+			d, n = d+1, n+multiplier
+		case *ast.InlineFragment:
+			d, n = c.selections(s.SelectionSet, multiplier)
+		case *ast.FragmentSpread:
+			if fragment, ok := c.fragments[s.Name.Value]; ok {
+				d, n = c.selections(fragment.SelectionSet, multiplier)
+			}
+		}
+		depth = max(depth, d)
+		complexity += n
+	}
+	return depth, complexity
+}
+
+// items returns how many items field may return: the limit argument of
+// the paginated fields, 1 for the others. A limit that cannot be told
+// before the field runs counts as the most it may be.
+func (c cost) items(field *ast.Field) int {
+	if !paginated[field.Name.Value] {
+		return 1
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		value := arg.Value
+		if v, ok := value.(*ast.Variable); ok {
+			given, ok := c.variables[v.Name.Value]
+			if !ok {
+				// Without a default either, the argument is left out
+				if value, ok = c.defaults[v.Name.Value]; !ok {
+					return defaultLimit
+				}
+			} else if n, ok := given.(float64); ok {
+				// Variables decode from JSON as float64
+				return max(int(n), 1)
+			}
+		}
+		if v, ok := value.(*ast.IntValue); ok {
+			if n, err := strconv.Atoi(v.Value); err == nil {
+				return max(n, 1)
+			}
+		}
+		return maxLimit
+	}
+	return defaultLimit
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Loader batches the lookups by key made while a level of a query resolves,
+// DataLoader style. Load registers the key and returns a thunk; graphql-go
+// calls thunks once every field of the level has been resolved, so the
+// first one fetches all keys registered so far in one call. Values are
+// cached for the loader's lifetime, a single request.
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	results map[K]*loaded[V]
+}
+
+type loaded[V any] struct {
+	value V
+	found bool
+	err   error
+}
+
+// NewLoader returns a loader getting values from fetch, which returns the
+// values found for keys, leaving out the missing ones.
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, results: make(map[K]*loaded[V])}
+}
+
+// Load returns a thunk resolving to the value for key, or nil when there is
+// none.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) func() (interface{}, error) {
+	l.mu.Lock()
+	result, ok := l.results[key]
+	if !ok {
+		result = &loaded[V]{}
+		l.results[key] = result
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.dispatch(ctx)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if result.err != nil {
+			return nil, result.err
+		}
+		if !result.found {
+			return nil, nil
+		}
+		return result.value, nil
+	}
+}
+
+// Prime caches value for key, as when a mutation returns it. Thunks already
+// returned for key resolve to it too, and it is no longer fetched.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result, ok := l.results[key]
+	if !ok {
+		result = &loaded[V]{}
+		l.results[key] = result
+	}
+	*result = loaded[V]{value: value, found: true}
+	l.pending = slices.DeleteFunc(l.pending, func(k K) bool { return k == key })
+}
+
+// dispatch fetches the pending keys.
+func (l *Loader[K, V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.pending) == 0 {
+		return
+	}
+	keys := l.pending
+	l.pending = nil
+
+	values, err := l.fetch(ctx, keys)
+	for _, key := range keys {
+		result := l.results[key]
+		if err != nil {
+			result.err = err
+			continue
+		}
+		result.value, result.found = values[key]
+	}
+}
@@ -0,0 +1,308 @@
+// Package graph serves the examples over GraphQL at /graphql, next to the
+// REST routes and through the same service.
+package graph
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/services"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+const (
+	// defaultLimit and maxLimit bound the examples returned by a list.
+	defaultLimit = 10
+	maxLimit     = 100
+)
+
+// paginated are the fields taking a limit argument, whose selections are
+// counted once per item by the complexity limit.
+var paginated = map[string]bool{"examples": true}
+
+// resolver resolves the fields of the schema with the example service.
+type resolver struct {
+	service   services.ExampleService
+	validator *validator.Validate
+}
+
+// loaders are created for every request so their caches do not outlive it.
+type loaders struct {
+	examples *Loader[uuid.UUID, models.Example]
+}
+
+type loadersKey struct{}
+
+func (r *resolver) loaders(ctx context.Context) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		examples: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]models.Example, error) {
+			examples, err := r.service.GetByIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			byID := make(map[uuid.UUID]models.Example, len(examples))
+			for _, e := range examples {
+				byID[e.ID] = e
+			}
+			return byID, nil
+		}),
+	})
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func newSchema(r *resolver) (graphql.Schema, error) {
+	example := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Example",
+		Fields: graphql.Fields{
+			"id":          {Type: graphql.NewNonNull(graphql.ID), Resolve: exampleField(func(e models.Example) interface{} { return e.ID.String() })},
+			"name":        {Type: graphql.NewNonNull(graphql.String), Resolve: exampleField(func(e models.Example) interface{} { return e.Name })},
+			"luckyNumber": {Type: graphql.NewNonNull(graphql.Float), Resolve: exampleField(func(e models.Example) interface{} { return e.LuckyNumber })},
+			"isPremium":   {Type: graphql.NewNonNull(graphql.Boolean), Resolve: exampleField(func(e models.Example) interface{} { return e.IsPremium })},
+			"createdAt":   {Type: graphql.NewNonNull(graphql.DateTime), Resolve: exampleField(func(e models.Example) interface{} { return e.CreatedAt })},
+			"updatedAt":   {Type: graphql.NewNonNull(graphql.DateTime), Resolve: exampleField(func(e models.Example) interface{} { return e.UpdatedAt })},
+		},
+	})
+
+	filter := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "ExampleFilter",
+		Description: "Selects examples by their fields; fields left out match every example.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":           {Type: graphql.String},
+			"isPremium":      {Type: graphql.Boolean},
+			"minLuckyNumber": {Type: graphql.Float},
+			"maxLuckyNumber": {Type: graphql.Float},
+		},
+	})
+	sortValues := graphql.EnumValueConfigMap{}
+	for _, column := range models.ExampleSortColumns {
+		sortValues[strings.ToUpper(column)] = &graphql.EnumValueConfig{Value: column}
+	}
+	sort := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ExampleSort",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"field":      {Type: graphql.NewNonNull(graphql.NewEnum(graphql.EnumConfig{Name: "ExampleSortField", Values: sortValues}))},
+			"descending": {Type: graphql.Boolean, DefaultValue: false},
+		},
+	})
+
+	create := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "CreateExampleInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        {Type: graphql.NewNonNull(graphql.String)},
+			"luckyNumber": {Type: graphql.NewNonNull(graphql.Float)},
+			"isPremium":   {Type: graphql.Boolean, DefaultValue: false},
+		},
+	})
+	update := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        "UpdateExampleInput",
+		Description: "Only the fields present are changed.",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        {Type: graphql.String},
+			"luckyNumber": {Type: graphql.Float},
+			"isPremium":   {Type: graphql.Boolean},
+		},
+	})
+	id := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"example": {
+					Type: example, Description: "The example with the given id, or null.",
+					Args: graphql.FieldConfigArgument{"id": id}, Resolve: r.example,
+				},
+				"examples": {
+					Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(example))),
+					Args: graphql.FieldConfigArgument{
+						"filter": {Type: filter},
+						"sort":   {Type: sort, Description: "Creation order when unset."},
+						"limit":  {Type: graphql.Int, DefaultValue: defaultLimit, Description: fmt.Sprintf("At most %d.", maxLimit)},
+						"offset": {Type: graphql.Int, DefaultValue: 0},
+					},
+					Resolve: r.examples,
+				},
+			},
+		}),
+		Mutation: graphql.NewObject(graphql.ObjectConfig{
+			Name: "Mutation",
+			Fields: graphql.Fields{
+				"createExample": {
+					Type: graphql.NewNonNull(example),
+					Args: graphql.FieldConfigArgument{"input": {Type: graphql.NewNonNull(create)}}, Resolve: r.createExample,
+				},
+				"updateExample": {
+					Type: graphql.NewNonNull(example),
+					Args: graphql.FieldConfigArgument{"id": id, "input": {Type: graphql.NewNonNull(update)}}, Resolve: r.updateExample,
+				},
+				"deleteExample": {
+					Type: graphql.NewNonNull(graphql.ID), Description: "Deletes the example and returns its id.",
+					Args: graphql.FieldConfigArgument{"id": id}, Resolve: r.deleteExample,
+				},
+			},
+		}),
+	})
+}
+
+func (r *resolver) example(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	return loadersFrom(p.Context).examples.Load(p.Context, id), nil
+}
+
+func (r *resolver) examples(p graphql.ResolveParams) (interface{}, error) {
+	limit, _ := p.Args["limit"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if limit < 1 || limit > maxLimit {
+		return nil, apiError{errors.BadRequestError(errors.ErrBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxLimit))}
+	}
+	if offset < 0 {
+		return nil, apiError{errors.BadRequestError(errors.ErrBadRequest, "offset must not be negative")}
+	}
+
+	q := models.ExampleQuery{Limit: uint64(limit), Offset: uint64(offset)}
+	if filter, ok := p.Args["filter"].(map[string]interface{}); ok {
+		q.Filter = models.ExampleFilter{
+			Name:           arg[string](filter, "name"),
+			IsPremium:      arg[bool](filter, "isPremium"),
+			MinLuckyNumber: arg[float64](filter, "minLuckyNumber"),
+			MaxLuckyNumber: arg[float64](filter, "maxLuckyNumber"),
+		}
+	}
+	if sort, ok := p.Args["sort"].(map[string]interface{}); ok {
+		q.Sort, _ = sort["field"].(string)
+		q.Desc, _ = sort["descending"].(bool)
+	}
+
+	examples, err := r.service.Search(p.Context, q)
+	if err != nil {
+		return nil, internalError(p.Context, "failed to search examples", err)
+	}
+	loader := loadersFrom(p.Context).examples
+	for _, e := range examples {
+		loader.Prime(e.ID, e)
+	}
+	return examples, nil
+}
+
+func (r *resolver) createExample(p graphql.ResolveParams) (interface{}, error) {
+	input := p.Args["input"].(map[string]interface{})
+	req := models.CreateExampleRequest{
+		Name:        input["name"].(string),
+		LuckyNumber: input["luckyNumber"].(float64),
+	}
+	req.IsPremium, _ = input["isPremium"].(bool)
+	if err := r.validator.Struct(req); err != nil {
+		return nil, apiError{errors.BadRequestError(errors.ErrValidationFailed, err.Error())}
+	}
+
+	example, err := r.service.Create(p.Context, req)
+	if err != nil {
+		return nil, internalError(p.Context, "failed to create example", err)
+	}
+	loadersFrom(p.Context).examples.Prime(example.ID, *example)
+	return example, nil
+}
+
+func (r *resolver) updateExample(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	input := p.Args["input"].(map[string]interface{})
+	req := models.UpdateExampleRequest{
+		Name:        arg[string](input, "name"),
+		LuckyNumber: arg[float64](input, "luckyNumber"),
+		IsPremium:   arg[bool](input, "isPremium"),
+	}
+
+	example, err := r.service.Update(p.Context, id, req)
+	if stderrors.Is(err, database.ErrNotFound) {
+		return nil, apiError{errors.NotFoundError(errors.ErrNotFound, "Example not found")}
+	} else if err != nil {
+		return nil, internalError(p.Context, "failed to update example", err)
+	}
+	loadersFrom(p.Context).examples.Prime(example.ID, *example)
+	return example, nil
+}
+
+func (r *resolver) deleteExample(p graphql.ResolveParams) (interface{}, error) {
+	id, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	err = r.service.Delete(p.Context, id)
+	if stderrors.Is(err, database.ErrNotFound) {
+		return nil, apiError{errors.NotFoundError(errors.ErrNotFound, "Example not found")}
+	} else if err != nil {
+		return nil, internalError(p.Context, "failed to delete example", err)
+	}
+	return id.String(), nil
+}
+
+// exampleField resolves a field of the Example type. Sources are examples
+// from lists and pointers to examples from the other fields.
+func exampleField(get func(models.Example) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		switch e := p.Source.(type) {
+		case models.Example:
+			return get(e), nil
+		case *models.Example:
+			return get(*e), nil
+		}
+		return nil, nil
+	}
+}
+
+func parseID(v interface{}) (uuid.UUID, error) {
+	s, _ := v.(string)
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, apiError{errors.BadRequestError(errors.ErrBadRequest, "Invalid UUID")}
+	}
+	return id, nil
+}
+
+// arg returns the value of an optional input field, nil when left out.
+func arg[T any](input map[string]interface{}, name string) *T {
+	if v, ok := input[name].(T); ok {
+		return &v
+	}
+	return nil
+}
+
+// apiError reports an *errors.APIError in a GraphQL response, with its
+// type as the code extension.
+type apiError struct {
+	err *errors.APIError
+}
+
+func (e apiError) Error() string {
+	if details, ok := e.err.Details.(string); ok && details != "" {
+		return details
+	}
+	return e.err.Message
+}
+
+func (e apiError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.err.Type}
+}
+
+// internalError logs err and hides it from the client.
+func internalError(ctx context.Context, msg string, err error) error {
+	logger.FromContext(ctx).Error(msg, "error", err)
+	return apiError{errors.InternalServerError(errors.ErrInternalServerError)}
+}
@@ -1,13 +1,17 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
 
+	"github.com/ctrixcode/go-chi-postgres/internal/auth"
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
 	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
 	"github.com/ctrixcode/go-chi-postgres/internal/services"
 	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/request"
 	"github.com/ctrixcode/go-chi-postgres/pkg/response"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
@@ -16,12 +20,16 @@ import (
 
 type ExampleHandler struct {
 	service   services.ExampleService
+	feed      *changefeed.Feed
+	auth      *auth.Authenticator
 	validator *validator.Validate
 }
 
-func NewExampleHandler(service services.ExampleService) *ExampleHandler {
+func NewExampleHandler(service services.ExampleService, feed *changefeed.Feed, authenticator *auth.Authenticator) *ExampleHandler {
 	return &ExampleHandler{
 		service:   service,
+		feed:      feed,
+		auth:      authenticator,
 		validator: validator.New(),
 	}
 }
@@ -30,16 +38,74 @@ func (h *ExampleHandler) RegisterRoutes() http.Handler {
 	r := chi.NewRouter()
 	r.Post("/", h.Create)
 	r.Get("/", h.List)
+	r.Get("/stream", h.Stream)
+	r.With(h.auth.Middleware).Get("/ws", h.Socket)
 	r.Get("/{id}", h.Get)
 	r.Put("/{id}", h.Update)
 	r.Delete("/{id}", h.Delete)
 	return r
 }
 
+// Routes documents the routes registered by RegisterRoutes.
+func (h *ExampleHandler) Routes() []openapi.Route {
+	id := openapi.PathParam("id", "Example ID", uuid.UUID{})
+	idempotencyKey := openapi.HeaderParam("Idempotency-Key", "Replays the stored response when a request is retried", "max=255")
+
+	return []openapi.Route{
+		{
+			Method: http.MethodPost, Path: "/", OperationID: "createExample", Summary: "Create an example",
+			Params: []openapi.Param{idempotencyKey}, Request: models.CreateExampleRequest{}, Response: models.Example{},
+			Status: http.StatusCreated, Errors: []int{http.StatusBadRequest, http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodGet, Path: "/", OperationID: "listExamples", Summary: "List examples",
+			Params: []openapi.Param{
+				openapi.QueryParam("limit", "Maximum number of examples, 10 when unset", uint64(0), ""),
+				openapi.QueryParam("offset", "Number of examples to skip", uint64(0), ""),
+			},
+			Response: []models.Example{},
+			Errors:   []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodGet, Path: "/stream", OperationID: "streamExampleChanges", Summary: "Stream changes to examples",
+			Description: "Server-Sent Events named example.created, example.updated and example.deleted, carrying the example, or only its id when deleted. " +
+				"Reconnecting with Last-Event-ID replays the changes missed; a reset event means they are no longer available and the list should be reloaded.",
+			Params:      []openapi.Param{openapi.HeaderParam("Last-Event-ID", "ID of the last event received", "max=20")},
+			ContentType: "text/event-stream",
+			Errors:      []int{http.StatusBadRequest, http.StatusTooManyRequests},
+		},
+		{
+			Method: http.MethodGet, Path: "/ws", OperationID: "subscribeExampleChanges", Summary: "Subscribe to changes to filtered examples",
+			Description: "A WebSocket on which clients send {\"type\": \"subscribe\", \"id\": ..., \"filter\": {...}} and {\"type\": \"unsubscribe\", \"id\": ...}. " +
+				"A subscription gets added with the example when one starts matching its filter, updated with the changed fields and removed when it stops matching or is deleted. " +
+				"Clients falling behind are closed with status 1013 and should resubscribe.",
+			Params: []openapi.Param{openapi.QueryParam("access_token", "Bearer token, for clients that cannot set the Authorization header", "", "")},
+			Status: http.StatusSwitchingProtocols,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusUpgradeRequired, http.StatusTooManyRequests},
+		},
+		{
+			Method: http.MethodGet, Path: "/{id}", OperationID: "getExample", Summary: "Get an example",
+			Params: []openapi.Param{id}, Response: models.Example{},
+			Errors: []int{http.StatusBadRequest, http.StatusNotFound, http.StatusTooManyRequests},
+		},
+		{
+			Method: http.MethodPut, Path: "/{id}", OperationID: "updateExample", Summary: "Update an example",
+			Description: "Only the fields present in the body are changed.",
+			Params:      []openapi.Param{id}, Request: models.UpdateExampleRequest{}, Response: models.Example{},
+			Errors: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodDelete, Path: "/{id}", OperationID: "deleteExample", Summary: "Delete an example",
+			Params: []openapi.Param{id},
+			Errors: []int{http.StatusBadRequest, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+	}
+}
+
 func (h *ExampleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var req models.CreateExampleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, err.Error()))
+	if err := request.DecodeJSON(r, &req, request.DisallowUnknownFields()); err != nil {
+		response.JSONError(w, err)
 		return
 	}
 
@@ -50,6 +116,7 @@ func (h *ExampleHandler) Create(w http.ResponseWriter, r *http.Request) {
 
 	example, err := h.service.Create(r.Context(), req)
 	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to create example", "error", err)
 		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
 		return
 	}
@@ -67,6 +134,7 @@ func (h *ExampleHandler) Get(w http.ResponseWriter, r *http.Request) {
 
 	example, err := h.service.GetByID(r.Context(), id)
 	if err != nil {
+		logger.FromContext(r.Context()).Debug("example lookup failed", "example_id", id, "error", err)
 		response.JSONError(w, errors.NotFoundError(errors.ErrNotFound, "Example not found"))
 		return
 	}
@@ -86,6 +154,7 @@ func (h *ExampleHandler) List(w http.ResponseWriter, r *http.Request) {
 
 	examples, err := h.service.List(r.Context(), limit, offset)
 	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to list examples", "error", err)
 		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
 		return
 	}
@@ -102,13 +171,14 @@ func (h *ExampleHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req models.UpdateExampleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, err.Error()))
+	if err := request.DecodeJSON(r, &req, request.DisallowUnknownFields()); err != nil {
+		response.JSONError(w, err)
 		return
 	}
 
 	example, err := h.service.Update(r.Context(), id, req)
 	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to update example", "example_id", id, "error", err)
 		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
 		return
 	}
@@ -125,6 +195,7 @@ func (h *ExampleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.service.Delete(r.Context(), id); err != nil {
+		logger.FromContext(r.Context()).Error("failed to delete example", "example_id", id, "error", err)
 		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
 		return
 	}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	// socketReadLimit bounds the messages clients send, which are only
+	// requests.
+	socketReadLimit = 4 << 10
+	// socketReplies is how many replies may wait for the writer; a client
+	// sending more requests without reading is closed.
+	socketReplies = 16
+)
+
+// Socket serves a WebSocket on which clients subscribe to the examples
+// matching filters. Every subscription gets an example as added when it
+// starts matching, the fields an update changed while it matches, and
+// removed when it stops matching or is deleted. Clients falling behind are
+// closed with StatusTryAgainLater and resubscribe.
+func (h *ExampleHandler) Socket(w http.ResponseWriter, r *http.Request) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		response.JSONError(w, errors.NewAPIError(http.StatusUpgradeRequired, errors.ErrUpgradeRequired, nil, true))
+		return
+	}
+
+	log := logger.FromContext(r.Context())
+	opts := h.feed.Options()
+
+	// The hijacked connection keeps the server's deadlines otherwise
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: opts.Origins})
+	if err != nil {
+		// Accept has responded
+		log.Debug("websocket handshake failed", "error", err)
+		return
+	}
+	conn.SetReadLimit(socketReadLimit)
+
+	sub := h.feed.Subscribe()
+	defer sub.Close()
+
+	s := &socket{
+		conn:      conn,
+		sub:       sub,
+		opts:      opts,
+		log:       log,
+		validator: h.validator,
+		replies:   make(chan models.ExampleSocketMessage, socketReplies),
+		filters:   make(map[string]models.ExampleFilter),
+		status:    websocket.StatusNormalClosure,
+	}
+	s.serve(r.Context())
+}
+
+// socket is one client connection. Only the write loop writes messages, so
+// replies and changes keep their order.
+type socket struct {
+	conn      *websocket.Conn
+	sub       *changefeed.Subscription
+	opts      changefeed.Options
+	log       *slog.Logger
+	validator *validator.Validate
+	replies   chan models.ExampleSocketMessage
+
+	mu      sync.Mutex
+	filters map[string]models.ExampleFilter
+	// status and reason close the connection; the first failure sets them.
+	status websocket.StatusCode
+	reason string
+	failed bool
+}
+
+func (s *socket) serve(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go s.read(ctx, cancel)
+	if s.opts.Heartbeat > 0 {
+		go s.keepalive(ctx, cancel)
+	}
+	s.write(ctx, cancel)
+
+	s.mu.Lock()
+	status, reason := s.status, s.reason
+	s.mu.Unlock()
+	s.conn.Close(status, reason)
+}
+
+// fail records why the connection is closed and stops serving it.
+func (s *socket) fail(cancel context.CancelFunc, status websocket.StatusCode, reason string) {
+	s.mu.Lock()
+	if !s.failed {
+		s.status, s.reason, s.failed = status, reason, true
+	}
+	s.mu.Unlock()
+	cancel()
+}
+
+func (s *socket) read(ctx context.Context, cancel context.CancelFunc) {
+	for {
+		typ, data, err := s.conn.Read(ctx)
+		if err != nil {
+			if status := websocket.CloseStatus(err); status == -1 && ctx.Err() == nil {
+				s.log.Debug("websocket read failed", "error", err)
+			}
+			cancel()
+			return
+		}
+		reply := s.handle(typ, data)
+		select {
+		case s.replies <- reply:
+		default:
+			s.fail(cancel, websocket.StatusPolicyViolation, "too many requests")
+			return
+		}
+	}
+}
+
+// handle applies a request and returns the reply to it.
+func (s *socket) handle(typ websocket.MessageType, data []byte) models.ExampleSocketMessage {
+	var req models.ExampleSocketRequest
+	if typ != websocket.MessageText || json.Unmarshal(data, &req) != nil {
+		return models.ExampleSocketMessage{Type: models.SocketError, Error: "Invalid JSON"}
+	}
+	if err := s.validator.Struct(req); err != nil {
+		return models.ExampleSocketMessage{Type: models.SocketError, ID: req.ID, Error: err.Error()}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch req.Type {
+	case models.SocketSubscribe:
+		if _, ok := s.filters[req.ID]; !ok && len(s.filters) >= s.opts.MaxSubscriptions {
+			return models.ExampleSocketMessage{Type: models.SocketError, ID: req.ID, Error: "Too many subscriptions"}
+		}
+		s.filters[req.ID] = req.Filter
+		return models.ExampleSocketMessage{Type: models.SocketSubscribed, ID: req.ID}
+	default:
+		if _, ok := s.filters[req.ID]; !ok {
+			return models.ExampleSocketMessage{Type: models.SocketError, ID: req.ID, Error: "Unknown subscription"}
+		}
+		delete(s.filters, req.ID)
+		return models.ExampleSocketMessage{Type: models.SocketUnsubscribed, ID: req.ID}
+	}
+}
+
+func (s *socket) write(ctx context.Context, cancel context.CancelFunc) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-s.replies:
+			if err := s.send(ctx, msg); err != nil {
+				cancel()
+				return
+			}
+		case c, ok := <-s.sub.Changes():
+			if !ok {
+				if stderrors.Is(s.sub.Err(), changefeed.ErrClosed) {
+					s.fail(cancel, websocket.StatusGoingAway, "server shutting down")
+				} else {
+					s.fail(cancel, websocket.StatusTryAgainLater, "fell behind")
+				}
+				return
+			}
+			if err := s.deliver(ctx, c); err != nil {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// deliver sends c to the subscriptions it concerns.
+func (s *socket) deliver(ctx context.Context, c changefeed.Change) error {
+	s.mu.Lock()
+	filters := make(map[string]models.ExampleFilter, len(s.filters))
+	for id, filter := range s.filters {
+		filters[id] = filter
+	}
+	s.mu.Unlock()
+
+	for id, filter := range filters {
+		msg, ok, err := changefeed.View(filter, c)
+		if err != nil {
+			s.log.Error("reading example change failed", "change_id", c.ID, "error", err)
+			return nil
+		}
+		if !ok {
+			continue
+		}
+		msg.ID = id
+		if err := s.send(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *socket) send(ctx context.Context, msg models.ExampleSocketMessage) error {
+	if s.opts.WriteTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.opts.WriteTimeout)
+		defer cancel()
+	}
+	return wsjson.Write(ctx, s.conn, msg)
+}
+
+// keepalive pings the client every heartbeat and closes the connection when
+// a pong does not come back within the write timeout.
+func (s *socket) keepalive(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(s.opts.Heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pingCtx, done := context.WithTimeout(ctx, max(s.opts.WriteTimeout, time.Second))
+			err := s.conn.Ping(pingCtx)
+			done()
+			if err != nil {
+				if ctx.Err() == nil {
+					s.fail(cancel, websocket.StatusPolicyViolation, "pong timeout")
+				}
+				return
+			}
+		}
+	}
+}
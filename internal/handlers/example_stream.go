@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+)
+
+// Stream sends changes to examples as Server-Sent Events named after the
+// change type, with the change id as the event id. A client reconnecting
+// with Last-Event-ID first gets the changes it missed, or a reset event when
+// they are no longer logged and it should reload instead.
+func (h *ExampleHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	var after int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, "Invalid Last-Event-ID"))
+			return
+		}
+		after = id
+	}
+
+	ctx := r.Context()
+	log := logger.FromContext(ctx)
+	opts := h.feed.Options()
+
+	// A stream outlives the server's timeouts; each write gets a deadline of
+	// its own instead. Writers that cannot set deadlines have none to lift.
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	// Subscribe before replaying so no change falls in between
+	sub := h.feed.Subscribe()
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keeps nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	stream := &eventStream{w: w, rc: rc, timeout: opts.WriteTimeout, last: after}
+	if opts.Retry > 0 {
+		if err := stream.write("retry: %d\n\n", opts.Retry.Milliseconds()); err != nil {
+			return
+		}
+	} else if err := rc.Flush(); err != nil {
+		return
+	}
+	if after > 0 {
+		err := h.feed.Since(ctx, after, stream.send)
+		switch {
+		case stderrors.Is(err, changefeed.ErrExpired):
+			if err := stream.write("event: reset\ndata: {}\n\n"); err != nil {
+				return
+			}
+		case err != nil:
+			// The client reconnects and resumes from the last change sent
+			log.Error("replaying example changes failed", "after", after, "error", err)
+			return
+		}
+	}
+
+	var heartbeat <-chan time.Time
+	if opts.Heartbeat > 0 {
+		ticker := time.NewTicker(opts.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case c, ok := <-sub.Changes():
+			if !ok {
+				// Fell behind or shutting down; the client resumes from the
+				// last change sent
+				return
+			}
+			if err := stream.send(c); err != nil {
+				return
+			}
+		case <-heartbeat:
+			if err := stream.write(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// eventStream writes Server-Sent Events, flushing each one.
+type eventStream struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+	// last is the id of the newest change sent; changes replayed from the
+	// log are skipped when they arrive from the subscription as well.
+	last int64
+}
+
+func (s *eventStream) send(c changefeed.Change) error {
+	if c.ID <= s.last {
+		return nil
+	}
+	// Data must fit on one line
+	var data bytes.Buffer
+	if err := json.Compact(&data, c.Data); err != nil {
+		return err
+	}
+	if err := s.write("id: %d\nevent: %s\ndata: %s\n\n", c.ID, c.Type, data.Bytes()); err != nil {
+		return err
+	}
+	s.last = c.ID
+	return nil
+}
+
+func (s *eventStream) write(format string, args ...any) error {
+	if s.timeout > 0 {
+		s.rc.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+	if _, err := fmt.Fprintf(s.w, format, args...); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
@@ -2,8 +2,68 @@ package handlers
 
 import (
 	"net/http"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/health"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+	"github.com/go-chi/chi/v5"
 )
 
 func HelloWorldHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Hello World"))
 }
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+	}
+}
+
+func (h *HealthHandler) RegisterRoutes() http.Handler {
+	r := chi.NewRouter()
+	r.Get("/live", h.Live)
+	r.Get("/ready", h.Ready)
+	r.Get("/startup", h.Startup)
+	return r
+}
+
+// Routes documents the routes registered by RegisterRoutes.
+func (h *HealthHandler) Routes() []openapi.Route {
+	probe := func(path, id, summary string) openapi.Route {
+		return openapi.Route{
+			Method: http.MethodGet, Path: path, OperationID: id, Summary: summary,
+			Response: health.Report{}, Errors: []int{http.StatusServiceUnavailable},
+		}
+	}
+	return []openapi.Route{
+		probe("/live", "liveness", "Liveness probe"),
+		probe("/ready", "readiness", "Readiness probe"),
+		probe("/startup", "startup", "Startup probe"),
+	}
+}
+
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.registry.Live(r.Context()))
+}
+
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.registry.Ready(r.Context()))
+}
+
+func (h *HealthHandler) Startup(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, h.registry.Startup(r.Context()))
+}
+
+func writeReport(w http.ResponseWriter, report health.Report) {
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != health.StatusPass {
+		response.JSONError(w, errors.ServiceUnavailableError(errors.ErrServiceUnavailable, report))
+		return
+	}
+	response.JSONSuccess(w, report, http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+	"github.com/go-chi/chi/v5"
+)
+
+// JobsHandler serves the admin endpoints for the job queue.
+type JobsHandler struct {
+	client *jobs.Client
+}
+
+func NewJobsHandler(client *jobs.Client) *JobsHandler {
+	return &JobsHandler{client: client}
+}
+
+// RequeueDead makes a dead job available again.
+func (h *JobsHandler) RequeueDead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, "Invalid job id"))
+		return
+	}
+
+	if err := h.client.RequeueDead(r.Context(), id); err != nil {
+		if stderrors.Is(err, database.ErrNotFound) {
+			response.JSONError(w, errors.NotFoundError(errors.ErrNotFound, "No dead job with this id"))
+			return
+		}
+		logger.FromContext(r.Context()).Error("failed to requeue job", "job_id", id, "error", err)
+		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("dead job requeued", "job_id", id)
+	response.JSONSuccess(w, map[string]int64{"id": id}, http.StatusOK, "Job requeued")
+}
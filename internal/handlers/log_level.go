@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/request"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+)
+
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+func GetLogLevel(w http.ResponseWriter, r *http.Request) {
+	response.JSONSuccess(w, map[string]string{"level": logger.Level().String()}, http.StatusOK)
+}
+
+func SetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevelRequest
+	if err := request.DecodeJSON(r, &req, request.DisallowUnknownFields()); err != nil {
+		response.JSONError(w, err)
+		return
+	}
+
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil || req.Level == "" {
+		response.JSONError(w, errors.BadRequestError(errors.ErrValidationFailed, "level must be one of debug, info, warn, error"))
+		return
+	}
+
+	previous := logger.Level()
+	logger.SetLevel(level)
+	logger.FromContext(r.Context()).Log(r.Context(), slog.LevelWarn, "log level changed", "from", previous.String(), "to", level.String())
+
+	response.JSONSuccess(w, map[string]string{"level": level.String()}, http.StatusOK, "Log level updated")
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/scheduler"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+)
+
+// SchedulesHandler serves the admin view of scheduled tasks.
+type SchedulesHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewSchedulesHandler(s *scheduler.Scheduler) *SchedulesHandler {
+	return &SchedulesHandler{scheduler: s}
+}
+
+// List shows the leader and each task's last and next run.
+func (h *SchedulesHandler) List(w http.ResponseWriter, r *http.Request) {
+	status, err := h.scheduler.Status(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to load schedules", "error", err)
+		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
+		return
+	}
+
+	response.JSONSuccess(w, status, http.StatusOK)
+}
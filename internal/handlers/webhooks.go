@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	stderrors "errors"
+	"net/http"
+	"strconv"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/auth"
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/internal/webhooks"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/request"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// WebhooksHandler manages webhook subscriptions and their delivery log.
+// Webhooks make the API send requests, so every route requires an
+// authenticated client.
+type WebhooksHandler struct {
+	service   *webhooks.Service
+	auth      *auth.Authenticator
+	validator *validator.Validate
+}
+
+func NewWebhooksHandler(service *webhooks.Service, authenticator *auth.Authenticator) *WebhooksHandler {
+	return &WebhooksHandler{
+		service:   service,
+		auth:      authenticator,
+		validator: validator.New(),
+	}
+}
+
+func (h *WebhooksHandler) RegisterRoutes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(h.auth.Middleware)
+	r.Post("/", h.Create)
+	r.Get("/", h.List)
+	r.Get("/{id}", h.Get)
+	r.Put("/{id}", h.Update)
+	r.Delete("/{id}", h.Delete)
+	r.Get("/{id}/deliveries", h.Deliveries)
+	r.Post("/{id}/deliveries/{deliveryID}/redeliver", h.Redeliver)
+	return r
+}
+
+// Routes documents the routes registered by RegisterRoutes.
+func (h *WebhooksHandler) Routes() []openapi.Route {
+	id := openapi.PathParam("id", "Webhook ID", uuid.UUID{})
+
+	return []openapi.Route{
+		{
+			Method: http.MethodPost, Path: "/", OperationID: "createWebhook", Summary: "Create a webhook",
+			Description: "The response carries the secret deliveries are signed with; it is not returned again.",
+			Request:     models.CreateWebhookRequest{}, Response: models.Webhook{}, Status: http.StatusCreated,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodGet, Path: "/", OperationID: "listWebhooks", Summary: "List webhooks",
+			Response: []models.Webhook{},
+			Errors:   []int{http.StatusUnauthorized, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodGet, Path: "/{id}", OperationID: "getWebhook", Summary: "Get a webhook",
+			Params: []openapi.Param{id}, Response: models.Webhook{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodPut, Path: "/{id}", OperationID: "updateWebhook", Summary: "Update a webhook",
+			Description: "Only the fields present in the body are changed. Enabling a disabled webhook resets its failure count.",
+			Params:      []openapi.Param{id}, Request: models.UpdateWebhookRequest{}, Response: models.Webhook{},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodDelete, Path: "/{id}", OperationID: "deleteWebhook", Summary: "Delete a webhook",
+			Params: []openapi.Param{id},
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodGet, Path: "/{id}/deliveries", OperationID: "listWebhookDeliveries", Summary: "List a webhook's deliveries",
+			Description: "Every attempt is logged, newest first.",
+			Params: []openapi.Param{
+				id,
+				openapi.QueryParam("limit", "Maximum number of deliveries, 50 when unset", uint64(0), "max=500"),
+				openapi.QueryParam("offset", "Number of deliveries to skip", uint64(0), ""),
+			},
+			Response: []models.WebhookDelivery{},
+			Errors:   []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+		{
+			Method: http.MethodPost, Path: "/{id}/deliveries/{deliveryID}/redeliver", OperationID: "redeliverWebhook", Summary: "Redeliver an event",
+			Description: "Sends the event of a logged delivery again, with a fresh set of attempts.",
+			Params:      []openapi.Param{id, openapi.PathParam("deliveryID", "Delivery ID", int64(0))},
+			Response:    map[string]int64{}, Status: http.StatusAccepted,
+			Errors: []int{http.StatusBadRequest, http.StatusUnauthorized, http.StatusNotFound, http.StatusConflict, http.StatusTooManyRequests, http.StatusInternalServerError},
+		},
+	}
+}
+
+func (h *WebhooksHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if err := request.DecodeJSON(r, &req, request.DisallowUnknownFields()); err != nil {
+		response.JSONError(w, err)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.JSONError(w, errors.BadRequestError(errors.ErrValidationFailed, err.Error()))
+		return
+	}
+
+	webhook, err := h.service.Create(r.Context(), req)
+	if err != nil {
+		webhookError(w, r, err, "failed to create webhook")
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("webhook created", "webhook_id", webhook.ID, "url", webhook.URL)
+	response.JSONSuccess(w, webhook, http.StatusCreated, "Webhook created successfully")
+}
+
+func (h *WebhooksHandler) List(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.List(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context()).Error("failed to list webhooks", "error", err)
+		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
+		return
+	}
+
+	response.JSONSuccess(w, list, http.StatusOK)
+}
+
+func (h *WebhooksHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		webhookError(w, r, err, "failed to get webhook")
+		return
+	}
+
+	response.JSONSuccess(w, webhook, http.StatusOK)
+}
+
+func (h *WebhooksHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := request.DecodeJSON(r, &req, request.DisallowUnknownFields()); err != nil {
+		response.JSONError(w, err)
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		response.JSONError(w, errors.BadRequestError(errors.ErrValidationFailed, err.Error()))
+		return
+	}
+
+	webhook, err := h.service.Update(r.Context(), id, req)
+	if err != nil {
+		webhookError(w, r, err, "failed to update webhook")
+		return
+	}
+
+	response.JSONSuccess(w, webhook, http.StatusOK, "Webhook updated successfully")
+}
+
+func (h *WebhooksHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		webhookError(w, r, err, "failed to delete webhook")
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("webhook deleted", "webhook_id", id)
+	response.JSONSuccess(w, nil, http.StatusOK, "Webhook deleted successfully")
+}
+
+func (h *WebhooksHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, _ := strconv.ParseUint(r.URL.Query().Get("limit"), 10, 64)
+	if limit == 0 {
+		limit = 50
+	}
+	offset, _ := strconv.ParseUint(r.URL.Query().Get("offset"), 10, 64)
+
+	deliveries, err := h.service.Deliveries(r.Context(), id, limit, offset)
+	if err != nil {
+		webhookError(w, r, err, "failed to list webhook deliveries")
+		return
+	}
+
+	response.JSONSuccess(w, deliveries, http.StatusOK)
+}
+
+func (h *WebhooksHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, "Invalid delivery id"))
+		return
+	}
+
+	job, err := h.service.Redeliver(r.Context(), id, deliveryID)
+	if err != nil {
+		webhookError(w, r, err, "failed to redeliver webhook")
+		return
+	}
+
+	logger.FromContext(r.Context()).Info("webhook redelivery enqueued", "webhook_id", id, "delivery_id", deliveryID, "job_id", job.ID)
+	response.JSONSuccess(w, map[string]int64{"job_id": job.ID}, http.StatusAccepted, "Redelivery enqueued")
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, "Invalid UUID"))
+		return uuid.UUID{}, false
+	}
+	return id, true
+}
+
+// webhookError maps service errors to responses, logging unexpected ones
+// with msg.
+func webhookError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case stderrors.Is(err, database.ErrNotFound):
+		response.JSONError(w, errors.NotFoundError(errors.ErrNotFound, "Webhook or delivery not found"))
+	case stderrors.Is(err, webhooks.ErrDisabled):
+		response.JSONError(w, errors.ConflictError(errors.ErrWebhookDisabled))
+	case stderrors.Is(err, webhooks.ErrForbiddenDestination):
+		response.JSONError(w, errors.BadRequestError(errors.ErrValidationFailed, err.Error()))
+	default:
+		logger.FromContext(r.Context()).Error(msg, "error", err)
+		response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError, err.Error()))
+	}
+}
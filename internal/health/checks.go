@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DatabaseCheck pings the database.
+func DatabaseCheck(db *sqlx.DB) CheckFunc {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_.+\.(sql|go)$`)
+
+// LatestMigrationVersion returns the highest goose version found in dir.
+func LatestMigrationVersion(dir string) (int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := migrationFilePattern.FindStringSubmatch(filepath.Base(entry.Name()))
+		if m == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest, nil
+}
+
+// MigrationCheck verifies that the database schema is at the version of the
+// newest migration in dir.
+func MigrationCheck(db *sqlx.DB, dir string) CheckFunc {
+	return func(ctx context.Context) error {
+		expected, err := LatestMigrationVersion(dir)
+		if err != nil {
+			return fmt.Errorf("read migrations: %w", err)
+		}
+
+		var current int64
+		query := "SELECT COALESCE(MAX(version_id), 0) FROM goose_db_version WHERE is_applied"
+		if err := db.GetContext(ctx, &current, query); err != nil {
+			return fmt.Errorf("read schema version: %w", err)
+		}
+
+		if current != expected {
+			return fmt.Errorf("schema version %d does not match latest migration %d", current, expected)
+		}
+		return nil
+	}
+}
+
+// DiskSpaceCheck fails when the filesystem holding path has fewer than
+// minFreeBytes available.
+func DiskSpaceCheck(path string, minFreeBytes uint64) CheckFunc {
+	return func(ctx context.Context) error {
+		free, err := freeDiskSpace(path)
+		if err != nil {
+			return err
+		}
+		if free < minFreeBytes {
+			return fmt.Errorf("only %d bytes free on %s, need %d", free, path, minFreeBytes)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,205 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusFail Status = "fail"
+)
+
+// Probe identifies which endpoint(s) a check contributes to. Values can be
+// combined, e.g. Readiness|Startup.
+type Probe uint8
+
+const (
+	Liveness Probe = 1 << iota
+	Readiness
+	Startup
+)
+
+const defaultTimeout = 2 * time.Second
+
+type CheckFunc func(ctx context.Context) error
+
+type Check struct {
+	Name     string
+	Probes   Probe
+	Timeout  time.Duration
+	CacheTTL time.Duration
+	Func     CheckFunc
+}
+
+type Result struct {
+	Status    Status    `json:"status"`
+	Duration  string    `json:"duration"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status Status            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+type registeredCheck struct {
+	Check
+
+	mu     sync.Mutex
+	last   Result
+	lastAt time.Time
+}
+
+type Registry struct {
+	mu     sync.RWMutex
+	checks []*registeredCheck
+
+	shuttingDown atomic.Bool
+	started      atomic.Bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check to the registry. Checks with the same name replace
+// the previously registered one.
+func (r *Registry) Register(c Check) {
+	if c.Timeout <= 0 {
+		c.Timeout = defaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, existing := range r.checks {
+		if existing.Name == c.Name {
+			r.checks[i] = &registeredCheck{Check: c}
+			return
+		}
+	}
+	r.checks = append(r.checks, &registeredCheck{Check: c})
+}
+
+// SetShuttingDown makes every subsequent readiness probe fail so load
+// balancers stop routing traffic while in-flight requests drain.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+func (r *Registry) IsShuttingDown() bool {
+	return r.shuttingDown.Load()
+}
+
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, Liveness)
+}
+
+func (r *Registry) Ready(ctx context.Context) Report {
+	report := r.run(ctx, Readiness)
+	if r.IsShuttingDown() {
+		report.Status = StatusFail
+		report.Checks["shutdown"] = Result{
+			Status:    StatusFail,
+			Error:     "server is shutting down",
+			CheckedAt: time.Now(),
+		}
+	}
+	return report
+}
+
+// Startup runs startup checks until they pass once, after which the probe
+// reports success without re-running them.
+func (r *Registry) Startup(ctx context.Context) Report {
+	if r.started.Load() {
+		return Report{Status: StatusPass, Checks: map[string]Result{}}
+	}
+
+	report := r.run(ctx, Startup)
+	if report.Status == StatusPass {
+		r.started.Store(true)
+	}
+	return report
+}
+
+func (r *Registry) run(ctx context.Context, probe Probe) Report {
+	r.mu.RLock()
+	var selected []*registeredCheck
+	for _, c := range r.checks {
+		if c.Probes&probe != 0 {
+			selected = append(selected, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	results := make([]Result, len(selected))
+	var wg sync.WaitGroup
+	for i, c := range selected {
+		wg.Add(1)
+		go func(i int, c *registeredCheck) {
+			defer wg.Done()
+			results[i] = c.execute(ctx)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusPass, Checks: make(map[string]Result, len(selected))}
+	for i, c := range selected {
+		report.Checks[c.Name] = results[i]
+		if results[i].Status != StatusPass {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+func (c *registeredCheck) execute(ctx context.Context) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.CacheTTL > 0 && !c.lastAt.IsZero() && time.Since(c.lastAt) < c.CacheTTL {
+		return c.last
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errCh := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				errCh <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		errCh <- c.Func(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errCh:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %s", c.Timeout)
+	}
+
+	result := Result{
+		Status:    StatusPass,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+
+	c.last = result
+	c.lastAt = start
+	return result
+}
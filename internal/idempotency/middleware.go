@@ -0,0 +1,185 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/requestid"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	Header         = "Idempotency-Key"
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+)
+
+// Options are read on every request so reloaded settings apply immediately.
+type Options struct {
+	Enabled bool
+	// TTL is how long completed responses are kept for replay.
+	TTL time.Duration
+	// LockTimeout is how long an in-flight request holds its key before a
+	// retry may take it over, e.g. after the original process crashed.
+	LockTimeout time.Duration
+}
+
+// perRequestHeaders are set by other middleware for every response and are
+// not part of the stored response. The stored body is uncompressed, so the
+// encoding headers are recomputed on replay.
+var perRequestHeaders = []string{"Date", "Set-Cookie", "Retry-After", "Content-Encoding", "Content-Length", requestid.Header}
+
+type Middleware struct {
+	store   Store
+	options func() Options
+}
+
+func NewMiddleware(store Store, options func() Options) *Middleware {
+	return &Middleware{store: store, options: options}
+}
+
+// Handler makes POST requests carrying an Idempotency-Key safe to retry. The
+// first request runs and its response is stored; repeats with the same body
+// get the stored response, with a different body 409, and while the first is
+// still running 409 as well. 5xx responses are not stored so clients can
+// retry them.
+func (m *Middleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		opts := m.options()
+		if r.Method != http.MethodPost || key == "" || !opts.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !validKey(key) {
+			response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, "Idempotency-Key must be 1-255 printable characters"))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if stderrors.As(err, &maxErr) {
+				response.JSONError(w, errors.PayloadTooLargeError(errors.ErrPayloadTooLarge,
+					fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit)))
+				return
+			}
+			response.JSONError(w, errors.BadRequestError(errors.ErrBadRequest, "failed to read request body"))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := r.Context()
+		log := logger.FromContext(ctx)
+		scope := scopeOf(r)
+		fingerprint := fingerprintOf(r, body)
+
+		rec, acquired, err := m.store.Acquire(ctx, scope, key, fingerprint, opts.LockTimeout, opts.TTL)
+		if err != nil {
+			log.Error("idempotency key lookup failed", "error", err)
+			response.JSONError(w, errors.InternalServerError(errors.ErrInternalServerError))
+			return
+		}
+		if !acquired {
+			switch {
+			case rec.Fingerprint != fingerprint:
+				response.JSONError(w, errors.ConflictError(errors.ErrIdempotencyKeyReused))
+			case rec.Response == nil:
+				w.Header().Set("Retry-After", "1")
+				response.JSONError(w, errors.ConflictError(errors.ErrIdempotencyKeyInUse))
+			default:
+				replay(w, rec.Response)
+			}
+			return
+		}
+
+		// Store the outcome even if the client has gone away
+		storeCtx := context.WithoutCancel(ctx)
+		completed := false
+		defer func() {
+			if !completed {
+				if err := m.store.Release(storeCtx, scope, key, rec); err != nil {
+					log.Error("failed to release idempotency key", "error", err)
+				}
+			}
+		}()
+
+		var buf bytes.Buffer
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		ww.Tee(&buf)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= 500 {
+			return
+		}
+
+		resp := Response{Status: status, Header: storedHeader(w.Header()), Body: buf.Bytes()}
+		if err := m.store.Complete(storeCtx, scope, key, resp); err != nil {
+			log.Error("failed to store idempotent response", "error", err)
+			return
+		}
+		completed = true
+	})
+}
+
+func replay(w http.ResponseWriter, resp *Response) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+func storedHeader(h http.Header) http.Header {
+	out := h.Clone()
+	for _, k := range perRequestHeaders {
+		out.Del(k)
+	}
+	for k := range out {
+		if strings.HasPrefix(k, "Ratelimit-") || strings.HasPrefix(k, "Access-Control-") {
+			delete(out, k)
+		}
+	}
+	return out
+}
+
+// scopeOf keeps keys from different users and endpoints apart.
+func scopeOf(r *http.Request) string {
+	return logger.UserIDFromContext(r.Context()) + " " + r.Method + " " + r.URL.Path
+}
+
+func fingerprintOf(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func validKey(key string) bool {
+	if len(key) > maxKeyLength {
+		return false
+	}
+	for _, c := range key {
+		if c < 0x20 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Response is a stored response replayed for repeated requests.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is the stored state of a key. Response is nil while the original
+// request is still in flight, which holds the key until LockedUntil.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+	LockedUntil time.Time
+}
+
+// Store persists idempotency keys. Keys are unique per scope.
+type Store interface {
+	// Acquire claims a key for a new request and returns the claim. When the
+	// key already exists acquired is false and the existing record is
+	// returned instead. Times are on the store's clock, so servers with
+	// skewed clocks agree on when a key is free.
+	Acquire(ctx context.Context, scope, key, fingerprint string, lockTimeout, ttl time.Duration) (rec *Record, acquired bool, err error)
+	Complete(ctx context.Context, scope, key string, resp Response) error
+	// Release forgets a key whose request did not complete, so it can be
+	// retried. It leaves the key alone if another request has since taken
+	// over the claim rec.
+	Release(ctx context.Context, scope, key string, rec *Record) error
+}
+
+type postgresStore struct {
+	db    *sqlx.DB
+	calls atomic.Int64
+}
+
+func NewPostgresStore(db *sqlx.DB) Store {
+	return &postgresStore{db: db}
+}
+
+type recordRow struct {
+	Fingerprint     string        `db:"fingerprint"`
+	ResponseStatus  sql.NullInt32 `db:"response_status"`
+	ResponseHeaders []byte        `db:"response_headers"`
+	ResponseBody    []byte        `db:"response_body"`
+}
+
+func (s *postgresStore) Acquire(ctx context.Context, scope, key, fingerprint string, lockTimeout, ttl time.Duration) (*Record, bool, error) {
+	// Purge expired keys now and then
+	if s.calls.Add(1)%1000 == 0 {
+		if _, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < now()"); err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Take over keys that expired or whose request died without completing
+	var lockedUntil time.Time
+	err := s.db.GetContext(ctx, &lockedUntil, `INSERT INTO idempotency_keys (scope, key, fingerprint, locked_until, expires_at, created_at)
+		VALUES ($1, $2, $3, now() + $4::bigint * interval '1 millisecond', now() + $5::bigint * interval '1 millisecond', now())
+		ON CONFLICT (scope, key) DO UPDATE SET
+			fingerprint = EXCLUDED.fingerprint,
+			locked_until = EXCLUDED.locked_until,
+			expires_at = EXCLUDED.expires_at,
+			created_at = EXCLUDED.created_at,
+			response_status = NULL,
+			response_headers = NULL,
+			response_body = NULL
+		WHERE idempotency_keys.expires_at < now()
+			OR (idempotency_keys.response_status IS NULL AND idempotency_keys.locked_until < now())
+		RETURNING locked_until`,
+		scope, key, fingerprint, lockTimeout.Milliseconds(), ttl.Milliseconds())
+	if err == nil {
+		return &Record{Fingerprint: fingerprint, LockedUntil: lockedUntil}, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	var row recordRow
+	err = s.db.GetContext(ctx, &row,
+		"SELECT fingerprint, response_status, response_headers, response_body FROM idempotency_keys WHERE scope = $1 AND key = $2",
+		scope, key)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released between the two statements, report it as still in flight
+		return &Record{Fingerprint: fingerprint}, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	rec := &Record{Fingerprint: row.Fingerprint}
+	if row.ResponseStatus.Valid {
+		rec.Response = &Response{Status: int(row.ResponseStatus.Int32), Body: row.ResponseBody}
+		if err := json.Unmarshal(row.ResponseHeaders, &rec.Response.Header); err != nil {
+			return nil, false, err
+		}
+	}
+	return rec, false, nil
+}
+
+func (s *postgresStore) Complete(ctx context.Context, scope, key string, resp Response) error {
+	header, err := json.Marshal(resp.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE idempotency_keys
+		SET response_status = $3, response_headers = $4, response_body = $5, locked_until = NULL
+		WHERE scope = $1 AND key = $2`,
+		scope, key, resp.Status, header, resp.Body)
+	return err
+}
+
+func (s *postgresStore) Release(ctx context.Context, scope, key string, rec *Record) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys
+		WHERE scope = $1 AND key = $2 AND response_status IS NULL AND fingerprint = $3 AND locked_until = $4`,
+		scope, key, rec.Fingerprint, rec.LockedUntil)
+	return err
+}
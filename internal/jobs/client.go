@@ -0,0 +1,175 @@
+// Package jobs runs work outside the request path on a queue kept in the
+// jobs table. Workers claim due jobs with FOR UPDATE SKIP LOCKED, so any
+// number of processes can share a queue without a broker.
+//
+// A job is a value implementing Args, stored as JSON, and handled by the
+// function registered for its kind:
+//
+//	type SendWelcomeEmail struct {
+//		UserID uuid.UUID `json:"user_id"`
+//	}
+//
+//	func (SendWelcomeEmail) Kind() string { return "send_welcome_email" }
+//
+//	jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args SendWelcomeEmail) error {
+//		return mailer.SendWelcome(ctx, args.UserID)
+//	})
+//
+//	client.Enqueue(ctx, SendWelcomeEmail{UserID: id}, jobs.WithDelay(time.Minute))
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+)
+
+// Job is a row of the jobs table.
+type Job = queries.Job
+
+// Job states. Available jobs run once run_at has passed; failed attempts go
+// back to available until max_attempts is reached and the job is dead.
+const (
+	StateAvailable = "available"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateDead      = "dead"
+)
+
+const (
+	DefaultQueue       = "default"
+	DefaultMaxAttempts = 20
+)
+
+// ErrDuplicate is returned by Enqueue when a job with the same kind and
+// unique key is still available or running.
+var ErrDuplicate = errors.New("a job with this unique key is already pending")
+
+// Args is the payload of a job. It is stored as JSON, so it should only
+// hold exported, serialisable fields.
+type Args interface {
+	// Kind names the handler that runs the job. It must not change while
+	// jobs of the kind are queued.
+	Kind() string
+}
+
+type enqueueOptions struct {
+	queue       string
+	priority    int16
+	runAt       time.Time
+	maxAttempts int32
+	uniqueKey   string
+}
+
+type EnqueueOption func(*enqueueOptions)
+
+// WithQueue puts the job on a named queue; workers only claim jobs from the
+// queues they are configured with.
+func WithQueue(queue string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.queue = queue
+	}
+}
+
+// WithPriority orders the job before lower priority jobs that are due. The
+// default is 0.
+func WithPriority(priority int16) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.priority = priority
+	}
+}
+
+// WithRunAt schedules the job to run no earlier than t.
+func WithRunAt(t time.Time) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = t
+	}
+}
+
+// WithDelay schedules the job to run no earlier than d from now.
+func WithDelay(d time.Duration) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.runAt = time.Now().Add(d)
+	}
+}
+
+// WithMaxAttempts sets how many times the job runs before it is dead.
+func WithMaxAttempts(n int32) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithUniqueKey rejects the job with ErrDuplicate while another job of the
+// same kind and key is available or running.
+func WithUniqueKey(key string) EnqueueOption {
+	return func(o *enqueueOptions) {
+		o.uniqueKey = key
+	}
+}
+
+// Client adds jobs to the queue. It needs no worker, so any process with
+// database access can enqueue.
+type Client struct {
+	queries *queries.Queries
+}
+
+func NewClient(db queries.DBTX) *Client {
+	return &Client{queries: database.NewQueries(db)}
+}
+
+// WithTx returns a client enqueuing in tx, so the job is only queued if the
+// transaction commits.
+func (c *Client) WithTx(tx queries.DBTX) *Client {
+	return NewClient(tx)
+}
+
+func (c *Client) Enqueue(ctx context.Context, args Args, opts ...EnqueueOption) (*Job, error) {
+	o := enqueueOptions{queue: DefaultQueue, maxAttempts: DefaultMaxAttempts, runAt: time.Now()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.maxAttempts < 1 {
+		return nil, fmt.Errorf("jobs: max attempts must be at least 1, got %d", o.maxAttempts)
+	}
+
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: encoding %s args: %w", args.Kind(), err)
+	}
+
+	job, err := c.queries.InsertJob(ctx, queries.InsertJobParams{
+		Kind:        args.Kind(),
+		Queue:       o.queue,
+		Payload:     payload,
+		Priority:    o.priority,
+		RunAt:       o.runAt,
+		MaxAttempts: o.maxAttempts,
+		UniqueKey:   sql.Null[string]{V: o.uniqueKey, Valid: o.uniqueKey != ""},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s %q: %w", args.Kind(), o.uniqueKey, ErrDuplicate)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// RequeueDead makes a dead job available again with a fresh set of attempts.
+func (c *Client) RequeueDead(ctx context.Context, id int64) error {
+	n, err := c.queries.RequeueDeadJob(ctx, queries.RequeueDeadJobParams{Now: time.Now(), ID: id})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("dead job %d: %w", id, database.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// handler runs a job whose payload has not been decoded yet.
+type handler func(ctx context.Context, job *Job) error
+
+// Registry maps job kinds to their handlers. Workers only claim jobs whose
+// kind is registered, so processes running different versions can share a
+// queue.
+type Registry struct {
+	mu       sync.RWMutex
+	handlers map[string]handler
+}
+
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]handler)}
+}
+
+// Register adds the handler for jobs with args of type T. It panics if the
+// kind already has a handler.
+func Register[T Args](r *Registry, fn func(ctx context.Context, job *Job, args T) error) {
+	var zero T
+	kind := zero.Kind()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.handlers[kind]; ok {
+		panic(fmt.Sprintf("jobs: kind %q is already registered", kind))
+	}
+	r.handlers[kind] = func(ctx context.Context, job *Job) error {
+		var args T
+		if err := json.Unmarshal(job.Payload, &args); err != nil {
+			// Retrying cannot fix a payload that does not decode
+			return Permanent(fmt.Errorf("decoding args: %w", err))
+		}
+		return fn(ctx, job, args)
+	}
+}
+
+// Kinds returns the registered kinds, sorted.
+func (r *Registry) Kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (r *Registry) handler(kind string) handler {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.handlers[kind]
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks a handler error as one retrying cannot fix; the job is
+// dead straight away.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
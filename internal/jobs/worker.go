@@ -0,0 +1,323 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ctrixcode/go-chi-postgres/internal/jobs")
+
+const (
+	// finishTimeout bounds the queries recording a job's outcome, which run
+	// even when the job's own context has ended.
+	finishTimeout = 5 * time.Second
+	// cleanupInterval is how often completed jobs past their retention are
+	// deleted.
+	cleanupInterval = time.Minute
+)
+
+var errLeaseLost = errors.New("job lease was lost to another worker")
+
+// Options are read before every poll, so they can change at runtime.
+type Options struct {
+	Queues       []string
+	Concurrency  int
+	PollInterval time.Duration
+	// LockTimeout is how long a claimed job is leased to the worker. The
+	// handler's context ends with the lease, after which another worker may
+	// claim the job again.
+	LockTimeout time.Duration
+	// A failed attempt is retried after BackoffBase, doubled for every
+	// earlier attempt and capped at BackoffMax.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Retention is how long completed jobs are kept; 0 keeps them.
+	Retention time.Duration
+}
+
+// Worker claims due jobs and runs up to Options.Concurrency of them at once.
+type Worker struct {
+	id       string
+	queries  *queries.Queries
+	registry *Registry
+	options  func() Options
+
+	processed *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	running   *prometheus.GaugeVec
+
+	inFlight    atomic.Int64
+	jobs        sync.WaitGroup
+	jobCtx      context.Context
+	cancelJobs  context.CancelFunc
+	lastCleanup time.Time
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	// wake is signalled when a job finishes and frees a slot
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewWorker(db queries.DBTX, registry *Registry, options func() Options, m *metrics.Metrics) *Worker {
+	host, _ := os.Hostname()
+	jobCtx, cancel := context.WithCancel(context.Background())
+	return &Worker{
+		id:         fmt.Sprintf("%s-%d", host, os.Getpid()),
+		queries:    database.NewQueries(db),
+		registry:   registry,
+		options:    options,
+		processed:  m.Counter("jobs_processed_total", "Job attempts by kind and outcome.", "kind", "outcome"),
+		duration:   m.Histogram("job_duration_seconds", "Job attempt duration by kind.", []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300}, "kind"),
+		running:    m.Gauge("jobs_running", "Jobs currently running in this process.", "kind"),
+		jobCtx:     jobCtx,
+		cancelJobs: cancel,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Start begins polling for jobs in the background.
+func (w *Worker) Start() {
+	if w.started.CompareAndSwap(false, true) {
+		slog.Info("job worker starting", "worker", w.id, "kinds", w.registry.Kinds())
+		go w.run()
+	}
+}
+
+// Shutdown stops claiming jobs and waits for running ones to finish. If ctx
+// ends first their contexts are cancelled, the interrupted attempts are
+// recorded as failed and ctx.Err() is returned.
+func (w *Worker) Shutdown(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	if w.started.Load() {
+		<-w.done
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		w.jobs.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		w.cancelJobs()
+		<-drained
+		return ctx.Err()
+	}
+}
+
+func (w *Worker) run() {
+	defer close(w.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-w.wake:
+		case <-timer.C:
+		}
+
+		opts := w.options()
+		full := w.poll(opts)
+		w.cleanup(opts)
+
+		// A full batch means more jobs may already be due
+		delay := opts.PollInterval
+		if full {
+			delay = 0
+		}
+		timer.Reset(delay)
+	}
+}
+
+// poll claims as many jobs as there are free slots and starts them. It
+// reports whether every free slot was filled.
+func (w *Worker) poll(opts Options) bool {
+	free := int64(max(opts.Concurrency, 1)) - w.inFlight.Load()
+	kinds := w.registry.Kinds()
+	if free <= 0 || len(kinds) == 0 {
+		return false
+	}
+	queues := opts.Queues
+	if len(queues) == 0 {
+		queues = []string{DefaultQueue}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+	// Leases end LockTimeout after the database's now(), which is no earlier
+	// than this, so handlers stop before the lease ends whatever the skew
+	// between the clocks
+	deadline := time.Now().Add(opts.LockTimeout)
+	claimed, err := w.queries.ClaimJobs(ctx, queries.ClaimJobsParams{
+		Worker: w.id,
+		LockMs: opts.LockTimeout.Milliseconds(),
+		Queues: queues,
+		Kinds:  kinds,
+		Max:    free,
+	})
+	if err != nil {
+		slog.Error("claiming jobs failed", "error", err)
+		return false
+	}
+
+	for i := range claimed {
+		job := &claimed[i]
+		w.inFlight.Add(1)
+		w.jobs.Add(1)
+		go w.execute(job, deadline, opts)
+	}
+	return int64(len(claimed)) == free
+}
+
+func (w *Worker) execute(job *Job, deadline time.Time, opts Options) {
+	defer func() {
+		w.inFlight.Add(-1)
+		w.jobs.Done()
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}()
+	w.running.WithLabelValues(job.Kind).Inc()
+	defer w.running.WithLabelValues(job.Kind).Dec()
+
+	ctx, cancel := context.WithDeadline(w.jobCtx, deadline)
+	defer cancel()
+	ctx, span := tracer.Start(ctx, "job "+job.Kind, trace.WithSpanKind(trace.SpanKindConsumer), trace.WithAttributes(
+		attribute.Int64("job.id", job.ID),
+		attribute.String("job.kind", job.Kind),
+		attribute.String("job.queue", job.Queue),
+		attribute.Int("job.attempt", int(job.Attempts)),
+	))
+	defer span.End()
+	log := logger.FromContext(ctx).With("job_id", job.ID, "job_kind", job.Kind, "attempt", job.Attempts)
+
+	start := time.Now()
+	var err error
+	if job.Attempts > job.MaxAttempts {
+		// Claimed again after the lease of its last attempt expired
+		err = Permanent(errors.New("lease expired on the last attempt"))
+	} else {
+		err = w.call(ctx, job)
+	}
+	elapsed := time.Since(start)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	outcome, runAt, ferr := w.finish(context.WithoutCancel(ctx), job, err, opts)
+	w.processed.WithLabelValues(job.Kind, outcome).Inc()
+	w.duration.WithLabelValues(job.Kind).Observe(elapsed.Seconds())
+
+	switch {
+	case ferr != nil:
+		log.Error("recording job outcome failed", "outcome", outcome, "error", ferr, "job_error", err)
+	case outcome == StateCompleted:
+		log.Debug("job completed", "duration", elapsed)
+	case outcome == StateDead:
+		log.Error("job failed permanently", "duration", elapsed, "error", err)
+	default:
+		log.Warn("job failed, retrying", "duration", elapsed, "error", err, "run_at", runAt)
+	}
+}
+
+// call runs the job's handler, turning a panic into an error.
+func (w *Worker) call(ctx context.Context, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	h := w.registry.handler(job.Kind)
+	if h == nil {
+		return Permanent(fmt.Errorf("no handler registered for kind %q", job.Kind))
+	}
+	return h(ctx, job)
+}
+
+// finish records the outcome of an attempt: completed, retried later or dead.
+func (w *Worker) finish(ctx context.Context, job *Job, jobErr error, opts Options) (outcome string, runAt time.Time, err error) {
+	ctx, cancel := context.WithTimeout(ctx, finishTimeout)
+	defer cancel()
+
+	now := time.Now()
+	var n int64
+	switch {
+	case jobErr == nil:
+		outcome = StateCompleted
+		n, err = w.queries.CompleteJob(ctx, queries.CompleteJobParams{Now: now, ID: job.ID, Attempts: job.Attempts})
+	case isPermanent(jobErr) || job.Attempts >= job.MaxAttempts:
+		outcome = StateDead
+		n, err = w.queries.KillJob(ctx, queries.KillJobParams{Now: now, Error: jobErr.Error(), ID: job.ID, Attempts: job.Attempts})
+	default:
+		outcome = "retried"
+		runAt = now.Add(Backoff(int(job.Attempts), opts.BackoffBase, opts.BackoffMax))
+		n, err = w.queries.RetryJob(ctx, queries.RetryJobParams{RunAt: runAt, Error: jobErr.Error(), Now: now, ID: job.ID, Attempts: job.Attempts})
+	}
+	if err == nil && n == 0 {
+		err = errLeaseLost
+	}
+	return outcome, runAt, err
+}
+
+// cleanup deletes completed jobs past their retention, at most once per
+// cleanupInterval.
+func (w *Worker) cleanup(opts Options) {
+	if opts.Retention <= 0 || time.Since(w.lastCleanup) < cleanupInterval {
+		return
+	}
+	w.lastCleanup = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), finishTimeout)
+	defer cancel()
+	if n, err := w.queries.DeleteCompletedJobs(ctx, time.Now().Add(-opts.Retention)); err != nil {
+		slog.Error("deleting completed jobs failed", "error", err)
+	} else if n > 0 {
+		slog.Debug("deleted completed jobs", "count", n)
+	}
+}
+
+// Backoff returns how long to wait before retrying after the given attempt:
+// base doubled for each earlier attempt, capped at max, less up to 10%
+// jitter so failed jobs do not retry in lockstep.
+func Backoff(attempt int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d - time.Duration(rand.Int64N(int64(d)/10+1))
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/repository"
+	"github.com/google/uuid"
+)
+
+type exampleRepository struct {
+	next    repository.ExampleRepository
+	metrics *Metrics
+}
+
+// InstrumentExampleRepository wraps repo so every method's query duration is
+// recorded.
+func InstrumentExampleRepository(repo repository.ExampleRepository, m *Metrics) repository.ExampleRepository {
+	return &exampleRepository{next: repo, metrics: m}
+}
+
+func (r *exampleRepository) Create(ctx context.Context, req models.CreateExampleRequest) (example *models.Example, err error) {
+	defer func(start time.Time) { r.metrics.ObserveQuery("example", "Create", start, err) }(time.Now())
+	return r.next.Create(ctx, req)
+}
+
+func (r *exampleRepository) GetByID(ctx context.Context, id uuid.UUID) (example *models.Example, err error) {
+	defer func(start time.Time) { r.metrics.ObserveQuery("example", "GetByID", start, err) }(time.Now())
+	return r.next.GetByID(ctx, id)
+}
+
+func (r *exampleRepository) List(ctx context.Context, limit, offset uint64) (examples []models.Example, err error) {
+	defer func(start time.Time) { r.metrics.ObserveQuery("example", "List", start, err) }(time.Now())
+	return r.next.List(ctx, limit, offset)
+}
+
+func (r *exampleRepository) Search(ctx context.Context, q models.ExampleQuery) (examples []models.Example, err error) {
+	defer func(start time.Time) { r.metrics.ObserveQuery("example", "Search", start, err) }(time.Now())
+	return r.next.Search(ctx, q)
+}
+
+func (r *exampleRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) (examples []models.Example, err error) {
+	defer func(start time.Time) { r.metrics.ObserveQuery("example", "GetByIDs", start, err) }(time.Now())
+	return r.next.GetByIDs(ctx, ids)
+}
+
+func (r *exampleRepository) Update(ctx context.Context, id uuid.UUID, req models.UpdateExampleRequest) (example *models.Example, err error) {
+	defer func(start time.Time) { r.metrics.ObserveQuery("example", "Update", start, err) }(time.Now())
+	return r.next.Update(ctx, id, req)
+}
+
+func (r *exampleRepository) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(start time.Time) { r.metrics.ObserveQuery("example", "Delete", start, err) }(time.Now())
+	return r.next.Delete(ctx, id)
+}
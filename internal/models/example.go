@@ -26,3 +26,36 @@ type UpdateExampleRequest struct {
 	LuckyNumber *float64 `json:"lucky_number"`
 	IsPremium   *bool    `json:"is_premium"`
 }
+
+// ExampleFilter selects examples by their fields; fields left out match
+// every example.
+type ExampleFilter struct {
+	Name           *string  `json:"name" validate:"omitempty,max=255"`
+	IsPremium      *bool    `json:"is_premium"`
+	MinLuckyNumber *float64 `json:"min_lucky_number"`
+	MaxLuckyNumber *float64 `json:"max_lucky_number"`
+}
+
+// ExampleQuery selects a page of the examples matching Filter, ordered by
+// the column Sort, one of ExampleSortColumns, or by creation when empty.
+type ExampleQuery struct {
+	Filter ExampleFilter
+	Sort   string
+	Desc   bool
+	Limit  uint64
+	Offset uint64
+}
+
+// ExampleSortColumns are the columns examples can be sorted by.
+var ExampleSortColumns = []string{"name", "lucky_number", "created_at", "updated_at"}
+
+// Event types published when examples change. Created and updated events
+// carry the Example, deleted events its ID.
+const (
+	EventExampleCreated = "example.created"
+	EventExampleUpdated = "example.updated"
+	EventExampleDeleted = "example.deleted"
+)
+
+// ExampleAggregate is the aggregate type of example events.
+const ExampleAggregate = "example"
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// Messages clients send over the examples WebSocket.
+const (
+	SocketSubscribe   = "subscribe"
+	SocketUnsubscribe = "unsubscribe"
+)
+
+// Messages the examples WebSocket sends. A subscription sees an example as
+// added when it starts matching the filter, whether created or updated into
+// it, and as removed when it stops matching or is deleted.
+const (
+	SocketSubscribed   = "subscribed"
+	SocketUnsubscribed = "unsubscribed"
+	SocketAdded        = "added"
+	SocketUpdated      = "updated"
+	SocketRemoved      = "removed"
+	SocketError        = "error"
+)
+
+// ExampleSocketRequest subscribes to the examples matching Filter under an
+// ID the client picks, or unsubscribes from it.
+type ExampleSocketRequest struct {
+	Type   string        `json:"type" validate:"required,oneof=subscribe unsubscribe"`
+	ID     string        `json:"id" validate:"required,max=64"`
+	Filter ExampleFilter `json:"filter"`
+}
+
+// ExampleSocketMessage is sent for requests and for the changes seen by a
+// subscription.
+type ExampleSocketMessage struct {
+	Type string `json:"type"`
+	// ID is the subscription the message is about.
+	ID        string     `json:"id,omitempty"`
+	ChangeID  int64      `json:"change_id,omitempty"`
+	ExampleID *uuid.UUID `json:"example_id,omitempty"`
+	// Example is set when an example is added.
+	Example json.RawMessage `json:"example,omitempty"`
+	// Changes holds the fields an update changed, with their new values.
+	Changes map[string]json.RawMessage `json:"changes,omitempty"`
+	Error   string                     `json:"error,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook is an endpoint notified of the events matching its patterns.
+type Webhook struct {
+	ID     uuid.UUID `json:"id"`
+	URL    string    `json:"url"`
+	Events []string  `json:"events"`
+	// Secret signs deliveries. It is only returned when the webhook is
+	// created.
+	Secret              string     `json:"secret,omitempty"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" validate:"required,url,max=2048"`
+	// Events are event types such as "example.created", prefixes such as
+	// "example.*", or "*" for every event.
+	Events []string `json:"events" validate:"required,min=1,max=50,dive,required,max=100"`
+	// Secret is generated when empty.
+	Secret string `json:"secret" validate:"omitempty,min=16,max=255"`
+}
+
+// UpdateWebhookRequest changes the fields present. Enabling a disabled
+// webhook resets its failure count.
+type UpdateWebhookRequest struct {
+	URL     *string  `json:"url" validate:"omitempty,url,max=2048"`
+	Events  []string `json:"events" validate:"omitempty,min=1,max=50,dive,required,max=100"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// WebhookDelivery is one attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID        int64     `json:"id"`
+	WebhookID uuid.UUID `json:"webhook_id"`
+	EventID   int64     `json:"event_id"`
+	EventType string    `json:"event_type"`
+	Attempt   int       `json:"attempt"`
+	// ResponseStatus is nil when no response was received, Error set when
+	// the attempt failed.
+	ResponseStatus *int      `json:"response_status"`
+	Error          *string   `json:"error"`
+	DurationMs     int       `json:"duration_ms"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,172 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+)
+
+// Route documents a route registered on a chi router. Handlers declare their
+// routes next to RegisterRoutes so the document follows the code.
+type Route struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Description string
+	Params      []Param
+	// Request is a value of the JSON body type, nil when there is no body.
+	Request interface{}
+	// Response is a value of the type returned in the data field of the
+	// success envelope, nil when there is no data.
+	Response interface{}
+	// ContentType is set for routes writing a raw body instead of the
+	// response envelope.
+	ContentType string
+	Status      int
+	Errors      []int
+}
+
+// Param is a path, query or header parameter. Type is a value of the Go
+// type it is parsed into; Validate takes validator rules like struct tags.
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Type        interface{}
+	Validate    string
+}
+
+func PathParam(name, description string, typ interface{}) Param {
+	return Param{Name: name, In: "path", Description: description, Required: true, Type: typ}
+}
+
+func QueryParam(name, description string, typ interface{}, validate string) Param {
+	return Param{Name: name, In: "query", Description: description, Type: typ, Validate: validate}
+}
+
+func HeaderParam(name, description string, validate string) Param {
+	return Param{Name: name, In: "header", Description: description, Type: "", Validate: validate}
+}
+
+// Builder assembles a document from routes mounted under path prefixes.
+type Builder struct {
+	doc *Document
+}
+
+func NewBuilder(info Info) *Builder {
+	b := &Builder{doc: &Document{
+		OpenAPI:    Version,
+		Info:       info,
+		Paths:      make(map[string]*PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}}
+	b.schemaFor(reflect.TypeOf(response.ErrorResponse{}))
+	return b
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]+)?\}`)
+
+// Mount adds routes served under prefix, tagging their operations.
+func (b *Builder) Mount(prefix, tag string, routes []Route) *Builder {
+	for _, rt := range routes {
+		path := strings.TrimSuffix(prefix, "/") + rt.Path
+		if path == "" {
+			path = "/"
+		}
+		// chi regexp params ({id:[0-9]+}) become plain template params
+		path = pathParamPattern.ReplaceAllString(path, "{$1}")
+
+		item, ok := b.doc.Paths[path]
+		if !ok {
+			item = &PathItem{}
+			b.doc.Paths[path] = item
+		}
+		op := b.operation(rt)
+		if tag != "" {
+			op.Tags = []string{tag}
+		}
+		(*item)[strings.ToLower(rt.Method)] = op
+	}
+	return b
+}
+
+func (b *Builder) Document() *Document {
+	return b.doc
+}
+
+func (b *Builder) operation(rt Route) *Operation {
+	op := &Operation{
+		OperationID: rt.OperationID,
+		Summary:     rt.Summary,
+		Description: rt.Description,
+		Responses:   make(map[string]*Response),
+	}
+
+	for _, p := range rt.Params {
+		schema := b.schemaFor(reflect.TypeOf(p.Type))
+		required := applyValidate(schema, p.Validate)
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        p.Name,
+			In:          p.In,
+			Description: p.Description,
+			Required:    p.Required || required,
+			Schema:      schema,
+		})
+	}
+
+	if rt.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: b.schemaFor(reflect.TypeOf(rt.Request))}},
+		}
+	}
+
+	status := rt.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	switch {
+	case status == http.StatusSwitchingProtocols:
+		// The connection is taken over by another protocol
+	case rt.ContentType != "":
+		success.Content = map[string]*MediaType{rt.ContentType: {Schema: &Schema{Type: "string"}}}
+	default:
+		success.Content = map[string]*MediaType{"application/json": {Schema: b.envelope(rt.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	for _, code := range rt.Errors {
+		op.Responses[strconv.Itoa(code)] = &Response{
+			Description: http.StatusText(code),
+			Content: map[string]*MediaType{"application/json": {
+				Schema: &Schema{Ref: "#/components/schemas/ErrorResponse"},
+			}},
+		}
+	}
+	return op
+}
+
+// envelope describes response.SuccessResponse with data of the given type.
+func (b *Builder) envelope(data interface{}) *Schema {
+	s := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+		},
+		Required:             []string{"success"},
+		AdditionalProperties: false,
+	}
+	if data != nil {
+		s.Properties["data"] = b.schemaFor(reflect.TypeOf(data))
+		s.Required = append(s.Required, "data")
+	}
+	return s
+}
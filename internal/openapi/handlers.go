@@ -0,0 +1,83 @@
+package openapi
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/security"
+	"github.com/ctrixcode/go-chi-postgres/internal/webui"
+)
+
+// SpecHandler serves the document as JSON.
+func SpecHandler(doc *Document) http.HandlerFunc {
+	body, err := json.Marshal(doc)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+var swaggerUIPage = template.Must(template.New("swagger").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="{{.Assets.CSS}}">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="{{.Assets.Script}}"></script>
+  <script nonce="{{.Nonce}}">
+    window.ui = SwaggerUIBundle({ url: {{.SpecURL}}, dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`))
+
+var redocPage = template.Must(template.New("redoc").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+</head>
+<body>
+  <redoc spec-url="{{.SpecURL}}"></redoc>
+  <script src="{{.Assets.Script}}"></script>
+</body>
+</html>
+`))
+
+// page is what a UI page is rendered with.
+type page struct {
+	Title, SpecURL, Nonce string
+	Assets                struct{ Script, CSS string }
+}
+
+// SwaggerUIHandler serves an interactive page for the document at specURL.
+func SwaggerUIHandler(title, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := page{Title: title, SpecURL: specURL, Nonce: security.PagePolicy(w, webui.Sources(webui.SwaggerUI))}
+		p.Assets.Script = webui.SwaggerUI.URL("swagger-ui-bundle.js")
+		p.Assets.CSS = webui.SwaggerUI.URL("swagger-ui.css")
+		render(w, swaggerUIPage, p)
+	}
+}
+
+// RedocHandler serves a read-only reference page for the document at specURL.
+func RedocHandler(title, specURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := page{Title: title, SpecURL: specURL, Nonce: security.PagePolicy(w, webui.Sources(webui.Redoc))}
+		p.Assets.Script = webui.Redoc.URL("bundles/redoc.standalone.js")
+		render(w, redocPage, p)
+	}
+}
+
+func render(w http.ResponseWriter, t *template.Template, p page) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t.Execute(w, p)
+}
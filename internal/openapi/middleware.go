@@ -0,0 +1,370 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+)
+
+// Response validation modes.
+const (
+	ResponsesOff    = "off"
+	ResponsesLog    = "log"
+	ResponsesStrict = "strict"
+)
+
+// ValidatorOptions is read on every request so it can be reloaded.
+type ValidatorOptions struct {
+	// Requests rejects requests that do not match the document with 400.
+	Requests bool
+	// Responses is off, log or strict. Log reports mismatching responses,
+	// strict also replaces them with a 500 so tests fail on drift.
+	Responses string
+}
+
+// Validator checks requests and responses of documented operations against
+// the document. Undocumented paths and methods pass through untouched.
+type Validator struct {
+	doc     *Document
+	routes  []route
+	options func() ValidatorOptions
+}
+
+type route struct {
+	template string
+	segments []string
+	item     *PathItem
+}
+
+func NewValidator(doc *Document, options func() ValidatorOptions) *Validator {
+	v := &Validator{doc: doc, options: options}
+	for template, item := range doc.Paths {
+		v.routes = append(v.routes, route{template: template, segments: splitPath(template), item: item})
+	}
+	// Templates with more literal segments are tried first, so /examples/new
+	// would win over /examples/{id}
+	sort.Slice(v.routes, func(i, j int) bool {
+		a, b := literals(v.routes[i].segments), literals(v.routes[j].segments)
+		if a != b {
+			return a > b
+		}
+		return v.routes[i].template < v.routes[j].template
+	})
+	return v
+}
+
+func (v *Validator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := v.options()
+		if !opts.Requests && (opts.Responses == "" || opts.Responses == ResponsesOff) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		op, params, template := v.find(r)
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if opts.Requests {
+			problems, err := v.validateRequest(r, op, params)
+			if err != nil {
+				response.JSONError(w, err)
+				return
+			}
+			if len(problems) > 0 {
+				response.JSONError(w, errors.BadRequestError(errors.ErrValidationFailed, problems))
+				return
+			}
+		}
+
+		if opts.Responses == "" || opts.Responses == ResponsesOff {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		vw := &validatingWriter{ResponseWriter: w}
+		next.ServeHTTP(vw, r)
+		if vw.streaming {
+			return
+		}
+
+		problems := v.validateResponse(op, vw)
+		if len(problems) == 0 {
+			vw.send()
+			return
+		}
+
+		log := logger.FromContext(r.Context())
+		if opts.Responses != ResponsesStrict {
+			log.Warn("response does not match the OpenAPI document",
+				"method", r.Method, "path", template, "status", vw.statusCode(), "problems", problems)
+			vw.send()
+			return
+		}
+		log.Error("response does not match the OpenAPI document",
+			"method", r.Method, "path", template, "status", vw.statusCode(), "problems", problems)
+		response.JSONError(w, errors.InternalServerError(errors.ErrInvalidResponse, problems))
+	})
+}
+
+// find returns the operation serving r and its path parameters. A request
+// for /examples also matches /examples/, as chi serves both from a mount.
+func (v *Validator) find(r *http.Request) (*Operation, map[string]string, string) {
+	segments := splitPath(r.URL.Path)
+	candidates := [][]string{segments}
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		candidates = append(candidates, append(segments[:len(segments):len(segments)], ""))
+	}
+
+	for _, segs := range candidates {
+		for _, rt := range v.routes {
+			params, ok := match(rt.segments, segs)
+			if !ok {
+				continue
+			}
+			op := (*rt.item)[strings.ToLower(r.Method)]
+			if op == nil && r.Method == http.MethodHead {
+				op = (*rt.item)["get"]
+			}
+			if op == nil {
+				return nil, nil, ""
+			}
+			return op, params, rt.template
+		}
+	}
+	return nil, nil, ""
+}
+
+func (v *Validator) validateRequest(r *http.Request, op *Operation, params map[string]string) ([]string, error) {
+	var problems []string
+	query := r.URL.Query()
+
+	for _, p := range op.Parameters {
+		var raw string
+		var present bool
+		switch p.In {
+		case "path":
+			raw, present = params[p.Name]
+		case "query":
+			if values, ok := query[p.Name]; ok && len(values) > 0 {
+				raw, present = values[0], true
+			}
+		case "header":
+			if values := r.Header.Values(p.Name); len(values) > 0 {
+				raw, present = values[0], true
+			}
+		default:
+			continue
+		}
+
+		at := p.In + "." + p.Name
+		if !present {
+			if p.Required {
+				problems = append(problems, at+": is required")
+			}
+			continue
+		}
+		value, ok := v.parseParam(raw, p.Schema)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: must be %s", at, strings.Join(schemaTypes(p.Schema), " or ")))
+			continue
+		}
+		problems = append(problems, v.doc.validateValue(value, p.Schema, at)...)
+	}
+
+	if op.RequestBody == nil {
+		return problems, nil
+	}
+	media := op.RequestBody.Content["application/json"]
+	if media == nil || !isJSON(r.Header.Get("Content-Type")) {
+		// The handler reports the unsupported media type
+		return problems, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if stderrors.As(err, &maxErr) {
+			return nil, errors.PayloadTooLargeError(errors.ErrPayloadTooLarge,
+				fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit))
+		}
+		return nil, errors.BadRequestError(errors.ErrBadRequest, "failed to read request body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	// Malformed and empty bodies are left to the handler, which reports
+	// where decoding failed
+	var decoded interface{}
+	if len(bytes.TrimSpace(body)) == 0 || json.Unmarshal(body, &decoded) != nil {
+		return problems, nil
+	}
+	return append(problems, v.doc.validateValue(decoded, media.Schema, "body")...), nil
+}
+
+// parseParam converts a raw parameter to the JSON value its schema
+// describes, reporting false when it cannot be parsed.
+func (v *Validator) parseParam(raw string, s *Schema) (interface{}, bool) {
+	if s.Ref != "" {
+		s = v.doc.resolve(s.Ref)
+	}
+	types := schemaTypes(s)
+	if len(types) == 0 {
+		return raw, true
+	}
+	for _, t := range types {
+		switch t {
+		case "string":
+			return raw, true
+		case "integer", "number":
+			if n, err := strconv.ParseFloat(raw, 64); err == nil {
+				return n, true
+			}
+		case "boolean":
+			if b, err := strconv.ParseBool(raw); err == nil {
+				return b, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (v *Validator) validateResponse(op *Operation, vw *validatingWriter) []string {
+	status := strconv.Itoa(vw.statusCode())
+	resp, ok := op.Responses[status]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok {
+		return []string{fmt.Sprintf("status %s is not documented", status)}
+	}
+
+	contentType := vw.Header().Get("Content-Type")
+	if !isJSON(contentType) {
+		// Only JSON bodies are checked, other formats carry the same values
+		return nil
+	}
+	media := resp.Content["application/json"]
+	if media == nil {
+		if vw.buf.Len() == 0 {
+			return nil
+		}
+		return []string{fmt.Sprintf("status %s must not have a JSON body", status)}
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(vw.buf.Bytes(), &decoded); err != nil {
+		return []string{"body: is not valid JSON"}
+	}
+	return v.doc.validateValue(decoded, media.Schema, "body")
+}
+
+// validatingWriter buffers the response until it has been validated. A
+// handler that flushes is streaming, and one switching protocols takes over
+// the connection, so the rest of their response passes through unchecked.
+type validatingWriter struct {
+	http.ResponseWriter
+	status    int
+	buf       bytes.Buffer
+	streaming bool
+}
+
+func (vw *validatingWriter) Unwrap() http.ResponseWriter {
+	return vw.ResponseWriter
+}
+
+func (vw *validatingWriter) WriteHeader(status int) {
+	if status == http.StatusSwitchingProtocols {
+		vw.streaming = true
+	}
+	if vw.streaming || status >= 100 && status < 200 {
+		vw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if vw.status == 0 {
+		vw.status = status
+	}
+}
+
+func (vw *validatingWriter) Write(p []byte) (int, error) {
+	if vw.streaming {
+		return vw.ResponseWriter.Write(p)
+	}
+	return vw.buf.Write(p)
+}
+
+func (vw *validatingWriter) Flush() {
+	if !vw.streaming {
+		vw.send()
+		vw.streaming = true
+	}
+	if f, ok := vw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (vw *validatingWriter) statusCode() int {
+	if vw.status == 0 {
+		return http.StatusOK
+	}
+	return vw.status
+}
+
+func (vw *validatingWriter) send() {
+	vw.ResponseWriter.WriteHeader(vw.statusCode())
+	vw.ResponseWriter.Write(vw.buf.Bytes())
+}
+
+func isJSON(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+func literals(segments []string) int {
+	n := 0
+	for _, s := range segments {
+		if !strings.HasPrefix(s, "{") {
+			n++
+		}
+	}
+	return n
+}
+
+func match(template, segments []string) (map[string]string, bool) {
+	if len(template) != len(segments) {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for i, t := range template {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[t[1:len(t)-1]] = segments[i]
+			continue
+		}
+		if t != segments[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
@@ -0,0 +1,211 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// validateValue checks a decoded JSON value against a schema and returns
+// one problem per violation, prefixed with the location of the value.
+func (d *Document) validateValue(v interface{}, s *Schema, at string) []string {
+	if s == nil {
+		return nil
+	}
+	if s.Ref != "" {
+		return d.validateValue(v, d.resolve(s.Ref), at)
+	}
+	if len(s.AnyOf) > 0 {
+		for _, alt := range s.AnyOf {
+			if len(d.validateValue(v, alt, at)) == 0 {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("%s: does not match any allowed schema", at)}
+	}
+
+	if types := schemaTypes(s); len(types) > 0 && !hasType(types, v) {
+		return []string{fmt.Sprintf("%s: must be %s", at, strings.Join(types, " or "))}
+	}
+
+	var problems []string
+	if len(s.Enum) > 0 && !inEnum(s.Enum, v) {
+		problems = append(problems, fmt.Sprintf("%s: must be one of %v", at, s.Enum))
+	}
+
+	switch val := v.(type) {
+	case string:
+		problems = append(problems, validateString(val, s, at)...)
+	case float64:
+		problems = append(problems, validateNumber(val, s, at)...)
+	case []interface{}:
+		if s.MinItems != nil && len(val) < *s.MinItems {
+			problems = append(problems, fmt.Sprintf("%s: must have at least %d items", at, *s.MinItems))
+		}
+		if s.MaxItems != nil && len(val) > *s.MaxItems {
+			problems = append(problems, fmt.Sprintf("%s: must have at most %d items", at, *s.MaxItems))
+		}
+		for i, item := range val {
+			problems = append(problems, d.validateValue(item, s.Items, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case map[string]interface{}:
+		problems = append(problems, d.validateObject(val, s, at)...)
+	}
+	return problems
+}
+
+func (d *Document) validateObject(obj map[string]interface{}, s *Schema, at string) []string {
+	var problems []string
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s: is required", join(at, name)))
+		}
+	}
+
+	// Sort keys so problems come out in a stable order
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if prop, ok := s.Properties[k]; ok {
+			problems = append(problems, d.validateValue(obj[k], prop, join(at, k))...)
+			continue
+		}
+		switch extra := s.AdditionalProperties.(type) {
+		case bool:
+			if !extra {
+				problems = append(problems, fmt.Sprintf("%s: is not allowed", join(at, k)))
+			}
+		case *Schema:
+			problems = append(problems, d.validateValue(obj[k], extra, join(at, k))...)
+		}
+	}
+	return problems
+}
+
+func validateString(v string, s *Schema, at string) []string {
+	var problems []string
+	n := utf8.RuneCountInString(v)
+	if s.MinLength != nil && n < *s.MinLength {
+		problems = append(problems, fmt.Sprintf("%s: length must be at least %d", at, *s.MinLength))
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		problems = append(problems, fmt.Sprintf("%s: length must be at most %d", at, *s.MaxLength))
+	}
+
+	var err error
+	switch s.Format {
+	case "uuid":
+		_, err = uuid.Parse(v)
+	case "date-time":
+		_, err = time.Parse(time.RFC3339Nano, v)
+	case "date":
+		if !datePattern.MatchString(v) {
+			err = fmt.Errorf("invalid date")
+		}
+	case "email":
+		_, err = mail.ParseAddress(v)
+	}
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("%s: must be a valid %s", at, s.Format))
+	}
+	return problems
+}
+
+func validateNumber(v float64, s *Schema, at string) []string {
+	var problems []string
+	if s.Minimum != nil && v < *s.Minimum {
+		problems = append(problems, fmt.Sprintf("%s: must be at least %v", at, *s.Minimum))
+	}
+	if s.Maximum != nil && v > *s.Maximum {
+		problems = append(problems, fmt.Sprintf("%s: must be at most %v", at, *s.Maximum))
+	}
+	if s.ExclusiveMinimum != nil && v <= *s.ExclusiveMinimum {
+		problems = append(problems, fmt.Sprintf("%s: must be greater than %v", at, *s.ExclusiveMinimum))
+	}
+	if s.ExclusiveMaximum != nil && v >= *s.ExclusiveMaximum {
+		problems = append(problems, fmt.Sprintf("%s: must be less than %v", at, *s.ExclusiveMaximum))
+	}
+	return problems
+}
+
+func (d *Document) resolve(ref string) *Schema {
+	return d.Components.Schemas[strings.TrimPrefix(ref, "#/components/schemas/")]
+}
+
+func schemaTypes(s *Schema) []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []interface{}:
+		// Documents decoded from JSON
+		out := make([]string, 0, len(t))
+		for _, v := range t {
+			out = append(out, fmt.Sprint(v))
+		}
+		return out
+	}
+	return nil
+}
+
+func hasType(types []string, v interface{}) bool {
+	for _, t := range types {
+		switch val := v.(type) {
+		case nil:
+			if t == "null" {
+				return true
+			}
+		case bool:
+			if t == "boolean" {
+				return true
+			}
+		case string:
+			if t == "string" {
+				return true
+			}
+		case float64:
+			if t == "number" || t == "integer" && val == math.Trunc(val) {
+				return true
+			}
+		case []interface{}:
+			if t == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if t == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(enum []interface{}, v interface{}) bool {
+	for _, e := range enum {
+		if fmt.Sprint(e) == fmt.Sprint(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
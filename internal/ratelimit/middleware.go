@@ -0,0 +1,138 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+)
+
+// Options are read on every request so reloaded settings apply immediately.
+type Options struct {
+	Enabled           bool
+	Policies          []Policy
+	TrustForwardedFor bool
+	APIKeyHeader      string
+}
+
+type Limiter struct {
+	store   Store
+	options func() Options
+	now     func() time.Time
+}
+
+func NewLimiter(store Store, options func() Options) *Limiter {
+	return &Limiter{store: store, options: options, now: time.Now}
+}
+
+// Middleware checks every policy matching the request. The request is
+// rejected with 429 when any of them is exhausted, and the RateLimit headers
+// describe the most restrictive one.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		opts := l.options()
+		if !opts.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var (
+			tightest *Result
+			policy   Policy
+		)
+		now := l.now()
+		for _, p := range opts.Policies {
+			if !p.Matches(r.Method, r.URL.Path) {
+				continue
+			}
+
+			res, err := l.store.Allow(r.Context(), l.key(r, p, opts), p, now)
+			if err != nil {
+				// Fail open, an unavailable backend shouldn't take the API down
+				logger.FromContext(r.Context()).Error("rate limit check failed", "policy", p.Name, "error", err)
+				continue
+			}
+			if tightest == nil || !res.Allowed && tightest.Allowed || res.Allowed == tightest.Allowed && res.Remaining < tightest.Remaining {
+				tightest, policy = &res, p
+			}
+		}
+
+		if tightest == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(tightest.Reset)))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, ceilSeconds(policy.Window)))
+
+		if !tightest.Allowed {
+			retryAfter := ceilSeconds(tightest.RetryAfter)
+			h.Set("Retry-After", strconv.Itoa(retryAfter))
+			response.JSONError(w, errors.TooManyRequestsError(errors.ErrTooManyRequests, map[string]interface{}{
+				"policy":      policy.Name,
+				"retry_after": retryAfter,
+			}))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// key identifies the client for a policy. Requests without an API key or
+// authenticated user fall back to the client IP. API keys are hashed so they
+// are never stored in the backend.
+func (l *Limiter) key(r *http.Request, p Policy, opts Options) string {
+	switch p.Key {
+	case KeyAPIKey:
+		header := opts.APIKeyHeader
+		if header == "" {
+			header = "X-API-Key"
+		}
+		if k := r.Header.Get(header); k != "" {
+			sum := sha256.Sum256([]byte(k))
+			return p.Name + ":api_key:" + hex.EncodeToString(sum[:])
+		}
+	case KeyUser:
+		if id := logger.UserIDFromContext(r.Context()); id != "" {
+			return p.Name + ":user:" + id
+		}
+	}
+	return p.Name + ":ip:" + ClientIP(r, opts.TrustForwardedFor)
+}
+
+// ClientIP returns the address of the client. X-Forwarded-For is only
+// honoured behind a trusted proxy, since clients can set it freely.
+func ClientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+			ip, _, _ := strings.Cut(xff, ",")
+			return strings.TrimSpace(ip)
+		}
+		if ip := r.Header.Get("X-Real-IP"); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
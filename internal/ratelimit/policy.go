@@ -0,0 +1,201 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	AlgorithmTokenBucket   = "token_bucket"
+	AlgorithmSlidingWindow = "sliding_window"
+
+	KeyIP     = "ip"
+	KeyAPIKey = "api_key"
+	KeyUser   = "user"
+)
+
+// Policy allows Limit requests per Window for each client identified by Key,
+// on requests matching Methods and Path. Path is either exact or a prefix
+// ending in "*"; an empty Path or "*" matches everything.
+type Policy struct {
+	Name      string        `json:"name"`
+	Methods   []string      `json:"methods,omitempty"`
+	Path      string        `json:"path,omitempty"`
+	Limit     int           `json:"limit"`
+	Window    time.Duration `json:"window"`
+	Key       string        `json:"key,omitempty"`
+	Algorithm string        `json:"algorithm,omitempty"`
+}
+
+// UnmarshalJSON accepts windows written as duration strings, e.g. "1m".
+func (p *Policy) UnmarshalJSON(data []byte) error {
+	type alias Policy
+	aux := struct {
+		*alias
+		Window interface{} `json:"window"`
+	}{alias: (*alias)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	switch w := aux.Window.(type) {
+	case string:
+		d, err := time.ParseDuration(w)
+		if err != nil {
+			return fmt.Errorf("policy %q: invalid window %q", p.Name, w)
+		}
+		p.Window = d
+	case float64:
+		p.Window = time.Duration(w)
+	}
+	return nil
+}
+
+func (p Policy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("policy name is required")
+	}
+	if p.Limit <= 0 {
+		return fmt.Errorf("policy %q: limit must be positive", p.Name)
+	}
+	if p.Window <= 0 {
+		return fmt.Errorf("policy %q: window must be positive", p.Name)
+	}
+	switch p.Key {
+	case "", KeyIP, KeyAPIKey, KeyUser:
+	default:
+		return fmt.Errorf("policy %q: unknown key %q", p.Name, p.Key)
+	}
+	switch p.Algorithm {
+	case "", AlgorithmTokenBucket, AlgorithmSlidingWindow:
+	default:
+		return fmt.Errorf("policy %q: unknown algorithm %q", p.Name, p.Algorithm)
+	}
+	return nil
+}
+
+func (p Policy) Matches(method, path string) bool {
+	if len(p.Methods) > 0 {
+		found := false
+		for _, m := range p.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	switch {
+	case p.Path == "" || p.Path == "*":
+		return true
+	case strings.HasSuffix(p.Path, "*"):
+		return strings.HasPrefix(path, strings.TrimSuffix(p.Path, "*"))
+	default:
+		return path == p.Path
+	}
+}
+
+// Result describes the outcome of a single rate limit check.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// state is the per-key data persisted by a Store. Token buckets use Tokens
+// and Stamp (last refill); sliding windows use Prev, Curr and Stamp (start of
+// the current window).
+type state struct {
+	Tokens float64
+	Prev   int
+	Curr   int
+	Stamp  time.Time
+}
+
+// ttl is how long state must be kept before it no longer affects decisions.
+func (p Policy) ttl() time.Duration {
+	return 2 * p.Window
+}
+
+// apply records one request against st and reports whether it is allowed.
+func (p Policy) apply(st *state, now time.Time) Result {
+	if p.Algorithm == AlgorithmSlidingWindow {
+		return p.applySlidingWindow(st, now)
+	}
+	return p.applyTokenBucket(st, now)
+}
+
+func (p Policy) applyTokenBucket(st *state, now time.Time) Result {
+	rate := float64(p.Limit) / p.Window.Seconds()
+
+	if st.Stamp.IsZero() {
+		st.Tokens = float64(p.Limit)
+	} else if elapsed := now.Sub(st.Stamp).Seconds(); elapsed > 0 {
+		st.Tokens = math.Min(float64(p.Limit), st.Tokens+elapsed*rate)
+	}
+	st.Stamp = now
+
+	res := Result{Limit: p.Limit}
+	if st.Tokens >= 1 {
+		st.Tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = time.Duration((1 - st.Tokens) / rate * float64(time.Second))
+	}
+
+	res.Remaining = int(st.Tokens)
+	res.Reset = time.Duration((float64(p.Limit) - st.Tokens) / rate * float64(time.Second))
+	return res
+}
+
+// applySlidingWindow approximates a sliding log by weighting the previous
+// fixed window's count by how much of it still overlaps the sliding window.
+func (p Policy) applySlidingWindow(st *state, now time.Time) Result {
+	windowStart := now.Truncate(p.Window)
+	switch {
+	case st.Stamp.Equal(windowStart):
+	case st.Stamp.Add(p.Window).Equal(windowStart):
+		st.Prev, st.Curr = st.Curr, 0
+	default:
+		st.Prev, st.Curr = 0, 0
+	}
+	st.Stamp = windowStart
+
+	elapsed := now.Sub(windowStart)
+	weight := 1 - float64(elapsed)/float64(p.Window)
+	used := float64(st.Prev)*weight + float64(st.Curr)
+
+	res := Result{Limit: p.Limit, Reset: p.Window - elapsed}
+	if used+1 <= float64(p.Limit) {
+		st.Curr++
+		used++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = p.Window - elapsed
+	}
+
+	res.Remaining = int(math.Max(0, float64(p.Limit)-used))
+	return res
+}
+
+// MarshalYAML prints the window as a duration string, matching what config
+// files accept.
+func (p Policy) MarshalYAML() (interface{}, error) {
+	return struct {
+		Name      string   `yaml:"name"`
+		Methods   []string `yaml:"methods,omitempty"`
+		Path      string   `yaml:"path,omitempty"`
+		Limit     int      `yaml:"limit"`
+		Window    string   `yaml:"window"`
+		Key       string   `yaml:"key,omitempty"`
+		Algorithm string   `yaml:"algorithm,omitempty"`
+	}{p.Name, p.Methods, p.Path, p.Limit, p.Window.String(), p.Key, p.Algorithm}, nil
+}
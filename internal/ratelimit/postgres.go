@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// postgresStore keeps state in the rate_limits table so limits hold across
+// replicas. Each check locks the key's row, creating it if need be, for the
+// duration of a short transaction. Windows and expiry follow the database clock rather than the
+// caller's now, so replicas with skewed clocks agree on shared limits.
+type postgresStore struct {
+	db    *sqlx.DB
+	calls atomic.Int64
+}
+
+func NewPostgresStore(db *sqlx.DB) Store {
+	return &postgresStore{db: db}
+}
+
+type stateRow struct {
+	Tokens    float64   `db:"tokens"`
+	PrevCount int       `db:"prev_count"`
+	CurrCount int       `db:"curr_count"`
+	Stamp     time.Time `db:"stamp"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+func (s *postgresStore) Allow(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	tx, err := s.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.GetContext(ctx, &now, "SELECT now()"); err != nil {
+		return Result{}, err
+	}
+
+	// Purge expired keys now and then
+	if s.calls.Add(1)%1000 == 0 {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM rate_limits WHERE expires_at < now()"); err != nil {
+			return Result{}, err
+		}
+	}
+
+	// FOR UPDATE locks nothing while the key has no row, so create an
+	// expired one first; concurrent first hits then queue on it instead of
+	// each starting from an empty state
+	if _, err := tx.ExecContext(ctx, `INSERT INTO rate_limits (key, stamp, expires_at) VALUES ($1, now(), now())
+		ON CONFLICT (key) DO NOTHING`, key); err != nil {
+		return Result{}, err
+	}
+
+	var row stateRow
+	err = tx.GetContext(ctx, &row,
+		"SELECT tokens, prev_count, curr_count, stamp, expires_at FROM rate_limits WHERE key = $1 FOR UPDATE", key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	var st state
+	if err == nil && now.Before(row.ExpiresAt) {
+		st = state{Tokens: row.Tokens, Prev: row.PrevCount, Curr: row.CurrCount, Stamp: row.Stamp}
+	}
+
+	res := policy.apply(&st, now)
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO rate_limits (key, tokens, prev_count, curr_count, stamp, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = EXCLUDED.tokens,
+			prev_count = EXCLUDED.prev_count,
+			curr_count = EXCLUDED.curr_count,
+			stamp = EXCLUDED.stamp,
+			expires_at = EXCLUDED.expires_at`,
+		key, st.Tokens, st.Prev, st.Curr, st.Stamp, now.Add(policy.ttl()))
+	if err != nil {
+		return Result{}, err
+	}
+
+	return res, tx.Commit()
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Store applies a policy to a key and persists the resulting state.
+type Store interface {
+	Allow(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+type memoryEntry struct {
+	state     state
+	expiresAt time.Time
+}
+
+// memoryStore keeps state in process. Limits are per replica.
+type memoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	calls   int
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *memoryStore) Allow(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Evict idle keys periodically so the map doesn't grow unbounded
+	s.calls++
+	if s.calls%1000 == 0 {
+		for k, e := range s.entries {
+			if now.After(e.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+	}
+
+	e, ok := s.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		e = &memoryEntry{}
+		s.entries[key] = e
+	}
+
+	res := policy.apply(&e.state, now)
+	e.expiresAt = now.Add(policy.ttl())
+	return res, nil
+}
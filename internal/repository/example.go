@@ -11,6 +11,11 @@ type ExampleRepository interface {
 	Create(ctx context.Context, req models.CreateExampleRequest) (*models.Example, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Example, error)
 	List(ctx context.Context, limit, offset uint64) ([]models.Example, error)
+	// Search returns the examples selected by q.
+	Search(ctx context.Context, q models.ExampleQuery) ([]models.Example, error)
+	// GetByIDs returns the examples with the given ids that exist, in no
+	// particular order.
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Example, error)
 	Update(ctx context.Context, id uuid.UUID, req models.UpdateExampleRequest) (*models.Example, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
@@ -0,0 +1,10 @@
+package repository
+
+import "context"
+
+// Transactor runs fn in a database transaction. Repository calls made with
+// the context fn receives run in it; it commits when fn returns nil and
+// rolls back otherwise.
+type Transactor interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -0,0 +1,175 @@
+package scaffold
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// RoutesFile is where generated resources are registered, above the line
+// containing RoutesMarker.
+const (
+	RoutesFile   = "internal/server/routes.go"
+	RoutesMarker = "cmd/tools/generate adds new resources above this line"
+)
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"lowerFirst": func(s string) string { return strings.ToLower(s[:1]) + s[1:] },
+	"title":      func(s string) string { return strings.ToUpper(s[:1]) + s[1:] },
+	"plural": func(label string) string {
+		return strings.ReplaceAll(pluralize(strings.ReplaceAll(label, " ", "_")), "_", " ")
+	},
+	"article": article,
+}).ParseFS(templateFS, "templates/*.tmpl"))
+
+// File is a rendered file, with Path relative to the repository root.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Render produces every file for res. now timestamps the migration.
+func Render(res *Resource, now time.Time) ([]File, error) {
+	targets := []struct{ tmpl, path string }{
+		{"model.go.tmpl", "internal/models/" + res.Snake + ".go"},
+		{"repository.go.tmpl", "internal/repository/" + res.Snake + ".go"},
+		{"database.go.tmpl", "internal/database/" + res.Snake + "_repository.go"},
+		{"service.go.tmpl", "internal/services/" + res.Snake + "_service.go"},
+		{"handler.go.tmpl", "internal/handlers/" + res.Snake + ".go"},
+		{"migration.sql.tmpl", fmt.Sprintf("database/migrations/%s_create_%s_table.sql", now.UTC().Format("20060102150405"), res.Table)},
+		{"test.go.tmpl", "tests/" + res.Snake + "_test.go"},
+	}
+
+	files := make([]File, 0, len(targets))
+	for _, t := range targets {
+		var buf bytes.Buffer
+		if err := templates.ExecuteTemplate(&buf, t.tmpl, res); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.path, err)
+		}
+		content := buf.Bytes()
+		if strings.HasSuffix(t.path, ".go") {
+			formatted, err := format.Source(content)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", t.path, err)
+			}
+			content = formatted
+		}
+		files = append(files, File{Path: t.path, Content: content})
+	}
+	return files, nil
+}
+
+// Generate writes the files for res under root and registers its routes.
+// Nothing is written if any file already exists or the routes cannot be
+// registered. It returns the paths written.
+func Generate(root string, res *Resource, now time.Time) ([]string, error) {
+	if res.Module == "" {
+		module, err := ModulePath(root)
+		if err != nil {
+			return nil, err
+		}
+		res.Module = module
+	}
+
+	files, err := Render(res, now)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(root, f.Path)); err == nil {
+			return nil, fmt.Errorf("%s already exists", f.Path)
+		}
+	}
+	existing, _ := filepath.Glob(filepath.Join(root, "database/migrations", "*_create_"+res.Table+"_table.sql"))
+	if len(existing) > 0 {
+		return nil, fmt.Errorf("a migration creating %s already exists", res.Table)
+	}
+
+	routes, err := registerRoutes(root, res)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for _, f := range files {
+		if err := writeFile(filepath.Join(root, f.Path), f.Content); err != nil {
+			return written, err
+		}
+		written = append(written, f.Path)
+	}
+	if err := writeFile(filepath.Join(root, RoutesFile), routes); err != nil {
+		return written, err
+	}
+	return append(written, RoutesFile), nil
+}
+
+// registerRoutes returns the routes file with res added to the resources
+// mounted by RegisterRoutes.
+func registerRoutes(root string, res *Resource) ([]byte, error) {
+	src, err := os.ReadFile(filepath.Join(root, RoutesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	entry := fmt.Sprintf("{%q, %q, handlers.New%sHandler(services.New%sService(database.New%sRepository(s.db.GetDB())))},",
+		res.Path, strings.TrimPrefix(res.Path, "/"), res.Name, res.Name, res.Name)
+	if bytes.Contains(src, []byte(entry)) {
+		return nil, fmt.Errorf("%s already registers %s", RoutesFile, res.Path)
+	}
+
+	var out bytes.Buffer
+	found := false
+	for _, line := range strings.SplitAfter(string(src), "\n") {
+		if !found && strings.Contains(line, RoutesMarker) {
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			out.WriteString(indent + entry + "\n")
+			found = true
+		}
+		out.WriteString(line)
+	}
+	if !found {
+		return nil, fmt.Errorf("%s: marker %q not found", RoutesFile, RoutesMarker)
+	}
+	return format.Source(out.Bytes())
+}
+
+// ModulePath reads the module path from root/go.mod.
+func ModulePath(root string) (string, error) {
+	f, err := os.Open(filepath.Join(root, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "module "); ok {
+			return strings.Trim(strings.TrimSpace(module), `"`), nil
+		}
+	}
+	return "", fmt.Errorf("go.mod: module directive not found")
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
+}
+
+func article(label string) string {
+	if strings.ContainsRune("aeiou", rune(label[0])) {
+		return "an " + label
+	}
+	return "a " + label
+}
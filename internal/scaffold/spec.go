@@ -0,0 +1,320 @@
+// Package scaffold generates the model, repository, service, handler,
+// migration and tests for a new resource, following the examples resource.
+package scaffold
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// FieldType maps a field type accepted on the command line to its Go and
+// Postgres types.
+type FieldType struct {
+	Go  string
+	SQL string
+}
+
+var fieldTypes = map[string]FieldType{
+	"string": {Go: "string", SQL: "TEXT"},
+	"text":   {Go: "string", SQL: "TEXT"},
+	"int":    {Go: "int64", SQL: "BIGINT"},
+	"int32":  {Go: "int32", SQL: "INTEGER"},
+	"float":  {Go: "float64", SQL: "DOUBLE PRECISION"},
+	"bool":   {Go: "bool", SQL: "BOOLEAN"},
+	"time":   {Go: "time.Time", SQL: "TIMESTAMP WITH TIME ZONE"},
+	"uuid":   {Go: "uuid.UUID", SQL: "UUID"},
+}
+
+// reservedColumns are added to every resource.
+var reservedColumns = []string{"id", "created_at", "updated_at"}
+
+// initialisms are written in upper case in Go names, as golint expects.
+var initialisms = map[string]bool{
+	"api": true, "http": true, "id": true, "ip": true, "json": true,
+	"sql": true, "uri": true, "url": true, "uuid": true,
+}
+
+var identPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Resource describes the entity to generate.
+type Resource struct {
+	Module string
+	// Name is the Go type, e.g. ProductReview
+	Name string
+	// Var is the unexported Go name, e.g. productReview
+	Var string
+	// Plural is the exported plural, e.g. ProductReviews
+	Plural string
+	// Snake names files, e.g. product_review
+	Snake string
+	// Table is the snake case plural, e.g. product_reviews
+	Table string
+	// Path is the route prefix, e.g. /product-reviews
+	Path string
+	// Label is used in messages, e.g. product review
+	Label  string
+	Fields []Field
+}
+
+type Field struct {
+	Name     string
+	Column   string
+	Type     FieldType
+	Validate string
+}
+
+// ParseResource builds a resource from an entity name in snake or camel case
+// and fields written as name:type[:validations]. Validations use validator
+// tag syntax, e.g. price:float:required,gt=0.
+func ParseResource(name string, fields []string, plural string) (*Resource, error) {
+	snake := toSnake(name)
+	if !identPattern.MatchString(snake) {
+		return nil, fmt.Errorf("invalid entity name %q", name)
+	}
+	if plural == "" {
+		plural = pluralize(snake)
+	}
+	plural = toSnake(plural)
+	if !identPattern.MatchString(plural) {
+		return nil, fmt.Errorf("invalid plural %q", plural)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("at least one field is required")
+	}
+
+	res := &Resource{
+		Name:   toCamel(snake, true),
+		Var:    toCamel(snake, false),
+		Plural: toCamel(plural, true),
+		Snake:  snake,
+		Table:  plural,
+		Path:   "/" + strings.ReplaceAll(plural, "_", "-"),
+		Label:  strings.ReplaceAll(snake, "_", " "),
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range reservedColumns {
+		seen[c] = true
+	}
+	for _, spec := range fields {
+		f, err := parseField(spec)
+		if err != nil {
+			return nil, err
+		}
+		if seen[f.Column] {
+			return nil, fmt.Errorf("field %q: duplicate or reserved column", f.Column)
+		}
+		seen[f.Column] = true
+		res.Fields = append(res.Fields, f)
+	}
+	return res, nil
+}
+
+func parseField(spec string) (Field, error) {
+	parts := strings.SplitN(spec, ":", 3)
+	if len(parts) < 2 {
+		return Field{}, fmt.Errorf("field %q: must be name:type[:validations]", spec)
+	}
+
+	column := toSnake(parts[0])
+	if !identPattern.MatchString(column) {
+		return Field{}, fmt.Errorf("field %q: invalid name", spec)
+	}
+	typ, ok := fieldTypes[parts[1]]
+	if !ok {
+		return Field{}, fmt.Errorf("field %q: unknown type %q", spec, parts[1])
+	}
+
+	f := Field{Name: toCamel(column, true), Column: column, Type: typ}
+	if len(parts) == 3 {
+		f.Validate = parts[2]
+	}
+	return f, nil
+}
+
+// Required reports whether the validations include required.
+func (f Field) Required() bool {
+	for _, rule := range f.rules() {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateValidate is Validate for the optional fields of an update request.
+func (f Field) UpdateValidate() string {
+	var rules []string
+	for _, rule := range f.rules() {
+		if rule != "required" && rule != "omitempty" {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return ""
+	}
+	return strings.Join(append([]string{"omitempty"}, rules...), ",")
+}
+
+// ColumnDef is the column definition in the create table migration.
+func (f Field) ColumnDef() string {
+	def := f.Column + " " + f.Type.SQL + " NOT NULL"
+	if f.Type.Go == "bool" {
+		def += " DEFAULT false"
+	}
+	return def
+}
+
+// Sample is a Go expression for a value passing the field's validations,
+// used by the generated tests.
+func (f Field) Sample() string {
+	rules := make(map[string]string)
+	for _, rule := range f.rules() {
+		name, param, _ := strings.Cut(rule, "=")
+		rules[name] = param
+	}
+
+	switch f.Type.Go {
+	case "string":
+		return strconv.Quote(sampleString(f.Label(), rules))
+	case "int64", "int32":
+		return fmt.Sprintf("%s(%d)", f.Type.Go, int64(sampleNumber(rules, true)))
+	case "float64":
+		return fmt.Sprintf("float64(%s)", strconv.FormatFloat(sampleNumber(rules, false), 'f', -1, 64))
+	case "bool":
+		// required fails on false
+		return "true"
+	case "time.Time":
+		return "time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)"
+	default:
+		return `uuid.MustParse("6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b")`
+	}
+}
+
+// Label is the field name in words.
+func (f Field) Label() string {
+	return strings.ReplaceAll(f.Column, "_", " ")
+}
+
+func (f Field) rules() []string {
+	if f.Validate == "" {
+		return nil
+	}
+	return strings.Split(f.Validate, ",")
+}
+
+func sampleString(label string, rules map[string]string) string {
+	if options, ok := rules["oneof"]; ok {
+		return strings.Fields(options)[0]
+	}
+	if _, ok := rules["email"]; ok {
+		return "user@example.com"
+	}
+	if _, ok := rules["url"]; ok {
+		return "https://example.com"
+	}
+	if _, ok := rules["uuid"]; ok {
+		return "6f1c2b3a-4d5e-4f60-8a7b-9c0d1e2f3a4b"
+	}
+
+	s := "Sample " + label
+	if n, err := strconv.Atoi(rules["len"]); err == nil {
+		return strings.Repeat("a", n)
+	}
+	if n, err := strconv.Atoi(rules["min"]); err == nil && len(s) < n {
+		s += strings.Repeat("x", n-len(s))
+	}
+	if n, err := strconv.Atoi(rules["max"]); err == nil && len(s) > n {
+		s = strings.Repeat("a", n)
+	}
+	return s
+}
+
+func sampleNumber(rules map[string]string, integer bool) float64 {
+	if options, ok := rules["oneof"]; ok {
+		n, _ := strconv.ParseFloat(strings.Fields(options)[0], 64)
+		return n
+	}
+
+	step := 0.5
+	if integer {
+		step = 1
+	}
+	v := 42.0
+	bound := func(name string) (float64, bool) {
+		n, err := strconv.ParseFloat(rules[name], 64)
+		return n, err == nil
+	}
+	if n, ok := bound("len"); ok {
+		return n
+	}
+	for _, name := range []string{"min", "gte"} {
+		if n, ok := bound(name); ok && v < n {
+			v = n
+		}
+	}
+	if n, ok := bound("gt"); ok && v <= n {
+		v = n + step
+	}
+	for _, name := range []string{"max", "lte"} {
+		if n, ok := bound(name); ok && v > n {
+			v = n
+		}
+	}
+	if n, ok := bound("lt"); ok && v >= n {
+		v = n - step
+	}
+	return v
+}
+
+func toSnake(s string) string {
+	var b strings.Builder
+	runes := []rune(strings.TrimSpace(s))
+	for i, r := range runes {
+		switch {
+		case r == '-' || r == ' ':
+			b.WriteRune('_')
+		case unicode.IsUpper(r):
+			// Split before an upper case letter that starts a word
+			if i > 0 && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) && runes[i-1] != '_' {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func toCamel(snake string, exported bool) string {
+	var b strings.Builder
+	for i, word := range strings.Split(snake, "_") {
+		if word == "" {
+			continue
+		}
+		switch {
+		case i == 0 && !exported:
+			b.WriteString(word)
+		case initialisms[word]:
+			b.WriteString(strings.ToUpper(word))
+		default:
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+func pluralize(snake string) string {
+	switch {
+	case strings.HasSuffix(snake, "y") && !strings.HasSuffix(snake, "ay") && !strings.HasSuffix(snake, "ey") && !strings.HasSuffix(snake, "oy"):
+		return strings.TrimSuffix(snake, "y") + "ies"
+	case strings.HasSuffix(snake, "s"), strings.HasSuffix(snake, "x"), strings.HasSuffix(snake, "ch"), strings.HasSuffix(snake, "sh"):
+		return snake + "es"
+	default:
+		return snake + "s"
+	}
+}
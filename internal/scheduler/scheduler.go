@@ -0,0 +1,435 @@
+// Package scheduler runs periodic tasks on exactly one replica. Replicas
+// compete for a lease in the scheduler_leases table; the holder runs each
+// task when it is due and records the run in the schedules table, where a
+// slot can only be claimed once, even across a change of leader.
+//
+//	s.Add(scheduler.Task{
+//		Name:     "purge_sessions",
+//		Schedule: "0 3 * * *",
+//		Jitter:   time.Minute,
+//		Run:      sessions.Purge,
+//	})
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/ctrixcode/go-chi-postgres/internal/scheduler")
+
+const (
+	leaseName = "scheduler"
+	// queryTimeout bounds the bookkeeping queries, which run even after a
+	// task's context has ended.
+	queryTimeout = 5 * time.Second
+)
+
+// MissedPolicy decides what happens to runs that were due while no replica
+// was leader, or while the previous run was still going.
+type MissedPolicy string
+
+const (
+	// MissedSkip drops missed runs and waits for the next slot.
+	MissedSkip MissedPolicy = "skip"
+	// MissedRunOnce runs once as soon as possible, however many slots were
+	// missed.
+	MissedRunOnce MissedPolicy = "run_once"
+)
+
+type Task struct {
+	// Name identifies the task in the schedules table; renaming a task
+	// loses its history.
+	Name string
+	// Schedule is a cron expression such as "0 3 * * *", a descriptor such
+	// as "@daily", or an interval such as "@every 10m". Cron expressions use
+	// the server's time zone unless prefixed with CRON_TZ=<zone>.
+	Schedule string
+	// Jitter delays each run by up to this much, so tasks sharing a slot do
+	// not all start at once. It should be well below the interval.
+	Jitter time.Duration
+	// Missed defaults to MissedSkip.
+	Missed MissedPolicy
+	// Timeout bounds a run; 0 leaves it unbounded.
+	Timeout time.Duration
+	Run     func(ctx context.Context) error
+}
+
+// Options are read on every tick, so they can change at runtime.
+type Options struct {
+	// LeaseTTL is how long leadership lasts without renewal. The leader
+	// renews it every third of the TTL, so a replica that dies is replaced
+	// within one TTL.
+	LeaseTTL time.Duration
+}
+
+type task struct {
+	Task
+	schedule cron.Schedule
+
+	// Guarded by Scheduler.mu. next is the slot the task runs for next and
+	// fireAt when that run starts, jitter included.
+	next    time.Time
+	fireAt  time.Time
+	running bool
+}
+
+// Scheduler runs the added tasks while it holds the lease.
+type Scheduler struct {
+	id      string
+	queries *queries.Queries
+	options func() Options
+
+	mu    sync.Mutex
+	tasks []*task
+
+	runs     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	leading  *prometheus.GaugeVec
+
+	// Only touched by the run loop, and by Shutdown once the loop is done
+	leader     bool
+	leaseUntil time.Time
+	runCtx     context.Context
+	cancelRuns context.CancelFunc
+	inFlight   sync.WaitGroup
+
+	started  atomic.Bool
+	stopOnce sync.Once
+	// wake is signalled when a run finishes, as a run may be waiting for it
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func New(db queries.DBTX, options func() Options, m *metrics.Metrics) *Scheduler {
+	host, _ := os.Hostname()
+	runCtx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		id:         fmt.Sprintf("%s-%d", host, os.Getpid()),
+		queries:    database.NewQueries(db),
+		options:    options,
+		runs:       m.Counter("scheduler_runs_total", "Scheduled task runs by task and outcome.", "task", "outcome"),
+		duration:   m.Histogram("scheduler_run_duration_seconds", "Scheduled task run duration by task.", []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900}, "task"),
+		leading:    m.Gauge("scheduler_leader", "Whether this replica holds the scheduler lease."),
+		runCtx:     runCtx,
+		cancelRuns: cancel,
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Add schedules a task. It panics if the schedule does not parse, the name
+// is taken or the scheduler has started, as all three are programming errors.
+func (s *Scheduler) Add(t Task) {
+	if t.Name == "" || t.Run == nil {
+		panic("scheduler: a task needs a name and a Run function")
+	}
+	schedule, err := cron.ParseStandard(t.Schedule)
+	if err != nil {
+		panic(fmt.Sprintf("scheduler: task %s: %v", t.Name, err))
+	}
+	switch t.Missed {
+	case "":
+		t.Missed = MissedSkip
+	case MissedSkip, MissedRunOnce:
+	default:
+		panic(fmt.Sprintf("scheduler: task %s: unknown missed run policy %q", t.Name, t.Missed))
+	}
+	if s.started.Load() {
+		panic("scheduler: tasks must be added before Start")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, existing := range s.tasks {
+		if existing.Name == t.Name {
+			panic(fmt.Sprintf("scheduler: task %s is already scheduled", t.Name))
+		}
+	}
+	s.tasks = append(s.tasks, &task{Task: t, schedule: schedule})
+	sort.Slice(s.tasks, func(i, j int) bool { return s.tasks[i].Name < s.tasks[j].Name })
+}
+
+// Start begins competing for the lease in the background.
+func (s *Scheduler) Start() {
+	if s.started.CompareAndSwap(false, true) {
+		slog.Info("scheduler starting", "holder", s.id, "tasks", len(s.tasks))
+		go s.run()
+	}
+}
+
+// Shutdown stops starting runs and waits for running ones to finish, then
+// releases the lease so another replica can take over straight away. If
+// ctx ends first the runs are cancelled and ctx.Err() is returned.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	if !s.started.Load() {
+		return nil
+	}
+	<-s.done
+
+	drained := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(drained)
+	}()
+	var err error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		s.cancelRuns()
+		<-drained
+		err = ctx.Err()
+	}
+
+	if s.leader {
+		qctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), queryTimeout)
+		defer cancel()
+		if rerr := s.queries.ReleaseSchedulerLease(qctx, queries.ReleaseSchedulerLeaseParams{Name: leaseName, Holder: s.id}); rerr != nil {
+			slog.Error("releasing scheduler lease failed", "error", rerr)
+		}
+		s.leading.WithLabelValues().Set(0)
+	}
+	return err
+}
+
+func (s *Scheduler) run() {
+	defer close(s.done)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+
+		opts := s.options()
+		now := time.Now()
+		wake := now.Add(opts.LeaseTTL / 3)
+		if s.elect(now, opts) {
+			if next := s.dispatch(now); !next.IsZero() && next.Before(wake) {
+				wake = next
+			}
+		}
+		timer.Reset(time.Until(wake))
+	}
+}
+
+// elect takes or renews the lease and reports whether this replica leads.
+func (s *Scheduler) elect(now time.Time, opts Options) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+
+	n, err := s.queries.AcquireSchedulerLease(ctx, queries.AcquireSchedulerLeaseParams{
+		Name:   leaseName,
+		Holder: s.id,
+		TTLMs:  opts.LeaseTTL.Milliseconds(),
+	})
+	held := n > 0
+	if err != nil {
+		slog.Error("acquiring scheduler lease failed", "error", err)
+		// Keep leading on the lease already held
+		held = s.leader && now.Before(s.leaseUntil)
+	} else if held {
+		// Tracked on the local clock from before the query, so it never
+		// outlasts the lease the database granted
+		s.leaseUntil = now.Add(opts.LeaseTTL)
+	}
+
+	switch {
+	case held && !s.leader:
+		if err := s.lead(ctx, now); err != nil {
+			slog.Error("loading schedules failed", "error", err)
+			return false
+		}
+	case !held && s.leader:
+		// Another replica may run the tasks from now on
+		s.leader = false
+		s.cancelRuns()
+		s.leading.WithLabelValues().Set(0)
+		slog.Warn("scheduler lease lost", "holder", s.id)
+	}
+	return s.leader
+}
+
+// lead plans each task's next run from the runs recorded by earlier leaders.
+func (s *Scheduler) lead(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tasks {
+		if err := s.queries.EnsureSchedule(ctx, queries.EnsureScheduleParams{Name: t.Name, Now: now}); err != nil {
+			return err
+		}
+	}
+	rows, err := s.queries.ListSchedules(ctx)
+	if err != nil {
+		return err
+	}
+	recorded := make(map[string]queries.Schedule, len(rows))
+	for _, row := range rows {
+		recorded[row.Name] = row
+	}
+
+	for _, t := range s.tasks {
+		t.next = nextRun(t, recorded[t.Name], now)
+		t.fireAt = t.next.Add(jitter(t.Jitter))
+	}
+	s.runCtx, s.cancelRuns = context.WithCancel(context.Background())
+	s.leader = true
+	s.leading.WithLabelValues().Set(1)
+	slog.Info("scheduler lease acquired", "holder", s.id)
+	return nil
+}
+
+// nextRun returns the slot a task runs for next given its recorded runs.
+// A missed slot is returned as is when the task runs once for missed slots.
+func nextRun(t *task, row queries.Schedule, now time.Time) time.Time {
+	base := now
+	if row.ScheduledAt.Valid {
+		base = row.ScheduledAt.V
+	} else if !row.CreatedAt.IsZero() {
+		base = row.CreatedAt
+	}
+
+	next := t.schedule.Next(base)
+	if next.Before(now) && t.Missed == MissedSkip {
+		next = t.schedule.Next(now)
+	}
+	return next
+}
+
+// dispatch starts the tasks that are due and returns when the next one is.
+func (s *Scheduler) dispatch(now time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var wake time.Time
+	for _, t := range s.tasks {
+		if !now.Before(t.fireAt) {
+			switch {
+			case !t.running:
+				slot := t.next
+				t.running = true
+				s.inFlight.Add(1)
+				go s.execute(s.runCtx, t, slot)
+			case t.Missed == MissedRunOnce:
+				// Runs as soon as the previous run finishes
+				continue
+			default:
+				slog.Warn("scheduled run skipped, previous run still going", "task", t.Name, "slot", t.next)
+				s.runs.WithLabelValues(t.Name, "skipped").Inc()
+			}
+			t.next = t.schedule.Next(now)
+			t.fireAt = t.next.Add(jitter(t.Jitter))
+		}
+		if wake.IsZero() || t.fireAt.Before(wake) {
+			wake = t.fireAt
+		}
+	}
+	return wake
+}
+
+func (s *Scheduler) execute(ctx context.Context, t *task, slot time.Time) {
+	defer func() {
+		s.mu.Lock()
+		t.running = false
+		s.mu.Unlock()
+		s.inFlight.Done()
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}()
+
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	ctx, span := tracer.Start(ctx, "schedule "+t.Name, trace.WithAttributes(
+		attribute.String("schedule.task", t.Name),
+		attribute.String("schedule.slot", slot.Format(time.RFC3339)),
+	))
+	defer span.End()
+	log := slog.With("task", t.Name, "slot", slot)
+
+	qctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), queryTimeout)
+	n, err := s.queries.StartScheduledRun(qctx, queries.StartScheduledRunParams{ScheduledAt: slot, Now: time.Now(), Name: t.Name})
+	cancel()
+	if err != nil {
+		log.Error("recording scheduled run failed", "error", err)
+		return
+	}
+	if n == 0 {
+		log.Info("scheduled run skipped, slot already ran")
+		return
+	}
+
+	start := time.Now()
+	err = call(ctx, t)
+	elapsed := time.Since(start)
+
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		log.Error("scheduled task failed", "duration", elapsed, "error", err)
+	} else {
+		log.Info("scheduled task finished", "duration", elapsed)
+	}
+	s.runs.WithLabelValues(t.Name, outcome).Inc()
+	s.duration.WithLabelValues(t.Name).Observe(elapsed.Seconds())
+
+	qctx, cancel = context.WithTimeout(context.WithoutCancel(ctx), queryTimeout)
+	defer cancel()
+	lastError := sql.Null[string]{}
+	if err != nil {
+		lastError = sql.Null[string]{V: err.Error(), Valid: true}
+	}
+	if err := s.queries.FinishScheduledRun(qctx, queries.FinishScheduledRunParams{Now: time.Now(), Error: lastError, Name: t.Name}); err != nil {
+		log.Error("recording scheduled run failed", "error", err)
+	}
+}
+
+// call runs the task, turning a panic into an error.
+func call(ctx context.Context, t *task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return t.Run(ctx)
+}
+
+func jitter(max time.Duration) time.Duration {
+	if max <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(max)))
+}
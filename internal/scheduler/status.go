@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+)
+
+// Status is the state of the scheduler as recorded in the database, so
+// every replica reports the same.
+type Status struct {
+	// Leader is the replica holding the lease, empty when none does.
+	Leader         string       `json:"leader"`
+	LeaseExpiresAt *time.Time   `json:"lease_expires_at"`
+	Tasks          []TaskStatus `json:"tasks"`
+}
+
+type TaskStatus struct {
+	Name     string       `json:"name"`
+	Schedule string       `json:"schedule"`
+	Missed   MissedPolicy `json:"missed"`
+	Running  bool         `json:"running"`
+	// LastScheduledAt is the slot the last run was for, LastRunAt when it
+	// started.
+	LastScheduledAt *time.Time `json:"last_scheduled_at"`
+	LastRunAt       *time.Time `json:"last_run_at"`
+	LastFinishedAt  *time.Time `json:"last_finished_at"`
+	// LastError is the error of the last finished run, nil if it succeeded.
+	LastError *string `json:"last_error"`
+	// NextRunAt excludes jitter.
+	NextRunAt time.Time `json:"next_run_at"`
+}
+
+func (s *Scheduler) Status(ctx context.Context) (*Status, error) {
+	status := &Status{Tasks: []TaskStatus{}}
+	lease, err := s.queries.GetSchedulerLease(ctx, leaseName)
+	switch {
+	case err == nil:
+		status.Leader = lease.Holder
+		status.LeaseExpiresAt = &lease.ExpiresAt
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	rows, err := s.queries.ListSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]queries.Schedule, len(rows))
+	for _, row := range rows {
+		recorded[row.Name] = row
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.tasks {
+		row := recorded[t.Name]
+		next := nextRun(t, row, now)
+		if next.Before(now) {
+			next = now
+		}
+		status.Tasks = append(status.Tasks, TaskStatus{
+			Name:            t.Name,
+			Schedule:        t.Schedule,
+			Missed:          t.Missed,
+			Running:         row.StartedAt.Valid && (!row.FinishedAt.Valid || row.FinishedAt.V.Before(row.StartedAt.V)),
+			LastScheduledAt: nullTime(row.ScheduledAt),
+			LastRunAt:       nullTime(row.StartedAt),
+			LastFinishedAt:  nullTime(row.FinishedAt),
+			LastError:       nullString(row.LastError),
+			NextRunAt:       next,
+		})
+	}
+	return status, nil
+}
+
+func nullTime(t sql.Null[time.Time]) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.V
+}
+
+func nullString(s sql.Null[string]) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.V
+}
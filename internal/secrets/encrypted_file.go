@@ -0,0 +1,112 @@
+package secrets
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// encryptedFileProvider reads secrets from a JSON object encrypted with
+// AES-256-GCM. The file holds base64(nonce || ciphertext) and is re-read on
+// every lookup so rotated values are picked up.
+type encryptedFileProvider struct {
+	path string
+	key  []byte
+}
+
+func NewEncryptedFileProvider(path string, key []byte) (Provider, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(key))
+	}
+	return &encryptedFileProvider{path: path, key: key}, nil
+}
+
+// GetSecret looks up name in the decrypted object. Names may use "path#key"
+// to address a field of a nested object.
+func (p *encryptedFileProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	values, err := p.read()
+	if err != nil {
+		return "", err
+	}
+
+	if v, ok := values[name]; ok {
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+
+	path, key := splitName(name)
+	if nested, ok := values[path].(map[string]interface{}); ok {
+		if s, ok := nested[key].(string); ok {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+func (p *encryptedFileProvider) read() (map[string]interface{}, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", p.path, err)
+	}
+
+	gcm, err := newGCM(p.key)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return nil, fmt.Errorf("decrypt %s: file too short", p.path)
+	}
+
+	plaintext, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", p.path, err)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", p.path, err)
+	}
+	return values, nil
+}
+
+// EncryptFile writes values to path in the format read by the encrypted file
+// provider.
+func EncryptFile(path string, key []byte, values map[string]interface{}) error {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(sealed)+"\n"), 0o600)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
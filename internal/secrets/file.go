@@ -0,0 +1,26 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// fileProvider reads each secret from the file it is named after, as mounted
+// by Docker and Kubernetes secrets. Files are re-read on every lookup so
+// rotated values are picked up.
+type fileProvider struct{}
+
+func NewFileProvider() Provider {
+	return fileProvider{}
+}
+
+// GetSecret returns the contents of the file at name without the trailing
+// newline.
+func (fileProvider) GetSecret(ctx context.Context, name string) (string, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Refresher periodically re-reads a secret so rotated values are picked up
+// without a restart.
+type Refresher struct {
+	provider Provider
+	name     string
+	interval time.Duration
+	value    atomic.Value
+}
+
+func NewRefresher(provider Provider, name, initial string, interval time.Duration) *Refresher {
+	r := &Refresher{
+		provider: provider,
+		name:     name,
+		interval: interval,
+	}
+	r.value.Store(initial)
+	return r
+}
+
+// Value returns the most recently fetched secret.
+func (r *Refresher) Value() string {
+	return r.value.Load().(string)
+}
+
+// Run refreshes the secret until ctx is cancelled. Failed refreshes keep the
+// previous value.
+func (r *Refresher) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fetchCtx, cancel := context.WithTimeout(ctx, r.interval)
+			value, err := r.provider.GetSecret(fetchCtx, r.name)
+			cancel()
+			if err != nil {
+				slog.Warn("failed to refresh secret", "secret", r.name, "error", err)
+				continue
+			}
+			if value != r.Value() {
+				r.value.Store(value)
+				slog.Info("secret rotated", "secret", r.name)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// Prefix marks a configuration value as a reference to be resolved through
+// the configured Provider, e.g. "secret:app/db#password".
+const Prefix = "secret:"
+
+var ErrNotFound = errors.New("secret not found")
+
+// Provider resolves secret names to their current values.
+type Provider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// IsReference reports whether value is a secret reference and returns the
+// referenced name.
+func IsReference(value string) (string, bool) {
+	if !strings.HasPrefix(value, Prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, Prefix), true
+}
+
+// splitName splits "path#key" into its parts, defaulting the key to "value".
+func splitName(name string) (string, string) {
+	path, key, found := strings.Cut(name, "#")
+	if !found || key == "" {
+		key = "value"
+	}
+	return path, key
+}
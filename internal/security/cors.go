@@ -0,0 +1,115 @@
+package security
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/cors"
+)
+
+// CORSRule is a CORS policy. Origins may contain one wildcard, e.g.
+// "https://*.example.com" or "http://localhost:*".
+type CORSRule struct {
+	// Path limits a route override to an exact path or, ending in "*", a
+	// prefix. It is ignored on the default rule.
+	Path             string   `json:"path,omitempty" yaml:"path,omitempty"`
+	AllowedOrigins   []string `json:"allowed_origins,omitempty" yaml:"allowed_origins,omitempty"`
+	AllowedMethods   []string `json:"allowed_methods,omitempty" yaml:"allowed_methods,omitempty"`
+	AllowedHeaders   []string `json:"allowed_headers,omitempty" yaml:"allowed_headers,omitempty"`
+	ExposedHeaders   []string `json:"exposed_headers,omitempty" yaml:"exposed_headers,omitempty"`
+	AllowCredentials *bool    `json:"allow_credentials,omitempty" yaml:"allow_credentials,omitempty"`
+}
+
+func (r CORSRule) matches(path string) bool {
+	if strings.HasSuffix(r.Path, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(r.Path, "*"))
+	}
+	return path == r.Path
+}
+
+// inherit fills settings left empty in a route override from the default.
+func (r CORSRule) inherit(def CORSRule) CORSRule {
+	if r.AllowedOrigins == nil {
+		r.AllowedOrigins = def.AllowedOrigins
+	}
+	if r.AllowedMethods == nil {
+		r.AllowedMethods = def.AllowedMethods
+	}
+	if r.AllowedHeaders == nil {
+		r.AllowedHeaders = def.AllowedHeaders
+	}
+	if r.ExposedHeaders == nil {
+		r.ExposedHeaders = def.ExposedHeaders
+	}
+	if r.AllowCredentials == nil {
+		r.AllowCredentials = def.AllowCredentials
+	}
+	return r
+}
+
+// CORSOptions holds the default policy and per-route overrides. The longest
+// matching override wins.
+type CORSOptions struct {
+	Default CORSRule
+	Routes  []CORSRule
+	MaxAge  time.Duration
+}
+
+// CORS applies a policy that can be replaced while serving, as on config
+// reload. Handlers are built when the policy is applied, not per request.
+type CORS struct {
+	policy atomic.Pointer[corsPolicy]
+}
+
+type corsPolicy struct {
+	routes   []CORSRule
+	def      func(http.Handler) http.Handler
+	handlers []func(http.Handler) http.Handler
+}
+
+func NewCORS(opts CORSOptions) *CORS {
+	c := &CORS{}
+	c.Apply(opts)
+	return c
+}
+
+// Apply replaces the policy; requests arriving afterwards use opts.
+func (c *CORS) Apply(opts CORSOptions) {
+	p := &corsPolicy{
+		routes:   opts.Routes,
+		def:      corsHandler(opts.Default, opts.MaxAge),
+		handlers: make([]func(http.Handler) http.Handler, len(opts.Routes)),
+	}
+	for i, rule := range opts.Routes {
+		p.handlers[i] = corsHandler(rule.inherit(opts.Default), opts.MaxAge)
+	}
+	c.policy.Store(p)
+}
+
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := c.policy.Load()
+
+		handler := p.def
+		longest := -1
+		for i, rule := range p.routes {
+			if rule.matches(r.URL.Path) && len(rule.Path) > longest {
+				handler, longest = p.handlers[i], len(rule.Path)
+			}
+		}
+		handler(next).ServeHTTP(w, r)
+	})
+}
+
+func corsHandler(rule CORSRule, maxAge time.Duration) func(http.Handler) http.Handler {
+	return cors.Handler(cors.Options{
+		AllowedOrigins:   rule.AllowedOrigins,
+		AllowedMethods:   rule.AllowedMethods,
+		AllowedHeaders:   rule.AllowedHeaders,
+		ExposedHeaders:   rule.ExposedHeaders,
+		AllowCredentials: rule.AllowCredentials != nil && *rule.AllowCredentials,
+		MaxAge:           int(maxAge.Seconds()),
+	})
+}
@@ -0,0 +1,104 @@
+package security
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HeadersOptions configures the security headers added to every response.
+// Empty values leave the corresponding header unset.
+type HeadersOptions struct {
+	Enabled               bool
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+	ContentSecurityPolicy string
+	// ReportOnly sends the CSP as Content-Security-Policy-Report-Only so
+	// violations are reported to ReportURI without being blocked.
+	ReportOnly        bool
+	ReportURI         string
+	ReferrerPolicy    string
+	PermissionsPolicy string
+	FrameOptions      string
+}
+
+func (o HeadersOptions) hsts() string {
+	v := fmt.Sprintf("max-age=%d", int(o.HSTSMaxAge.Seconds()))
+	if o.HSTSIncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if o.HSTSPreload {
+		v += "; preload"
+	}
+	return v
+}
+
+func (o HeadersOptions) csp() string {
+	csp := strings.TrimSpace(o.ContentSecurityPolicy)
+	if o.ReportURI != "" {
+		csp = strings.TrimSuffix(csp, ";") + "; report-uri " + o.ReportURI
+	}
+	return csp
+}
+
+// Headers sets security headers from the live config before the handler
+// runs, so handlers can still override them.
+func Headers(options func() HeadersOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			opts := options()
+			if !opts.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			if opts.HSTSMaxAge > 0 {
+				h.Set("Strict-Transport-Security", opts.hsts())
+			}
+			if opts.ContentSecurityPolicy != "" {
+				if opts.ReportOnly {
+					h.Set("Content-Security-Policy-Report-Only", opts.csp())
+				} else {
+					h.Set("Content-Security-Policy", opts.csp())
+				}
+			}
+			if opts.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", opts.ReferrerPolicy)
+			}
+			if opts.PermissionsPolicy != "" {
+				h.Set("Permissions-Policy", opts.PermissionsPolicy)
+			}
+			if opts.FrameOptions != "" {
+				h.Set("X-Frame-Options", opts.FrameOptions)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// PagePolicy replaces the CSP set by Headers, in whichever mode (enforced or
+// report-only) is active, with one letting an HTML page load its assets
+// from assetSources and run the inline scripts carrying the returned nonce.
+// The default API policy blocks every asset.
+func PagePolicy(w http.ResponseWriter, assetSources string) (nonce string) {
+	b := make([]byte, 16)
+	rand.Read(b)
+	nonce = base64.StdEncoding.EncodeToString(b)
+
+	csp := fmt.Sprintf("default-src 'none'; script-src 'nonce-%s' %s; style-src 'unsafe-inline' %s; "+
+		"img-src 'self' data: %s; font-src %s; connect-src 'self'; worker-src blob:; frame-ancestors 'none'",
+		nonce, assetSources, assetSources, assetSources, assetSources)
+	for _, h := range []string{"Content-Security-Policy", "Content-Security-Policy-Report-Only"} {
+		if w.Header().Get(h) != "" {
+			w.Header().Set(h, csp)
+		}
+	}
+	return nonce
+}
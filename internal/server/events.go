@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+)
+
+// Subscribers returns the in-process subscribers the outbox relay delivers
+// events to. Subscribe before the relay starts to receive every event.
+func (s *Server) Subscribers() *events.Subscribers {
+	return s.subscribers
+}
+
+// ChangeFeed returns the feed streamed by /examples/stream. It only
+// receives changes once started.
+func (s *Server) ChangeFeed() *changefeed.Feed {
+	return s.changes
+}
+
+// NewOutboxRelay builds a relay delivering to the subscribers, the managed
+// webhooks and the sinks configured at startup, with the live outbox
+// settings.
+func (s *Server) NewOutboxRelay() (*events.Relay, error) {
+	sinks := []events.Sink{s.subscribers, s.webhooks}
+	if s.config.Outbox.Log {
+		sinks = append(sinks, events.LogSink{})
+	}
+	// The relay bounds each delivery with the delivery timeout
+	client := &http.Client{}
+	for _, url := range s.config.Outbox.Webhooks {
+		sink, err := events.NewWebhookSink(url, client)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return events.NewRelay(s.db.GetDB(), sinks, func() events.Options {
+		return s.runtime.Current().Outbox.Options()
+	}, s.metrics), nil
+}
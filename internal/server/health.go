@@ -0,0 +1,42 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/health"
+)
+
+func (s *Server) registerHealthChecks() {
+	s.health.Register(health.Check{
+		Name:     "database",
+		Probes:   health.Readiness | health.Startup,
+		Timeout:  time.Second,
+		CacheTTL: 2 * time.Second,
+		Func:     health.DatabaseCheck(s.db.GetDB()),
+	})
+
+	if s.config.MigrationsDir != "" {
+		s.health.Register(health.Check{
+			Name:     "migrations",
+			Probes:   health.Readiness | health.Startup,
+			Timeout:  2 * time.Second,
+			CacheTTL: 30 * time.Second,
+			Func:     health.MigrationCheck(s.db.GetDB(), s.config.MigrationsDir),
+		})
+	}
+
+	if s.config.HealthMinFreeDiskMB > 0 {
+		s.health.Register(health.Check{
+			Name:     "disk",
+			Probes:   health.Liveness | health.Readiness,
+			Timeout:  time.Second,
+			CacheTTL: 10 * time.Second,
+			Func:     health.DiskSpaceCheck(s.config.HealthDiskPath, s.config.HealthMinFreeDiskMB*1024*1024),
+		})
+	}
+}
+
+// Health exposes the registry so callers can add custom checks.
+func (s *Server) Health() *health.Registry {
+	return s.health
+}
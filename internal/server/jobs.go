@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+)
+
+// Jobs returns the client used to enqueue background jobs.
+func (s *Server) Jobs() *jobs.Client {
+	return s.jobs
+}
+
+// JobRegistry returns the registry of job handlers. Handlers must be
+// registered before the worker starts.
+func (s *Server) JobRegistry() *jobs.Registry {
+	return s.jobRegistry
+}
+
+// NewJobWorker builds a worker running the registered handlers with the
+// live jobs settings.
+func (s *Server) NewJobWorker() *jobs.Worker {
+	return jobs.NewWorker(s.db.GetDB(), s.jobRegistry, func() jobs.Options {
+		return s.runtime.Current().Jobs.Options()
+	}, s.metrics)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/handlers"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/internal/webui"
+	"github.com/go-chi/chi/v5"
+)
+
+// APIVersion is the version reported in the OpenAPI document.
+const APIVersion = "1.0.0"
+
+// systemRoutes documents the routes registered directly on the main router.
+func systemRoutes() []openapi.Route {
+	return []openapi.Route{
+		{Method: http.MethodGet, Path: "/", OperationID: "hello", Summary: "Hello world", ContentType: "text/plain"},
+		{Method: http.MethodGet, Path: "/health", OperationID: "health", Summary: "Database connection statistics",
+			Response: map[string]string{}, Errors: []int{http.StatusServiceUnavailable}},
+	}
+}
+
+func (s *Server) apiDocument(health *handlers.HealthHandler, resources []resource) *openapi.Document {
+	title := s.config.ServiceName
+	if title == "" {
+		title = "go-chi-postgres"
+	}
+	b := openapi.NewBuilder(openapi.Info{Title: title, Version: APIVersion}).
+		Mount("", "system", systemRoutes()).
+		Mount("/healthz", "health", health.Routes())
+	for _, res := range resources {
+		b.Mount(res.prefix, res.tag, res.handler.Routes())
+	}
+	return b.Document()
+}
+
+// mountAPIDocs serves the document at /openapi.json and, when enabled in the
+// live config, the Swagger UI and Redoc pages under /docs. The assets of
+// those pages and GraphiQL are served from the binary under /ui.
+func (s *Server) mountAPIDocs(r chi.Router, doc *openapi.Document) {
+	r.Get("/openapi.json", openapi.SpecHandler(doc))
+	r.Handle(webui.Prefix+"*", webui.Handler())
+
+	title := doc.Info.Title + " API"
+	r.Route("/docs", func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !s.runtime.Current().APIDocs {
+					http.NotFound(w, r)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		r.Get("/", openapi.SwaggerUIHandler(title, "/openapi.json"))
+		r.Get("/redoc", openapi.RedocHandler(title, "/openapi.json"))
+	})
+}
@@ -1,51 +1,158 @@
 package server
 
 import (
+	"crypto/subtle"
 	"net/http"
 	"strings"
 
 	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/graph"
 	"github.com/ctrixcode/go-chi-postgres/internal/handlers"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/internal/security"
 	"github.com/ctrixcode/go-chi-postgres/internal/services"
+	"github.com/ctrixcode/go-chi-postgres/internal/tracing"
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/ctrixcode/go-chi-postgres/pkg/request"
+	"github.com/ctrixcode/go-chi-postgres/pkg/requestid"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 )
 
+// resource is a handler mounted under its own prefix, with its routes tagged
+// in the OpenAPI document.
+type resource struct {
+	prefix  string
+	tag     string
+	handler interface {
+		RegisterRoutes() http.Handler
+		Routes() []openapi.Route
+	}
+}
+
 func (s *Server) RegisterRoutes() http.Handler {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(requestid.Middleware)
+	r.Use(tracing.Middleware)
+	r.Use(logger.AccessLog)
 	r.Use(middleware.Recoverer)
+	r.Use(s.metrics.Middleware)
 
-	allowedOrigins := []string{"https://*", "http://*"}
-	if s.config.Environment == "production" {
-		allowedOrigins = strings.Split(s.config.CorsAllowedOrigins, ",")
+	r.Use(security.Headers(func() security.HeadersOptions {
+		return s.runtime.Current().SecurityHeaders.Options()
+	}))
+	r.Use(s.cors.Handler)
+	// Known users are rate limited and their idempotency keys scoped by
+	// user; routes requiring authentication still reject the others
+	r.Use(s.auth.Identify)
+	r.Use(s.limiter.Middleware)
+	r.Use(request.LimitBody(func() request.LimitOptions {
+		return s.runtime.Current().Request.Options()
+	}))
+	r.Use(response.Compress(func() response.CompressOptions {
+		return s.runtime.Current().Response.CompressOptions()
+	}))
+	r.Use(s.idempotency.Handler)
+	r.Use(response.Negotiate(func() []string {
+		return s.runtime.Current().Response.Formats
+	}))
+
+	healthHandler := handlers.NewHealthHandler(s.health)
+	transactor := database.NewTransactor(s.db.GetDB())
+	outbox := events.NewOutbox(s.db.GetDB())
+	exampleRepo := metrics.InstrumentExampleRepository(database.NewExampleRepository(s.db.GetDB()), s.metrics)
+	exampleService := services.NewExampleService(exampleRepo, transactor, outbox, s.metrics)
+	resources := []resource{
+		{"/examples", "examples", handlers.NewExampleHandler(exampleService, s.changes, s.auth)},
+		{"/webhooks", "webhooks", handlers.NewWebhooksHandler(s.webhooks, s.auth)},
+		// cmd/tools/generate adds new resources above this line
 	}
 
-	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   allowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
+	// Validation runs closest to the handlers so it sees the request as they
+	// do and the response before it is compressed
+	doc := s.apiDocument(healthHandler, resources)
+	r.Use(openapi.NewValidator(doc, func() openapi.ValidatorOptions {
+		return s.runtime.Current().OpenAPI.Options()
+	}).Middleware)
 
 	r.Get("/", handlers.HelloWorldHandler)
 	r.Get("/health", s.healthHandler)
+	r.Mount("/healthz", healthHandler.RegisterRoutes())
+	for _, res := range resources {
+		r.Mount(res.prefix, res.handler.RegisterRoutes())
+	}
+	// GraphQL describes itself through introspection rather than the
+	// OpenAPI document
+	graphQL := graph.NewHandler(exampleService, func() graph.Options {
+		return s.runtime.Current().GraphQL.Options()
+	})
+	r.Handle("/graphql", graphQL)
+
+	s.mountAPIDocs(r, doc)
 
-	// Example Routes
-	exampleRepo := database.NewExampleRepository(s.db.GetDB())
-	exampleService := services.NewExampleService(exampleRepo)
-	exampleHandler := handlers.NewExampleHandler(exampleService)
+	if s.adminServer == nil {
+		s.mountAdminRoutes(r)
+	}
 
-	r.Mount("/examples", exampleHandler.RegisterRoutes())
+	return r
+}
 
+// RegisterAdminRoutes builds the router served on the admin port.
+func (s *Server) RegisterAdminRoutes() http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	s.mountAdminRoutes(r)
 	return r
 }
 
+func (s *Server) mountAdminRoutes(r chi.Router) {
+	// Metrics expose routes, pool stats and business counters, so they are
+	// guarded like the admin routes
+	r.With(s.requireAdminToken).Handle("/metrics", s.metrics.Handler())
+
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(s.requireAdminToken)
+		r.Get("/log-level", handlers.GetLogLevel)
+		r.Put("/log-level", handlers.SetLogLevel)
+		r.Post("/jobs/{id}/requeue", handlers.NewJobsHandler(s.jobs).RequeueDead)
+		r.Get("/schedules", handlers.NewSchedulesHandler(s.scheduler).List)
+	})
+}
+
+// requireAdminToken protects admin endpoints with a static bearer token.
+// Without ADMIN_TOKEN they are only served on a separate admin port, which is
+// expected to be unreachable from outside; on the main port they are hidden.
+func (s *Server) requireAdminToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminToken == "" {
+			if s.config.AdminPort == 0 {
+				response.JSONError(w, errors.NotFoundError(errors.ErrNotFound))
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) != 1 {
+			response.JSONError(w, errors.AuthenticationError(errors.ErrUnauthorized))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// healthHandler reports database statistics, with 503 while the database is
+// down so monitors checking only the status code notice.
 func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	// jsonResp, _ := json.Marshal(s.db.Health())
-	// _, _ = w.Write(jsonResp)
-	w.Write([]byte("health check"))
+	w.Header().Set("Cache-Control", "no-store")
+	stats := s.db.Health()
+	if stats["status"] == "down" {
+		response.JSONError(w, errors.ServiceUnavailableError(errors.ErrServiceUnavailable, stats))
+		return
+	}
+	response.JSONSuccess(w, stats, http.StatusOK)
 }
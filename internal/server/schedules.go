@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/scheduler"
+)
+
+// Scheduler returns the scheduler running the periodic tasks.
+func (s *Server) Scheduler() *scheduler.Scheduler {
+	return s.scheduler
+}
+
+// registerSchedules adds the periodic tasks this service runs.
+func (s *Server) registerSchedules() {
+	q := database.NewQueries(s.db.GetDB())
+
+	// The stores purge expired rows now and then as well, but only while
+	// they are being used
+	s.scheduler.Add(scheduler.Task{
+		Name:     "purge_idempotency_keys",
+		Schedule: "@every 1h",
+		Jitter:   time.Minute,
+		Run: func(ctx context.Context) error {
+			n, err := q.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+			if err == nil {
+				slog.Debug("purged expired idempotency keys", "count", n)
+			}
+			return err
+		},
+	})
+	s.scheduler.Add(scheduler.Task{
+		Name:     "purge_rate_limits",
+		Schedule: "@every 10m",
+		Jitter:   time.Minute,
+		Run: func(ctx context.Context) error {
+			n, err := q.DeleteExpiredRateLimits(ctx, time.Now())
+			if err == nil {
+				slog.Debug("purged expired rate limits", "count", n)
+			}
+			return err
+		},
+	})
+	s.scheduler.Add(scheduler.Task{
+		Name:     "purge_outbox",
+		Schedule: "@every 1h",
+		Jitter:   time.Minute,
+		Run: func(ctx context.Context) error {
+			retention := s.runtime.Current().Outbox.Retention
+			if retention <= 0 {
+				return nil
+			}
+			n, err := q.DeleteDispatchedOutboxEvents(ctx, time.Now().Add(-retention))
+			if err == nil {
+				slog.Debug("purged delivered outbox events", "count", n)
+			}
+			return err
+		},
+	})
+	s.scheduler.Add(scheduler.Task{
+		Name:     "purge_webhook_deliveries",
+		Schedule: "@every 1h",
+		Jitter:   time.Minute,
+		Run: func(ctx context.Context) error {
+			retention := s.runtime.Current().Webhooks.Retention
+			if retention <= 0 {
+				return nil
+			}
+			n, err := q.DeleteWebhookDeliveries(ctx, time.Now().Add(-retention))
+			if err == nil {
+				slog.Debug("purged webhook deliveries", "count", n)
+			}
+			return err
+		},
+	})
+	s.scheduler.Add(scheduler.Task{
+		Name:     "purge_example_changes",
+		Schedule: "@every 1h",
+		Jitter:   time.Minute,
+		Run: func(ctx context.Context) error {
+			retention := s.runtime.Current().Stream.Retention
+			if retention <= 0 {
+				return nil
+			}
+			n, err := q.DeleteExampleChanges(ctx, time.Now().Add(-retention))
+			if err == nil {
+				slog.Debug("purged example changes", "count", n)
+			}
+			return err
+		},
+	})
+}
@@ -1,27 +1,89 @@
 package server
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"github.com/ctrixcode/go-chi-postgres/internal/auth"
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
 	"github.com/ctrixcode/go-chi-postgres/internal/config"
 	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/health"
+	"github.com/ctrixcode/go-chi-postgres/internal/idempotency"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/internal/ratelimit"
+	"github.com/ctrixcode/go-chi-postgres/internal/scheduler"
+	"github.com/ctrixcode/go-chi-postgres/internal/security"
+	"github.com/ctrixcode/go-chi-postgres/internal/webhooks"
 )
 
 type Server struct {
-	port   int
-	db     database.Service
-	server *http.Server
-	config *config.Config
+	port        int
+	db          database.Service
+	server      *http.Server
+	adminServer *http.Server
+	config      *config.Config
+	runtime     *config.Manager
+	health      *health.Registry
+	metrics     *metrics.Metrics
+	cors        *security.CORS
+	limiter     *ratelimit.Limiter
+	idempotency *idempotency.Middleware
+	jobs        *jobs.Client
+	jobRegistry *jobs.Registry
+	scheduler   *scheduler.Scheduler
+	subscribers *events.Subscribers
+	webhooks    *webhooks.Service
+	changes     *changefeed.Feed
+	auth        *auth.Authenticator
 }
 
 func NewServer(cfg *config.Config, db database.Service) *Server {
 	s := &Server{
-		port:   cfg.Port,
-		db:     db,
-		config: cfg,
+		port:        cfg.Port,
+		db:          db,
+		config:      cfg,
+		runtime:     config.NewManager(cfg),
+		health:      health.NewRegistry(),
+		metrics:     metrics.New(),
+		jobs:        jobs.NewClient(db.GetDB()),
+		jobRegistry: jobs.NewRegistry(),
+		subscribers: events.NewSubscribers(),
 	}
+	s.registerHealthChecks()
+	s.webhooks = webhooks.NewService(db.GetDB(), s.jobs, func() webhooks.Options {
+		return s.runtime.Current().Webhooks.Options()
+	})
+	s.webhooks.Register(s.jobRegistry)
+	s.changes = changefeed.New(db.GetDB(), func(ctx context.Context, channel string, listening func(), fn func(string)) error {
+		return database.Listen(ctx, db.GetDB(), channel, listening, fn)
+	}, func() changefeed.Options {
+		cfg := s.runtime.Current()
+		opts := cfg.Stream.Options()
+		opts.Origins = cfg.CORS.AllowedOrigins
+		return opts
+	}, s.metrics)
+	s.auth = auth.NewAuthenticator(func() string {
+		return s.runtime.Current().JWTSecret
+	})
+	s.scheduler = scheduler.New(db.GetDB(), func() scheduler.Options {
+		return s.runtime.Current().Scheduler.Options()
+	}, s.metrics)
+	s.registerSchedules()
+	s.cors = security.NewCORS(cfg.CORS.Options())
+	s.runtime.Subscribe(func(_, next *config.Config) {
+		s.cors.Apply(next.CORS.Options())
+	})
+	s.limiter = s.newLimiter()
+	s.idempotency = idempotency.NewMiddleware(idempotency.NewPostgresStore(db.GetDB()), func() idempotency.Options {
+		return s.runtime.Current().Idempotency.Options()
+	})
+	s.metrics.RegisterDB(db.GetDB().DB, "postgres")
 
 	// Declare Server config
 	s.server = &http.Server{
@@ -32,10 +94,29 @@ func NewServer(cfg *config.Config, db database.Service) *Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Serve admin endpoints on a separate port when one is configured
+	if cfg.AdminPort != 0 {
+		s.adminServer = &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.AdminPort),
+			Handler:      s.RegisterAdminRoutes(),
+			IdleTimeout:  time.Minute,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+	}
+
 	return s
 }
 
 func (s *Server) Start() error {
+	if s.adminServer != nil {
+		go func() {
+			slog.Info("admin server starting", "addr", fmt.Sprintf("http://localhost%s", s.adminServer.Addr))
+			if err := s.adminServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("admin server failed", "error", err)
+			}
+		}()
+	}
 	return s.server.ListenAndServe()
 }
 
@@ -46,3 +127,31 @@ func (s *Server) Shutdown() error {
 func (s *Server) GetHTTPServer() *http.Server {
 	return s.server
 }
+
+// GetAdminServer returns the admin HTTP server, or nil when admin routes are
+// served on the main port.
+func (s *Server) GetAdminServer() *http.Server {
+	return s.adminServer
+}
+
+// Config returns the manager holding the live configuration. Handlers read
+// reloadable settings through it rather than the startup config.
+func (s *Server) Config() *config.Manager {
+	return s.runtime
+}
+
+func (s *Server) Metrics() *metrics.Metrics {
+	return s.metrics
+}
+
+// newLimiter picks the rate limit backend at startup; policies are read from
+// the live config on every request.
+func (s *Server) newLimiter() *ratelimit.Limiter {
+	store := ratelimit.NewMemoryStore()
+	if s.config.RateLimit.Backend == "postgres" {
+		store = ratelimit.NewPostgresStore(s.db.GetDB())
+	}
+	return ratelimit.NewLimiter(store, func() ratelimit.Options {
+		return s.runtime.Current().RateLimit.Options()
+	})
+}
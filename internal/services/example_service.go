@@ -2,48 +2,156 @@ package services
 
 import (
 	"context"
+	"strconv"
 
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
 	"github.com/ctrixcode/go-chi-postgres/internal/models"
 	"github.com/ctrixcode/go-chi-postgres/internal/repository"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var tracer = otel.Tracer("github.com/ctrixcode/go-chi-postgres/internal/services")
+
 type ExampleService interface {
 	Create(ctx context.Context, req models.CreateExampleRequest) (*models.Example, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Example, error)
 	List(ctx context.Context, limit, offset uint64) ([]models.Example, error)
+	Search(ctx context.Context, q models.ExampleQuery) ([]models.Example, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]models.Example, error)
 	Update(ctx context.Context, id uuid.UUID, req models.UpdateExampleRequest) (*models.Example, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 type exampleService struct {
-	repo repository.ExampleRepository
+	repo            repository.ExampleRepository
+	tx              repository.Transactor
+	events          events.Publisher
+	examplesCreated *prometheus.CounterVec
 }
 
-func NewExampleService(repo repository.ExampleRepository) ExampleService {
+// NewExampleService returns the service for examples. Every change publishes
+// an event to publisher in the same transaction, begun with tx.
+func NewExampleService(repo repository.ExampleRepository, tx repository.Transactor, publisher events.Publisher, m *metrics.Metrics) ExampleService {
 	return &exampleService{
-		repo: repo,
+		repo:   repo,
+		tx:     tx,
+		events: publisher,
+		// The premium ratio is derived from this counter's "premium" label
+		examplesCreated: m.Counter("examples_created_total", "Number of examples created.", "premium"),
 	}
 }
 
-func (s *exampleService) Create(ctx context.Context, req models.CreateExampleRequest) (*models.Example, error) {
+func (s *exampleService) Create(ctx context.Context, req models.CreateExampleRequest) (example *models.Example, err error) {
+	ctx, span := tracer.Start(ctx, "ExampleService.Create", trace.WithAttributes(attribute.Bool("example.is_premium", req.IsPremium)))
+	defer func() { endSpan(span, err) }()
+
 	// In a real application, you might have business logic here.
 	// For example, validating the request, calling other services, etc.
-	return s.repo.Create(ctx, req)
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		example, err = s.repo.Create(ctx, req)
+		if err != nil {
+			return err
+		}
+		return s.publish(ctx, models.EventExampleCreated, example.ID, example)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.examplesCreated.WithLabelValues(strconv.FormatBool(example.IsPremium)).Inc()
+	logger.FromContext(ctx).Info("example created", "example_id", example.ID, "is_premium", example.IsPremium)
+	return example, nil
 }
 
-func (s *exampleService) GetByID(ctx context.Context, id uuid.UUID) (*models.Example, error) {
+func (s *exampleService) GetByID(ctx context.Context, id uuid.UUID) (example *models.Example, err error) {
+	ctx, span := tracer.Start(ctx, "ExampleService.GetByID", trace.WithAttributes(attribute.String("example.id", id.String())))
+	defer func() { endSpan(span, err) }()
+
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *exampleService) List(ctx context.Context, limit, offset uint64) ([]models.Example, error) {
+func (s *exampleService) List(ctx context.Context, limit, offset uint64) (examples []models.Example, err error) {
+	ctx, span := tracer.Start(ctx, "ExampleService.List", trace.WithAttributes(
+		attribute.Int64("pagination.limit", int64(limit)),
+		attribute.Int64("pagination.offset", int64(offset)),
+	))
+	defer func() { endSpan(span, err) }()
+
 	return s.repo.List(ctx, limit, offset)
 }
 
-func (s *exampleService) Update(ctx context.Context, id uuid.UUID, req models.UpdateExampleRequest) (*models.Example, error) {
-	return s.repo.Update(ctx, id, req)
+func (s *exampleService) Search(ctx context.Context, q models.ExampleQuery) (examples []models.Example, err error) {
+	ctx, span := tracer.Start(ctx, "ExampleService.Search", trace.WithAttributes(
+		attribute.String("sort", q.Sort),
+		attribute.Int64("pagination.limit", int64(q.Limit)),
+		attribute.Int64("pagination.offset", int64(q.Offset)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.Search(ctx, q)
+}
+
+func (s *exampleService) GetByIDs(ctx context.Context, ids []uuid.UUID) (examples []models.Example, err error) {
+	ctx, span := tracer.Start(ctx, "ExampleService.GetByIDs", trace.WithAttributes(attribute.Int("example.count", len(ids))))
+	defer func() { endSpan(span, err) }()
+
+	return s.repo.GetByIDs(ctx, ids)
 }
 
-func (s *exampleService) Delete(ctx context.Context, id uuid.UUID) error {
-	return s.repo.Delete(ctx, id)
+func (s *exampleService) Update(ctx context.Context, id uuid.UUID, req models.UpdateExampleRequest) (example *models.Example, err error) {
+	ctx, span := tracer.Start(ctx, "ExampleService.Update", trace.WithAttributes(attribute.String("example.id", id.String())))
+	defer func() { endSpan(span, err) }()
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		example, err = s.repo.Update(ctx, id, req)
+		if err != nil {
+			return err
+		}
+		return s.publish(ctx, models.EventExampleUpdated, id, example)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return example, nil
+}
+
+func (s *exampleService) Delete(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "ExampleService.Delete", trace.WithAttributes(attribute.String("example.id", id.String())))
+	defer func() { endSpan(span, err) }()
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Delete(ctx, id); err != nil {
+			return err
+		}
+		return s.publish(ctx, models.EventExampleDeleted, id, map[string]uuid.UUID{"id": id})
+	})
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx).Info("example deleted", "example_id", id)
+	return nil
+}
+
+func (s *exampleService) publish(ctx context.Context, eventType string, id uuid.UUID, payload interface{}) error {
+	event, err := events.New(eventType, models.ExampleAggregate, id.String(), payload)
+	if err != nil {
+		return err
+	}
+	return s.events.Publish(ctx, event)
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
 }
@@ -0,0 +1,552 @@
+package sqlgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	gotoken "go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Header starts every generated file. Check treats Go files in the output
+// directory that start with it as owned by the generator.
+const Header = "// Code generated by cmd/tools/sqlgen. DO NOT EDIT."
+
+// Config locates the inputs and output, relative to the repository root.
+type Config struct {
+	Migrations string
+	Queries    string
+	Out        string
+	Package    string
+}
+
+// DefaultConfig is the layout of this repository.
+var DefaultConfig = Config{
+	Migrations: "database/migrations",
+	Queries:    "database/queries",
+	Out:        "internal/database/queries",
+	Package:    "queries",
+}
+
+// File is a generated file, with Path relative to the repository root.
+type File struct {
+	Path    string
+	Content []byte
+}
+
+// Render generates the query package for the queries under root.
+func Render(root string, cfg Config) ([]File, error) {
+	schema, err := LoadSchema(filepath.Join(root, cfg.Migrations))
+	if err != nil {
+		return nil, err
+	}
+
+	sources, err := filepath.Glob(filepath.Join(root, cfg.Queries, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(sources)
+
+	g := &generator{pkg: cfg.Package}
+	var files []File
+	names := make(map[string]string)
+	referenced := make(map[*Table]bool)
+	for _, src := range sources {
+		queries, err := ParseQueries(src, schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range queries {
+			if file, ok := names[q.Name]; ok {
+				return nil, fmt.Errorf("%s: query %s is already defined in %s", q.File, q.Name, file)
+			}
+			names[q.Name] = q.File
+			for _, t := range q.Tables {
+				referenced[t] = true
+			}
+		}
+
+		content, err := g.queryFile(queries)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(src), err)
+		}
+		files = append(files, File{Path: filepath.Join(cfg.Out, filepath.Base(src)+".go"), Content: content})
+	}
+
+	db, err := g.render("db", nil)
+	if err != nil {
+		return nil, err
+	}
+	// Only tables some query uses get a model, so migrations for tables
+	// accessed by hand leave the package alone
+	var tables []*Table
+	for _, t := range schema.Tables() {
+		if referenced[t] {
+			tables = append(tables, t)
+		}
+	}
+	models, err := g.render("models", tables)
+	if err != nil {
+		return nil, err
+	}
+	files = append([]File{
+		{Path: filepath.Join(cfg.Out, "db.go"), Content: db},
+		{Path: filepath.Join(cfg.Out, "models.go"), Content: models},
+	}, files...)
+	return files, nil
+}
+
+// Write renders the package under root and replaces the generated files,
+// removing ones whose query file is gone. It returns the paths written.
+func Write(root string, cfg Config) ([]string, error) {
+	files, err := Render(root, cfg)
+	if err != nil {
+		return nil, err
+	}
+	stale, err := staleFiles(root, cfg, files)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range stale {
+		if err := os.Remove(filepath.Join(root, path)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, cfg.Out), 0o755); err != nil {
+		return nil, err
+	}
+	var written []string
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(root, f.Path), f.Content, 0o644); err != nil {
+			return written, err
+		}
+		written = append(written, f.Path)
+	}
+	return written, nil
+}
+
+// Check reports the generated files that are missing, differ from what the
+// current migrations and queries produce, or no longer have a query file.
+func Check(root string, cfg Config) ([]string, error) {
+	files, err := Render(root, cfg)
+	if err != nil {
+		return nil, err
+	}
+	outdated, err := staleFiles(root, cfg, files)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		current, err := os.ReadFile(filepath.Join(root, f.Path))
+		if err != nil || !bytes.Equal(current, f.Content) {
+			outdated = append(outdated, f.Path)
+		}
+	}
+	sort.Strings(outdated)
+	return outdated, nil
+}
+
+// staleFiles lists generated files in the output directory that files no
+// longer includes.
+func staleFiles(root string, cfg Config, files []File) ([]string, error) {
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		keep[f.Path] = true
+	}
+	existing, err := filepath.Glob(filepath.Join(root, cfg.Out, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var stale []string
+	for _, path := range existing {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if keep[rel] {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.HasPrefix(content, []byte(Header)) {
+			stale = append(stale, rel)
+		}
+	}
+	return stale, nil
+}
+
+type generator struct {
+	pkg string
+}
+
+// queryFile renders the methods for the queries of one .sql file.
+func (g *generator) queryFile(queries []*Query) ([]byte, error) {
+	data := struct {
+		Imports []string
+		Queries []*Query
+	}{Queries: queries}
+
+	pkgs := map[string]bool{"context": true}
+	for _, q := range queries {
+		for _, p := range q.Params {
+			for _, pkg := range imports(p.GoType) {
+				pkgs[pkg] = true
+			}
+		}
+		if q.Table == nil {
+			for _, r := range q.Results {
+				for _, pkg := range imports(r.GoType) {
+					pkgs[pkg] = true
+				}
+			}
+		}
+	}
+	for pkg := range pkgs {
+		data.Imports = append(data.Imports, pkg)
+	}
+	sort.Strings(data.Imports)
+	return g.render("queries", data)
+}
+
+func (g *generator) render(name string, data interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString(Header + "\n\npackage " + g.pkg + "\n")
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+	out, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated %s code: %w", name, err)
+	}
+	return out, nil
+}
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"goName":       goName,
+	"modelName":    modelName,
+	"lowerFirst":   func(s string) string { return strings.ToLower(s[:1]) + s[1:] },
+	"modelImports": modelImports,
+	"importDecl":   importDecl,
+	"resultType":   resultType,
+	"scanTargets":  scanTargets,
+	"paramList":    paramList,
+	"argList":      argList,
+	"backquote":    func(s string) string { return "`" + s + "`" },
+}).Parse(`
+{{define "db"}}
+import (
+	"context"
+	"database/sql"
+)
+
+// DBTX is satisfied by *sql.DB, *sql.Tx, *sqlx.DB and *sqlx.Tx.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Conn runs the statements of the queries. Wrappers implement it rather than
+// DBTX to see the rows as they are read, e.g. to trace reading them.
+type Conn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) Row
+}
+
+// Row is a row being read, as *sql.Row.
+type Row interface {
+	Scan(dest ...interface{}) error
+}
+
+// Rows are rows being read, as *sql.Rows.
+type Rows interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Close() error
+	Err() error
+}
+
+// Queries runs the queries in database/queries.
+type Queries struct {
+	db Conn
+}
+
+func New(db DBTX) *Queries {
+	return &Queries{db: conn{db: db}}
+}
+
+// NewConn returns queries running on c.
+func NewConn(c Conn) *Queries {
+	return &Queries{db: c}
+}
+
+// WithTx returns a copy of q running its queries on tx.
+func (q *Queries) WithTx(tx DBTX) *Queries {
+	return New(tx)
+}
+
+// conn runs the statements on a DBTX.
+type conn struct {
+	db DBTX
+}
+
+func (c conn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+func (c conn) QueryContext(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	rows, err := c.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+func (c conn) QueryRowContext(ctx context.Context, query string, args ...interface{}) Row {
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+{{end}}
+
+{{define "models"}}
+{{importDecl (modelImports .)}}
+{{range .}}
+// {{modelName .}} is a row of the {{.Name}} table.
+type {{modelName .}} struct {
+{{- range .Columns}}
+	{{goName .Name}} {{.GoType}} ` + "`db:\"{{.Name}}\" json:\"{{.Name}}\"`" + `
+{{- end}}
+}
+{{end}}
+{{end}}
+
+{{define "queries"}}
+{{importDecl .Imports}}
+{{range .Queries}}
+const {{lowerFirst .Name}} = {{backquote .SQL}}
+{{if gt (len .Params) 1}}
+type {{.Name}}Params struct {
+{{- range .Params}}
+	{{.Field}} {{.GoType}}
+{{- end}}
+}
+{{end}}
+{{- if and (not .Table) (gt (len .Results) 1)}}
+type {{.Name}}Row struct {
+{{- range .Results}}
+	{{goName .Name}} {{.GoType}} ` + "`db:\"{{.Name}}\" json:\"{{.Name}}\"`" + `
+{{- end}}
+}
+{{end}}
+{{- range .Doc}}
+// {{.}}
+{{- end}}
+{{- if eq .Cmd ":one"}}
+func (q *Queries) {{.Name}}(ctx context.Context{{paramList .}}) ({{resultType .}}, error) {
+	row := q.db.QueryRowContext(ctx, {{lowerFirst .Name}}{{argList .}})
+	var i {{resultType .}}
+	err := row.Scan({{scanTargets .}})
+	return i, err
+}
+{{else if eq .Cmd ":many"}}
+func (q *Queries) {{.Name}}(ctx context.Context{{paramList .}}) ([]{{resultType .}}, error) {
+	rows, err := q.db.QueryContext(ctx, {{lowerFirst .Name}}{{argList .}})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []{{resultType .}}{}
+	for rows.Next() {
+		var i {{resultType .}}
+		if err := rows.Scan({{scanTargets .}}); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+{{else if eq .Cmd ":exec"}}
+func (q *Queries) {{.Name}}(ctx context.Context{{paramList .}}) error {
+	_, err := q.db.ExecContext(ctx, {{lowerFirst .Name}}{{argList .}})
+	return err
+}
+{{else}}
+func (q *Queries) {{.Name}}(ctx context.Context{{paramList .}}) (int64, error) {
+	result, err := q.db.ExecContext(ctx, {{lowerFirst .Name}}{{argList .}})
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+{{end}}
+{{- end}}
+{{end}}
+`))
+
+// GoType is the Go type of the column in its table model.
+func (c *Column) GoType() string {
+	return goType(c.Type, c.NotNull, c.Array)
+}
+
+func modelImports(tables []*Table) []string {
+	pkgs := make(map[string]bool)
+	for _, t := range tables {
+		for _, c := range t.Columns {
+			for _, pkg := range imports(c.GoType()) {
+				pkgs[pkg] = true
+			}
+		}
+	}
+	list := make([]string, 0, len(pkgs))
+	for pkg := range pkgs {
+		list = append(list, pkg)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// importDecl groups the standard library imports before the others, as
+// goimports does.
+func importDecl(pkgs []string) string {
+	if len(pkgs) == 0 {
+		return ""
+	}
+	var std, other []string
+	for _, pkg := range pkgs {
+		if strings.Contains(strings.Split(pkg, "/")[0], ".") {
+			other = append(other, pkg)
+		} else {
+			std = append(std, pkg)
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("import (\n")
+	for _, pkg := range std {
+		b.WriteString("\t\"" + pkg + "\"\n")
+	}
+	if len(std) > 0 && len(other) > 0 {
+		b.WriteString("\n")
+	}
+	for _, pkg := range other {
+		b.WriteString("\t\"" + pkg + "\"\n")
+	}
+	b.WriteString(")\n")
+	return b.String()
+}
+
+func resultType(q *Query) string {
+	switch {
+	case q.Table != nil:
+		return modelName(q.Table)
+	case len(q.Results) == 1:
+		return q.Results[0].GoType
+	}
+	return q.Name + "Row"
+}
+
+func scanTargets(q *Query) string {
+	if q.Table == nil && len(q.Results) == 1 {
+		return "&i"
+	}
+	targets := make([]string, len(q.Results))
+	for i, r := range q.Results {
+		targets[i] = "&i." + goName(r.Name)
+	}
+	return strings.Join(targets, ", ")
+}
+
+func paramList(q *Query) string {
+	switch len(q.Params) {
+	case 0:
+		return ""
+	case 1:
+		return ", " + q.Params[0].Name + " " + q.Params[0].GoType
+	}
+	return ", arg " + q.Name + "Params"
+}
+
+func argList(q *Query) string {
+	if len(q.Params) == 1 {
+		return ", " + q.Params[0].Name
+	}
+	var args strings.Builder
+	for _, p := range q.Params {
+		args.WriteString(", arg." + p.Field)
+	}
+	return args.String()
+}
+
+// initialisms are written in upper case in Go names.
+var initialisms = map[string]bool{
+	"id": true, "uuid": true, "url": true, "uri": true, "api": true, "http": true,
+	"json": true, "sql": true, "ip": true, "ttl": true, "html": true, "xml": true,
+}
+
+// goName turns snake_case or lowerCamel into an exported Go name.
+func goName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(s, "_") {
+		if part == "" {
+			continue
+		}
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+// lowerCamel turns a column name into a parameter name.
+func lowerCamel(s string) string {
+	name := goName(s)
+	if name == "" {
+		return "arg"
+	}
+	// Lower the leading word, including a whole initialism: ID -> id
+	n := 1
+	for n < len(name) && isUpper(name[n]) && (n+1 == len(name) || isUpper(name[n+1])) {
+		n++
+	}
+	name = strings.ToLower(name[:n]) + name[n:]
+	if gotoken.IsKeyword(name) || name == "ctx" || name == "q" || name == "arg" {
+		name += "Value"
+	}
+	return name
+}
+
+func isUpper(c byte) bool { return c >= 'A' && c <= 'Z' }
+
+// modelName is the singular Go name of a table: idempotency_keys ->
+// IdempotencyKey.
+func modelName(t *Table) string {
+	return goName(singular(t.Name))
+}
+
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies"):
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "xes"),
+		strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "shes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "ss"):
+		return name
+	case strings.HasSuffix(name, "s"):
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
@@ -0,0 +1,207 @@
+// Package sqlgen generates typed Go methods from annotated SQL query files,
+// taking column types from the goose migrations.
+package sqlgen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokString
+	tokNumber
+	tokParam
+	tokOp
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	// text is lower case for unquoted identifiers
+	text  string
+	start int
+	end   int
+	depth int
+}
+
+func (t token) is(kind tokenKind, text string) bool {
+	return t.kind == kind && t.text == text
+}
+
+func (t token) keyword(words ...string) bool {
+	if t.kind != tokIdent {
+		return false
+	}
+	for _, w := range words {
+		if t.text == w {
+			return true
+		}
+	}
+	return false
+}
+
+// lex splits SQL into tokens, dropping comments and whitespace. Each token
+// records its parenthesis depth.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	depth := 0
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		start := i
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "--"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment at offset %d", i)
+			}
+			i += end + 4
+			continue
+		case c == '\'':
+			i++
+			for i < len(src) {
+				if src[i] == '\'' {
+					if i+1 < len(src) && src[i+1] == '\'' {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("unterminated string at offset %d", start)
+			}
+			i++
+			tokens = append(tokens, token{kind: tokString, text: src[start:i], start: start, end: i, depth: depth})
+			continue
+		case c == '"':
+			end := strings.IndexByte(src[i+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated identifier at offset %d", start)
+			}
+			i += end + 2
+			tokens = append(tokens, token{kind: tokIdent, text: src[start+1 : i-1], start: start, end: i, depth: depth})
+			continue
+		case c == '$' && i+1 < len(src) && isDigit(src[i+1]):
+			i++
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokParam, text: src[start:i], start: start, end: i, depth: depth})
+			continue
+		case c == '$':
+			// Dollar quoted body, e.g. $$ ... $$ or $fn$ ... $fn$
+			tagEnd := strings.IndexByte(src[i+1:], '$')
+			if tagEnd < 0 {
+				return nil, fmt.Errorf("unterminated dollar quote at offset %d", start)
+			}
+			tag := src[i : i+tagEnd+2]
+			end := strings.Index(src[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar quote at offset %d", start)
+			}
+			i += len(tag) + end + len(tag)
+			tokens = append(tokens, token{kind: tokString, text: src[start:i], start: start, end: i, depth: depth})
+			continue
+		case c == '@' && i+1 < len(src) && isIdentStart(src[i+1]):
+			// Named parameter, numbered by the generator
+			i++
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokParam, text: strings.ToLower(src[start:i]), start: start, end: i, depth: depth})
+			continue
+		case isIdentStart(c):
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: strings.ToLower(src[start:i]), start: start, end: i, depth: depth})
+			continue
+		case isDigit(c):
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], start: start, end: i, depth: depth})
+			continue
+		case c == '(':
+			tokens = append(tokens, token{kind: tokPunct, text: "(", start: i, end: i + 1, depth: depth})
+			depth++
+			i++
+			continue
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced ) at offset %d", i)
+			}
+			tokens = append(tokens, token{kind: tokPunct, text: ")", start: i, end: i + 1, depth: depth})
+			i++
+			continue
+		case c == ',' || c == ';' || c == '.' || c == '[' || c == ']':
+			tokens = append(tokens, token{kind: tokPunct, text: string(c), start: i, end: i + 1, depth: depth})
+			i++
+			continue
+		case strings.HasPrefix(src[i:], "::"):
+			tokens = append(tokens, token{kind: tokOp, text: "::", start: i, end: i + 2, depth: depth})
+			i += 2
+			continue
+		}
+
+		// Operators are runs of operator characters
+		for i < len(src) && strings.IndexByte("+-*/<>=~!@#%^&|`?:", src[i]) >= 0 {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+		tokens = append(tokens, token{kind: tokOp, text: src[start:i], start: start, end: i, depth: depth})
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced (")
+	}
+	return tokens, nil
+}
+
+// splitTop splits tokens on sep at the depth of the first token.
+func splitTop(tokens []token, sep string) [][]token {
+	if len(tokens) == 0 {
+		return nil
+	}
+	depth := tokens[0].depth
+	var parts [][]token
+	last := 0
+	for i, t := range tokens {
+		if t.depth == depth && t.is(tokPunct, sep) {
+			parts = append(parts, tokens[last:i])
+			last = i + 1
+		}
+	}
+	return append(parts, tokens[last:])
+}
+
+// inner returns the tokens inside the parentheses opening at tokens[i].
+func inner(tokens []token, i int) ([]token, int) {
+	depth := tokens[i].depth
+	for j := i + 1; j < len(tokens); j++ {
+		if tokens[j].depth == depth && tokens[j].is(tokPunct, ")") {
+			return tokens[i+1 : j], j
+		}
+	}
+	return tokens[i+1:], len(tokens)
+}
+
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isIdentPart(c byte) bool  { return isIdentStart(c) || isDigit(c) || c == '$' }
@@ -0,0 +1,872 @@
+package sqlgen
+
+import (
+	"fmt"
+	gotoken "go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query commands, given after the query name.
+const (
+	CmdOne      = ":one"
+	CmdMany     = ":many"
+	CmdExec     = ":exec"
+	CmdExecRows = ":execrows"
+)
+
+// Query is an annotated query with its parameter and result types
+// resolved against the schema.
+type Query struct {
+	Name string
+	Cmd  string
+	Doc  []string
+	// SQL is the statement sent to the database, with * expanded
+	SQL     string
+	Params  []Param
+	Results []Result
+	// Table is set when the results are exactly the columns of a table, in
+	// order, so the table model is returned.
+	Table *Table
+	// Tables are the tables the query reads or writes
+	Tables []*Table
+	File   string
+	Line   int
+}
+
+type Param struct {
+	// Name is the Go parameter name, Field the name in the XParams struct;
+	// they differ for names like type that are Go keywords.
+	Name   string
+	Field  string
+	GoType string
+}
+
+type Result struct {
+	Name   string
+	GoType string
+	column *Column
+	table  *Table
+}
+
+var nameLine = regexp.MustCompile(`^--\s*name:\s*(\S+)\s+(\S+)\s*$`)
+
+// ParseQueries reads the annotated queries in a .sql file:
+//
+//	-- name: GetExample :one
+//	-- GetExample returns the example with the given id.
+//	SELECT * FROM examples WHERE id = $1;
+//
+// Comment lines right after the name line become the method's doc comment.
+// Parameters are numbered ($1) or named (@id), which also names the Go
+// parameter. Their types come from the column they are compared with or
+// stored in; values stored in a nullable column are sql.Null unless the
+// parameter has a cast, which pins its type.
+func ParseQueries(path string, schema *Schema) ([]*Query, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Base(path)
+
+	var (
+		queries []*Query
+		current *Query
+		sqlText strings.Builder
+	)
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		current.SQL = strings.TrimRight(strings.TrimSpace(sqlText.String()), ";")
+		if err := current.analyze(schema); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", file, current.Line, current.Name, err)
+		}
+		queries = append(queries, current)
+		sqlText.Reset()
+		return nil
+	}
+
+	for n, line := range strings.Split(string(src), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := nameLine.FindStringSubmatch(trimmed); m != nil {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			current = &Query{Name: m[1], Cmd: m[2], File: file, Line: n + 1}
+			if err := current.validateName(); err != nil {
+				return nil, fmt.Errorf("%s:%d: %w", file, n+1, err)
+			}
+			continue
+		}
+		if current == nil {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+				return nil, fmt.Errorf("%s:%d: SQL before the first \"-- name:\" annotation", file, n+1)
+			}
+			continue
+		}
+		if sqlText.Len() == 0 && strings.HasPrefix(trimmed, "--") {
+			current.Doc = append(current.Doc, strings.TrimSpace(strings.TrimPrefix(trimmed, "--")))
+			continue
+		}
+		if sqlText.Len() == 0 && trimmed == "" {
+			continue
+		}
+		sqlText.WriteString(strings.TrimRight(line, " \t\r") + "\n")
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return queries, nil
+}
+
+func (q *Query) validateName() error {
+	if !gotoken.IsExported(q.Name) || !gotoken.IsIdentifier(q.Name) {
+		return fmt.Errorf("query name %q must be an exported Go identifier", q.Name)
+	}
+	switch q.Cmd {
+	case CmdOne, CmdMany, CmdExec, CmdExecRows:
+		return nil
+	}
+	return fmt.Errorf("%s: unknown command %q, want :one, :many, :exec or :execrows", q.Name, q.Cmd)
+}
+
+// scopedTable is a table referenced by a query, under its alias.
+type scopedTable struct {
+	table *Table
+	alias string
+	depth int
+	// nullable is set for the outer side of a LEFT or FULL JOIN
+	nullable bool
+}
+
+type analysis struct {
+	schema *Schema
+	text   string
+	tokens []token
+	tables []*scopedTable
+	// rewrites replace token ranges in the SQL, for * expansion
+	rewrites []rewrite
+}
+
+type rewrite struct {
+	start, end int
+	text       string
+}
+
+func (q *Query) analyze(schema *Schema) error {
+	if q.SQL == "" {
+		return fmt.Errorf("no SQL")
+	}
+	tokens, err := lex(q.SQL)
+	if err != nil {
+		return err
+	}
+	for i, t := range tokens {
+		if t.depth == 0 && t.is(tokPunct, ";") && i != len(tokens)-1 {
+			return fmt.Errorf("a query must be a single statement")
+		}
+	}
+	if tokens[0].keyword("with") {
+		return fmt.Errorf("WITH queries are not supported")
+	}
+	if !tokens[0].keyword("select", "insert", "update", "delete") {
+		return fmt.Errorf("unsupported statement %s", strings.ToUpper(tokens[0].text))
+	}
+
+	a := &analysis{schema: schema, text: q.SQL, tokens: tokens}
+	if err := a.findTables(); err != nil {
+		return err
+	}
+	for _, st := range a.tables {
+		if !slices.Contains(q.Tables, st.table) {
+			q.Tables = append(q.Tables, st.table)
+		}
+	}
+	if err := q.results(a); err != nil {
+		return err
+	}
+	if err := q.params(a); err != nil {
+		return err
+	}
+
+	sort.Slice(a.rewrites, func(i, j int) bool { return a.rewrites[i].start > a.rewrites[j].start })
+	for _, r := range a.rewrites {
+		q.SQL = q.SQL[:r.start] + r.text + q.SQL[r.end:]
+	}
+	return nil
+}
+
+// reserved words that end a table reference rather than alias it.
+var reserved = map[string]bool{
+	"where": true, "join": true, "left": true, "right": true, "inner": true, "outer": true,
+	"full": true, "cross": true, "natural": true, "on": true, "using": true, "group": true,
+	"order": true, "limit": true, "offset": true, "returning": true, "set": true, "values": true,
+	"as": true, "for": true, "union": true, "intersect": true, "except": true, "having": true,
+	"window": true, "default": true, "select": true, "lateral": true, "tablesample": true,
+}
+
+func (a *analysis) findTables() error {
+	tokens := a.tokens
+	for i, t := range tokens {
+		if !t.keyword("from", "join", "into", "update") || i+1 >= len(tokens) {
+			continue
+		}
+		if t.keyword("from") && i > 0 && tokens[i-1].keyword("distinct") {
+			// IS DISTINCT FROM compares values
+			continue
+		}
+		if t.keyword("update") && i > 0 {
+			// ON CONFLICT ... DO UPDATE and FOR UPDATE
+			continue
+		}
+
+		rest := skipWords(tokens[i+1:], "only")
+		name, n := qualifiedName(rest)
+		if name == "" || (!t.keyword("into") && n < len(rest) && rest[n].is(tokPunct, "(")) {
+			// A subquery or a function such as generate_series
+			continue
+		}
+		table := a.schema.Table(name)
+		if table == nil {
+			if t.depth == 0 {
+				return fmt.Errorf("unknown table %s", name)
+			}
+			continue
+		}
+
+		st := &scopedTable{table: table, alias: name, depth: t.depth}
+		if n < len(rest) && rest[n].keyword("as") && n+1 < len(rest) {
+			st.alias = rest[n+1].text
+		} else if n < len(rest) && rest[n].kind == tokIdent && !reserved[rest[n].text] {
+			st.alias = rest[n].text
+		}
+
+		if t.keyword("join") {
+			join := keywords(tokens[max(0, i-2):i])
+			switch {
+			case containsWord(join, "left"), containsWord(join, "full"):
+				st.nullable = true
+			case containsWord(join, "right"):
+				for _, prev := range a.tables {
+					if prev.depth == t.depth {
+						prev.nullable = true
+					}
+				}
+			}
+			if containsWord(join, "full") {
+				for _, prev := range a.tables {
+					if prev.depth == t.depth {
+						prev.nullable = true
+					}
+				}
+			}
+		}
+		a.tables = append(a.tables, st)
+	}
+	if len(a.tables) == 0 && !tokens[0].keyword("select") {
+		return fmt.Errorf("no table found")
+	}
+	return nil
+}
+
+// column resolves a possibly qualified column reference made at depth.
+func (a *analysis) column(qualifier, name string, depth int) (*scopedTable, *Column, error) {
+	var matches []*scopedTable
+	for _, st := range a.tables {
+		if qualifier != "" && st.alias != qualifier && st.table.Name != qualifier {
+			continue
+		}
+		if st.table.Column(name) != nil {
+			matches = append(matches, st)
+		}
+	}
+	if len(matches) > 1 {
+		// Prefer the innermost table visible from depth
+		var best []*scopedTable
+		for d := depth; d >= 0 && len(best) == 0; d-- {
+			for _, st := range matches {
+				if st.depth == d {
+					best = append(best, st)
+				}
+			}
+		}
+		matches = best
+	}
+	switch len(matches) {
+	case 0:
+		if qualifier != "" {
+			return nil, nil, fmt.Errorf("unknown column %s.%s", qualifier, name)
+		}
+		return nil, nil, fmt.Errorf("unknown column %s", name)
+	case 1:
+		return matches[0], matches[0].table.Column(name), nil
+	}
+	return nil, nil, fmt.Errorf("column reference %s is ambiguous", name)
+}
+
+// columnRef reads a column reference, optionally qualified, that is all of
+// tokens.
+func columnRef(tokens []token) (qualifier, name string, ok bool) {
+	switch {
+	case len(tokens) == 1 && tokens[0].kind == tokIdent && !reserved[tokens[0].text]:
+		return "", tokens[0].text, true
+	case len(tokens) == 3 && tokens[0].kind == tokIdent && tokens[1].is(tokPunct, ".") && tokens[2].kind == tokIdent:
+		return tokens[0].text, tokens[2].text, true
+	}
+	return "", "", false
+}
+
+func (q *Query) results(a *analysis) error {
+	tokens := a.tokens
+	var list []token
+	if tokens[0].keyword("select") {
+		start := 1
+		for start < len(tokens) && tokens[start].keyword("distinct", "all") {
+			start++
+		}
+		if start < len(tokens) && tokens[start].keyword("on") && start+1 < len(tokens) {
+			_, end := inner(tokens, start+1)
+			start = end + 1
+		}
+		end := start
+		for end < len(tokens) && !(tokens[end].depth == 0 && tokens[end].keyword("from", "where", "group", "order", "limit", "offset", "union", "intersect", "except", "having", "window", "for")) {
+			end++
+		}
+		list = tokens[start:end]
+	} else {
+		for i, t := range tokens {
+			if t.depth == 0 && t.keyword("returning") {
+				list = tokens[i+1:]
+				break
+			}
+		}
+	}
+	if n := len(list); n > 0 && list[n-1].is(tokPunct, ";") {
+		list = list[:n-1]
+	}
+
+	switch q.Cmd {
+	case CmdOne, CmdMany:
+		if len(list) == 0 {
+			return fmt.Errorf("%s needs a query returning columns", q.Cmd)
+		}
+	default:
+		if len(list) > 0 {
+			return fmt.Errorf("%s discards the returned columns, use :one or :many", q.Cmd)
+		}
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, item := range splitTop(list, ",") {
+		results, err := a.resultItem(item)
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			if seen[r.Name] {
+				return fmt.Errorf("column %s is returned twice, give one an alias", r.Name)
+			}
+			seen[r.Name] = true
+			q.Results = append(q.Results, r)
+		}
+	}
+
+	// Return the table model when the columns are exactly the table's
+	if len(q.Results) > 1 {
+		table := q.Results[0].table
+		if table != nil && len(table.Columns) == len(q.Results) {
+			for i, r := range q.Results {
+				c := table.Columns[i]
+				if r.table != table || r.column != c || r.Name != c.Name || r.GoType != goType(c.Type, c.NotNull, c.Array) {
+					table = nil
+					break
+				}
+			}
+			q.Table = table
+		}
+	}
+	return nil
+}
+
+func (a *analysis) resultItem(item []token) ([]Result, error) {
+	if len(item) == 0 {
+		return nil, fmt.Errorf("empty result column")
+	}
+	// Star and table.* expand to the table columns
+	if len(item) == 1 && item[0].is(tokOp, "*") {
+		var results []Result
+		var names []string
+		qualify := 0
+		for _, st := range a.tables {
+			if st.depth == 0 {
+				qualify++
+			}
+		}
+		for _, st := range a.tables {
+			if st.depth != 0 {
+				continue
+			}
+			for _, c := range st.table.Columns {
+				results = append(results, a.columnResult(st, c, c.Name))
+				if qualify > 1 {
+					names = append(names, st.alias+"."+c.Name)
+				} else {
+					names = append(names, c.Name)
+				}
+			}
+		}
+		if len(results) == 0 {
+			return nil, fmt.Errorf("* needs a table")
+		}
+		a.rewrites = append(a.rewrites, rewrite{item[0].start, item[0].end, strings.Join(names, ", ")})
+		return results, nil
+	}
+	if len(item) == 3 && item[0].kind == tokIdent && item[1].is(tokPunct, ".") && item[2].is(tokOp, "*") {
+		for _, st := range a.tables {
+			if st.depth == 0 && (st.alias == item[0].text || st.table.Name == item[0].text) {
+				var results []Result
+				var names []string
+				for _, c := range st.table.Columns {
+					results = append(results, a.columnResult(st, c, c.Name))
+					names = append(names, item[0].text+"."+c.Name)
+				}
+				a.rewrites = append(a.rewrites, rewrite{item[0].start, item[2].end, strings.Join(names, ", ")})
+				return results, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown table %s", item[0].text)
+	}
+
+	expr, alias := item, ""
+	if n := len(expr); n >= 3 && expr[n-2].keyword("as") {
+		expr, alias = expr[:n-2], expr[n-1].text
+	} else if n >= 2 && expr[n-1].kind == tokIdent && !reserved[expr[n-1].text] && expr[n-2].is(tokPunct, ")") {
+		expr, alias = expr[:n-1], expr[n-1].text
+	}
+
+	v, err := a.infer(expr)
+	if err != nil {
+		return nil, err
+	}
+	if v.typ == "" {
+		return nil, fmt.Errorf("cannot infer the type of %q, add a cast such as ::text", sourceText(a, expr))
+	}
+	name := alias
+	if name == "" {
+		name = v.name
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%q needs an alias", sourceText(a, expr))
+	}
+	r := Result{Name: name, GoType: goType(v.typ, v.notNull, v.array)}
+	if v.column != nil && alias == "" {
+		r.column, r.table = v.column, v.table
+	}
+	return []Result{r}, nil
+}
+
+func (a *analysis) columnResult(st *scopedTable, c *Column, name string) Result {
+	return Result{
+		Name:   name,
+		GoType: goType(c.Type, c.NotNull && !st.nullable, c.Array),
+		column: c,
+		table:  st.table,
+	}
+}
+
+// value is what is known about an expression.
+type value struct {
+	typ     string
+	array   bool
+	notNull bool
+	name    string
+	column  *Column
+	table   *Table
+}
+
+// infer works out the type of the simple expressions common in select
+// lists: columns, casts, literals and a few functions. Anything else needs
+// a cast. Expressions are nullable unless known otherwise.
+func (a *analysis) infer(expr []token) (value, error) {
+	if len(expr) == 0 {
+		return value{}, nil
+	}
+	if qualifier, name, ok := columnRef(expr); ok {
+		if !(qualifier == "" && expr[0].keyword("true", "false", "null", "current_timestamp", "current_date")) {
+			st, c, err := a.column(qualifier, name, expr[0].depth)
+			if err != nil {
+				return value{}, err
+			}
+			return value{typ: c.Type, array: c.Array, notNull: c.NotNull && !st.nullable, name: c.Name, column: c, table: st.table}, nil
+		}
+	}
+
+	// A trailing cast decides the type
+	for i := len(expr) - 1; i > 0; i-- {
+		if expr[i].depth == expr[0].depth && expr[i].is(tokOp, "::") {
+			typ, array := parseType(expr[i+1:])
+			inner, err := a.infer(expr[:i])
+			if err != nil {
+				inner = value{}
+			}
+			return value{typ: typ, array: array, notNull: inner.notNull, name: inner.name}, nil
+		}
+	}
+
+	if len(expr) == 1 {
+		t := expr[0]
+		switch {
+		case t.kind == tokString:
+			return value{typ: "text", notNull: true}, nil
+		case t.kind == tokNumber && strings.Contains(t.text, "."):
+			return value{typ: "numeric", notNull: true}, nil
+		case t.kind == tokNumber:
+			return value{typ: "integer", notNull: true}, nil
+		case t.keyword("true", "false"):
+			return value{typ: "boolean", notNull: true}, nil
+		case t.keyword("current_timestamp"):
+			return value{typ: "timestamptz", notNull: true}, nil
+		case t.keyword("current_date"):
+			return value{typ: "date", notNull: true}, nil
+		}
+	}
+
+	// Function calls: name(args)
+	if len(expr) >= 3 && expr[0].kind == tokIdent && expr[1].is(tokPunct, "(") {
+		args, end := inner(expr, 1)
+		if end != len(expr)-1 {
+			return value{}, nil
+		}
+		switch expr[0].text {
+		case "count":
+			return value{typ: "bigint", notNull: true, name: "count"}, nil
+		case "exists":
+			return value{typ: "boolean", notNull: true, name: "exists"}, nil
+		case "now":
+			return value{typ: "timestamptz", notNull: true, name: "now"}, nil
+		case "coalesce":
+			var v value
+			for _, arg := range splitTop(args, ",") {
+				av, err := a.infer(arg)
+				if err != nil {
+					return value{}, err
+				}
+				if v.typ == "" {
+					v.typ, v.array, v.name = av.typ, av.array, av.name
+				}
+				v.notNull = v.notNull || av.notNull
+			}
+			return v, nil
+		case "min", "max", "lower", "upper", "trim":
+			v, err := a.infer(args)
+			if err != nil || v.typ == "" {
+				return value{}, err
+			}
+			notNull := v.notNull && expr[0].text != "min" && expr[0].text != "max"
+			return value{typ: v.typ, array: v.array, notNull: notNull, name: expr[0].text}, nil
+		}
+	}
+	return value{}, nil
+}
+
+var comparisons = map[string]bool{"=": true, "<>": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (q *Query) params(a *analysis) error {
+	tokens := a.tokens
+	byIndex := make(map[int]*paramInfo)
+	maxIndex := 0
+
+	inserts := a.insertColumns()
+	named := make(map[string]int)
+	numbered := false
+	for i, t := range tokens {
+		if t.kind != tokParam {
+			continue
+		}
+		var n int
+		if name, ok := strings.CutPrefix(t.text, "@"); ok {
+			if n = named[name]; n == 0 {
+				n = len(named) + 1
+				named[name] = n
+			}
+			a.rewrites = append(a.rewrites, rewrite{t.start, t.end, "$" + strconv.Itoa(n)})
+		} else {
+			numbered = true
+			n, _ = strconv.Atoi(t.text[1:])
+		}
+		if n < 1 {
+			return fmt.Errorf("invalid parameter %s", t.text)
+		}
+		if numbered && len(named) > 0 {
+			return fmt.Errorf("$N and @name parameters cannot be mixed")
+		}
+		maxIndex = max(maxIndex, n)
+		p := byIndex[n]
+		if p == nil {
+			p = &paramInfo{}
+			if name, ok := strings.CutPrefix(t.text, "@"); ok {
+				p.given = name
+			}
+			byIndex[n] = p
+		}
+		if p.typ != "" {
+			continue
+		}
+		if err := a.param(p, i, inserts); err != nil {
+			return fmt.Errorf("%s: %w", t.text, err)
+		}
+	}
+
+	used := make(map[string]bool)
+	for n := 1; n <= maxIndex; n++ {
+		p := byIndex[n]
+		if p == nil {
+			return fmt.Errorf("$%d is never used", n)
+		}
+		if p.typ == "" {
+			return fmt.Errorf("cannot infer the type of $%d, add a cast such as $%d::text", n, n)
+		}
+		typ := goType(p.typ, p.notNull, p.array)
+		if p.any {
+			typ = "[]" + typ
+		}
+
+		raw := p.name
+		if p.given != "" {
+			raw = p.given
+		} else if raw == "" {
+			raw = "arg" + strconv.Itoa(n)
+		}
+		name, field := lowerCamel(raw), goName(raw)
+		if p.given == "" && used[name] {
+			name += strconv.Itoa(n)
+			field += strconv.Itoa(n)
+		}
+		used[name] = true
+		q.Params = append(q.Params, Param{Name: name, Field: field, GoType: typ})
+	}
+	return nil
+}
+
+type paramInfo struct {
+	// given is the name of an @name parameter
+	given   string
+	typ     string
+	array   bool
+	notNull bool
+	name    string
+	// any is set for col = ANY($1), which takes a slice
+	any bool
+}
+
+// param infers the type of the parameter at tokens[i] from its context.
+func (a *analysis) param(p *paramInfo, i int, inserts map[int]*Column) error {
+	tokens := a.tokens
+	assign := a.assignment(i)
+
+	var (
+		column *Column
+		err    error
+	)
+	switch {
+	case inserts[i] != nil:
+		column, assign = inserts[i], true
+	case i >= 2 && (comparisons[tokens[i-1].text] && tokens[i-1].kind == tokOp || tokens[i-1].keyword("like", "ilike")):
+		column, err = a.refEndingAt(i - 2)
+	case i >= 4 && tokens[i-1].is(tokPunct, "(") && tokens[i-2].keyword("any") && comparisons[tokens[i-3].text] && i+1 < len(tokens) && tokens[i+1].is(tokPunct, ")"):
+		column, err = a.refEndingAt(i - 4)
+		p.any = true
+	case i+2 < len(tokens) && tokens[i+1].kind == tokOp && comparisons[tokens[i+1].text]:
+		column, err = a.refStartingAt(i + 2)
+	case i >= 1 && tokens[i-1].keyword("limit", "offset"):
+		p.typ, p.notNull, p.name = "bigint", true, tokens[i-1].text
+	}
+	if err != nil {
+		return err
+	}
+
+	if column != nil {
+		p.name = column.Name
+		if p.any {
+			p.name = column.Name + "s"
+		}
+		p.typ, p.array = column.Type, column.Array
+		// Values compared with a column are never NULL; values stored in
+		// one may be when the column allows it
+		p.notNull = !assign || column.NotNull || p.any
+	}
+
+	// A cast pins the type, and the value is never NULL
+	if i+2 < len(tokens) && tokens[i+1].is(tokOp, "::") {
+		p.typ, p.array = castType(tokens[i+2:])
+		p.notNull = true
+	}
+	return nil
+}
+
+// refEndingAt resolves the column reference whose last token is tokens[j].
+// It returns nil when there is no column reference there.
+func (a *analysis) refEndingAt(j int) (*Column, error) {
+	tokens := a.tokens
+	if j < 0 || tokens[j].kind != tokIdent {
+		return nil, nil
+	}
+	if j >= 2 && tokens[j-1].is(tokPunct, ".") {
+		return a.columnOnly(tokens[j-2].text, tokens[j].text, tokens[j].depth)
+	}
+	if reserved[tokens[j].text] {
+		return nil, nil
+	}
+	return a.columnOnly("", tokens[j].text, tokens[j].depth)
+}
+
+// refStartingAt resolves the column reference whose first token is
+// tokens[j].
+func (a *analysis) refStartingAt(j int) (*Column, error) {
+	tokens := a.tokens
+	if tokens[j].kind != tokIdent {
+		return nil, nil
+	}
+	if j+2 < len(tokens) && tokens[j+1].is(tokPunct, ".") {
+		return a.columnOnly(tokens[j].text, tokens[j+2].text, tokens[j].depth)
+	}
+	if reserved[tokens[j].text] {
+		return nil, nil
+	}
+	return a.columnOnly("", tokens[j].text, tokens[j].depth)
+}
+
+func (a *analysis) columnOnly(qualifier, name string, depth int) (*Column, error) {
+	_, c, err := a.column(qualifier, name, depth)
+	return c, err
+}
+
+// assignment reports whether tokens[i] is inside a SET clause.
+func (a *analysis) assignment(i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		t := a.tokens[j]
+		if t.depth > a.tokens[i].depth {
+			continue
+		}
+		if t.keyword("set") {
+			return true
+		}
+		if t.keyword("where", "from", "returning", "on", "values", "select") {
+			return false
+		}
+	}
+	return false
+}
+
+// insertColumns maps the parameters in INSERT ... VALUES tuples to the
+// columns they are stored in, by token index.
+func (a *analysis) insertColumns() map[int]*Column {
+	tokens := a.tokens
+	columns := make(map[int]*Column)
+	if !tokens[0].keyword("insert") || len(a.tables) == 0 {
+		return columns
+	}
+	table := a.tables[0].table
+
+	var names []string
+	i := 0
+	for i < len(tokens) && !(tokens[i].depth == 0 && tokens[i].is(tokPunct, "(")) {
+		if tokens[i].depth == 0 && tokens[i].keyword("values", "select", "default") {
+			break
+		}
+		i++
+	}
+	if i < len(tokens) && tokens[i].is(tokPunct, "(") {
+		list, end := inner(tokens, i)
+		for _, part := range splitTop(list, ",") {
+			if len(part) == 1 {
+				names = append(names, part[0].text)
+			}
+		}
+		i = end + 1
+	} else {
+		for _, c := range table.Columns {
+			names = append(names, c.Name)
+		}
+	}
+
+	for ; i < len(tokens); i++ {
+		if tokens[i].depth != 0 {
+			continue
+		}
+		if tokens[i].keyword("on", "returning") {
+			break
+		}
+		if !tokens[i].is(tokPunct, "(") {
+			continue
+		}
+		values, end := inner(tokens, i)
+		for k, part := range splitTop(values, ",") {
+			if k < len(names) && len(part) > 0 && part[0].kind == tokParam {
+				columns[indexOf(tokens, part[0])] = table.Column(names[k])
+			}
+		}
+		i = end
+	}
+	return columns
+}
+
+func indexOf(tokens []token, t token) int {
+	for i := range tokens {
+		if tokens[i].start == t.start {
+			return i
+		}
+	}
+	return -1
+}
+
+// multiWordTypes are the type names spelled with more than one word.
+var multiWordTypes = []string{
+	"timestamp with time zone", "timestamp without time zone",
+	"time with time zone", "time without time zone",
+	"double precision", "character varying",
+}
+
+// castType reads the type following ::.
+func castType(tokens []token) (string, bool) {
+	words := keywords(tokens)
+	n := 1
+	for _, m := range multiWordTypes {
+		if hasPrefix(words, strings.Fields(m)...) {
+			n = len(strings.Fields(m))
+			break
+		}
+	}
+	if n == 1 && len(tokens) >= 3 && tokens[1].is(tokPunct, ".") {
+		// pg_catalog.int4
+		n = 3
+	}
+	if n < len(tokens) && tokens[n].is(tokPunct, "(") {
+		_, end := inner(tokens, n)
+		n = end + 1
+	}
+	for n+1 < len(tokens) && tokens[n].is(tokPunct, "[") && tokens[n+1].is(tokPunct, "]") {
+		n += 2
+	}
+	return parseType(tokens[:min(n, len(tokens))])
+}
+
+func sourceText(a *analysis, item []token) string {
+	return a.text[item[0].start:item[len(item)-1].end]
+}
+
+func containsWord(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,386 @@
+package sqlgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Schema is the set of tables left after applying every migration.
+type Schema struct {
+	tables map[string]*Table
+}
+
+type Table struct {
+	Name    string
+	Columns []*Column
+}
+
+type Column struct {
+	Name string
+	// Type is the normalised Postgres type, e.g. "text" or "timestamptz"
+	Type    string
+	NotNull bool
+	Array   bool
+}
+
+func (s *Schema) Table(name string) *Table {
+	return s.tables[name]
+}
+
+// Tables returns every table sorted by name.
+func (s *Schema) Tables() []*Table {
+	tables := make([]*Table, 0, len(s.tables))
+	for _, t := range s.tables {
+		tables = append(tables, t)
+	}
+	sort.Slice(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	return tables
+}
+
+func (t *Table) Column(name string) *Column {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// LoadSchema applies the Up section of each goose migration in dir, in file
+// name order. Statements other than CREATE, ALTER and DROP TABLE are ignored.
+func LoadSchema(dir string) (*Schema, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	s := &Schema{tables: make(map[string]*Table)}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.apply(upSection(string(src))); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	}
+	return s, nil
+}
+
+// upSection returns the part of a goose migration between "+goose Up" and
+// "+goose Down".
+func upSection(src string) string {
+	var out strings.Builder
+	up := false
+	for _, line := range strings.SplitAfter(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") && strings.Contains(trimmed, "+goose") {
+			switch {
+			case strings.Contains(trimmed, "+goose Up"):
+				up = true
+			case strings.Contains(trimmed, "+goose Down"):
+				up = false
+			}
+			continue
+		}
+		if up {
+			out.WriteString(line)
+		}
+	}
+	return out.String()
+}
+
+func (s *Schema) apply(src string) error {
+	tokens, err := lex(src)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitTop(tokens, ";") {
+		if len(stmt) == 0 {
+			continue
+		}
+		if err := s.statement(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) statement(stmt []token) error {
+	words := keywords(stmt)
+	switch {
+	case hasPrefix(words, "create", "table"),
+		hasPrefix(words, "create", "unlogged", "table"),
+		hasPrefix(words, "create", "temp", "table"),
+		hasPrefix(words, "create", "temporary", "table"):
+		return s.createTable(stmt)
+	case hasPrefix(words, "alter", "table"):
+		return s.alterTable(stmt[2:])
+	case hasPrefix(words, "drop", "table"):
+		rest := skipWords(stmt[2:], "if", "exists")
+		for _, part := range splitTop(rest, ",") {
+			name, _ := qualifiedName(part)
+			delete(s.tables, name)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) createTable(stmt []token) error {
+	i := 0
+	for !stmt[i].keyword("table") {
+		i++
+	}
+	rest := skipWords(stmt[i+1:], "if", "not", "exists")
+	name, n := qualifiedName(rest)
+	if name == "" || n >= len(rest) || !rest[n].is(tokPunct, "(") {
+		return fmt.Errorf("CREATE TABLE: expected a column list")
+	}
+	if _, ok := s.tables[name]; ok && !hasPrefix(keywords(stmt[i+1:]), "if", "not", "exists") {
+		return fmt.Errorf("table %s already exists", name)
+	} else if ok {
+		return nil
+	}
+
+	table := &Table{Name: name}
+	defs, _ := inner(rest, n)
+	for _, def := range splitTop(defs, ",") {
+		if len(def) == 0 {
+			continue
+		}
+		if def[0].keyword("primary", "unique", "constraint", "check", "foreign", "exclude", "like") {
+			table.constraint(def)
+			continue
+		}
+		column, err := parseColumn(def)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	s.tables[name] = table
+	return nil
+}
+
+// constraint applies the NOT NULL implied by a table level primary key.
+func (t *Table) constraint(def []token) {
+	for i, tok := range def {
+		if tok.keyword("primary") && i+2 < len(def) && def[i+1].keyword("key") && def[i+2].is(tokPunct, "(") {
+			names, _ := inner(def, i+2)
+			for _, part := range splitTop(names, ",") {
+				if len(part) > 0 {
+					if c := t.Column(part[0].text); c != nil {
+						c.NotNull = true
+					}
+				}
+			}
+		}
+	}
+}
+
+func (s *Schema) alterTable(stmt []token) error {
+	rest := skipWords(stmt, "if", "exists")
+	rest = skipWords(rest, "only")
+	name, n := qualifiedName(rest)
+	table := s.tables[name]
+	if table == nil {
+		return fmt.Errorf("ALTER TABLE: unknown table %s", name)
+	}
+
+	for _, action := range splitTop(rest[n:], ",") {
+		words := keywords(action)
+		switch {
+		case hasPrefix(words, "add", "constraint"), hasPrefix(words, "add", "primary"):
+			table.constraint(action[1:])
+		case hasPrefix(words, "add"):
+			def := skipWords(action[1:], "column")
+			def = skipWords(def, "if", "not", "exists")
+			column, err := parseColumn(def)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if table.Column(column.Name) == nil {
+				table.Columns = append(table.Columns, column)
+			}
+		case hasPrefix(words, "drop", "constraint"):
+		case hasPrefix(words, "drop"):
+			def := skipWords(action[1:], "column")
+			def = skipWords(def, "if", "exists")
+			if len(def) > 0 {
+				table.drop(def[0].text)
+			}
+		case hasPrefix(words, "rename", "to"):
+			newName, _ := qualifiedName(action[2:])
+			delete(s.tables, table.Name)
+			table.Name = newName
+			s.tables[newName] = table
+		case hasPrefix(words, "rename"):
+			def := skipWords(action[1:], "column")
+			if len(def) == 3 && def[1].keyword("to") {
+				if c := table.Column(def[0].text); c != nil {
+					c.Name = def[2].text
+				}
+			}
+		case hasPrefix(words, "alter"):
+			def := skipWords(action[1:], "column")
+			if len(def) < 2 {
+				continue
+			}
+			c := table.Column(def[0].text)
+			if c == nil {
+				return fmt.Errorf("%s: unknown column %s", name, def[0].text)
+			}
+			change := keywords(def[1:])
+			switch {
+			case hasPrefix(change, "set", "not", "null"):
+				c.NotNull = true
+			case hasPrefix(change, "drop", "not", "null"):
+				c.NotNull = false
+			case hasPrefix(change, "type"), hasPrefix(change, "set", "data", "type"):
+				typ := def[2:]
+				if def[1].keyword("set") {
+					typ = def[4:]
+				}
+				c.Type, c.Array = parseType(typ)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Table) drop(name string) {
+	for i, c := range t.Columns {
+		if c.Name == name {
+			t.Columns = append(t.Columns[:i], t.Columns[i+1:]...)
+			return
+		}
+	}
+}
+
+var columnConstraints = []string{"not", "null", "default", "primary", "references", "unique", "check", "constraint", "generated", "collate"}
+
+func parseColumn(def []token) (*Column, error) {
+	if len(def) < 2 || def[0].kind != tokIdent {
+		return nil, fmt.Errorf("invalid column definition")
+	}
+	column := &Column{Name: def[0].text}
+
+	end := 1
+	for end < len(def) && !(def[end].depth == def[0].depth && def[end].keyword(columnConstraints...)) {
+		end++
+	}
+	column.Type, column.Array = parseType(def[1:end])
+	if column.Type == "" {
+		return nil, fmt.Errorf("column %s has no type", column.Name)
+	}
+
+	for i := end; i < len(def); i++ {
+		switch {
+		case def[i].keyword("not") && i+1 < len(def) && def[i+1].keyword("null"):
+			column.NotNull = true
+		case def[i].keyword("primary") && def[i].depth == def[0].depth:
+			column.NotNull = true
+		}
+	}
+	return column, nil
+}
+
+// typeAliases maps spellings of a Postgres type to one name.
+var typeAliases = map[string]string{
+	"int":                         "integer",
+	"int4":                        "integer",
+	"serial":                      "integer",
+	"serial4":                     "integer",
+	"int8":                        "bigint",
+	"bigserial":                   "bigint",
+	"serial8":                     "bigint",
+	"int2":                        "smallint",
+	"smallserial":                 "smallint",
+	"serial2":                     "smallint",
+	"float8":                      "double precision",
+	"float":                       "double precision",
+	"float4":                      "real",
+	"decimal":                     "numeric",
+	"bool":                        "boolean",
+	"varchar":                     "text",
+	"character varying":           "text",
+	"character":                   "text",
+	"char":                        "text",
+	"citext":                      "text",
+	"timestamp with time zone":    "timestamptz",
+	"timestamp without time zone": "timestamp",
+	"time with time zone":         "timetz",
+	"time without time zone":      "time",
+}
+
+// parseType normalises a type, dropping modifiers such as varchar(255).
+func parseType(tokens []token) (string, bool) {
+	var words []string
+	array := false
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.is(tokPunct, "("):
+			_, i = inner(tokens, i)
+		case t.is(tokPunct, "["):
+			array = true
+		case t.kind == tokIdent && t.keyword("array"):
+			array = true
+		case t.kind == tokIdent:
+			words = append(words, t.text)
+		}
+	}
+	// Drop a schema qualifier such as pg_catalog.int4
+	if len(words) > 1 && (words[0] == "pg_catalog" || words[0] == "public") {
+		words = words[1:]
+	}
+	typ := strings.Join(words, " ")
+	if alias, ok := typeAliases[typ]; ok {
+		typ = alias
+	}
+	return typ, array
+}
+
+// qualifiedName reads a possibly schema qualified name and returns the
+// unqualified part and the number of tokens used.
+func qualifiedName(tokens []token) (string, int) {
+	if len(tokens) == 0 || tokens[0].kind != tokIdent {
+		return "", 0
+	}
+	if len(tokens) >= 3 && tokens[1].is(tokPunct, ".") && tokens[2].kind == tokIdent {
+		return tokens[2].text, 3
+	}
+	return tokens[0].text, 1
+}
+
+func keywords(tokens []token) []string {
+	words := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		words = append(words, t.text)
+	}
+	return words
+}
+
+func hasPrefix(words []string, prefix ...string) bool {
+	if len(words) < len(prefix) {
+		return false
+	}
+	for i, p := range prefix {
+		if words[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+// skipWords drops words from the front of tokens when all of them are there.
+func skipWords(tokens []token, words ...string) []token {
+	if hasPrefix(keywords(tokens), words...) {
+		return tokens[len(words):]
+	}
+	return tokens
+}
@@ -0,0 +1,57 @@
+package sqlgen
+
+import "strings"
+
+// goTypes maps normalised Postgres types to the Go type scanned for a NOT
+// NULL value. Nullable values use sql.Null of the same type; other types,
+// including arrays, scan into interface{}.
+var goTypes = map[string]string{
+	"text":             "string",
+	"uuid":             "uuid.UUID",
+	"smallint":         "int16",
+	"integer":          "int32",
+	"bigint":           "int64",
+	"real":             "float32",
+	"double precision": "float64",
+	"numeric":          "string",
+	"boolean":          "bool",
+	"timestamptz":      "time.Time",
+	"timestamp":        "time.Time",
+	"date":             "time.Time",
+	"time":             "string",
+	"timetz":           "string",
+	"interval":         "string",
+	"inet":             "string",
+	"cidr":             "string",
+	"jsonb":            "json.RawMessage",
+	"json":             "json.RawMessage",
+	"bytea":            "[]byte",
+}
+
+func goType(pgType string, notNull, array bool) string {
+	typ, ok := goTypes[pgType]
+	if !ok || array {
+		return "interface{}"
+	}
+	if notNull || strings.HasPrefix(typ, "[]") {
+		// []byte scans NULL as nil; named slices like json.RawMessage do not
+		return typ
+	}
+	return "sql.Null[" + typ + "]"
+}
+
+// imports lists the packages a Go type expression refers to.
+func imports(typ string) []string {
+	var pkgs []string
+	for prefix, pkg := range map[string]string{
+		"sql.":  "database/sql",
+		"uuid.": "github.com/google/uuid",
+		"time.": "time",
+		"json.": "encoding/json",
+	} {
+		if strings.Contains(typ, prefix) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs
+}
@@ -0,0 +1,176 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/pkg/logger"
+	"github.com/google/uuid"
+)
+
+// recordTimeout bounds the queries logging an attempt, which run even when
+// the delivery timed out.
+const recordTimeout = 5 * time.Second
+
+// DeliverArgs is the job delivering an event to a webhook.
+type DeliverArgs struct {
+	WebhookID uuid.UUID    `json:"webhook_id"`
+	Event     events.Event `json:"event"`
+}
+
+func (DeliverArgs) Kind() string { return "deliver_webhook" }
+
+// Register adds the delivery job handler to registry.
+func (s *Service) Register(registry *jobs.Registry) {
+	jobs.Register(registry, s.deliver)
+}
+
+func (s *Service) Name() string { return "webhooks" }
+
+// Deliver enqueues a delivery job for every enabled webhook subscribed to
+// e. Each job has a unique key, so an event the relay hands over again is
+// not enqueued twice while its first job is pending.
+func (s *Service) Deliver(ctx context.Context, e events.Event) error {
+	rows, err := s.queries.ListEnabledWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, row := range rows {
+		if !subscribed(row, e.Type) {
+			continue
+		}
+		_, err := s.enqueue(ctx, row.ID, e, jobs.WithUniqueKey(fmt.Sprintf("%s:%d", row.ID, e.ID)))
+		if err != nil && !errors.Is(err, jobs.ErrDuplicate) {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", row.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func subscribed(row queries.Webhook, eventType string) bool {
+	for _, pattern := range patterns(row) {
+		if events.Match(pattern, eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) enqueue(ctx context.Context, id uuid.UUID, e events.Event, opts ...jobs.EnqueueOption) (*jobs.Job, error) {
+	if n := s.options().MaxAttempts; n > 0 {
+		opts = append(opts, jobs.WithMaxAttempts(int32(n)))
+	}
+	return s.jobs.Enqueue(ctx, DeliverArgs{WebhookID: id, Event: e}, opts...)
+}
+
+// deliver runs one attempt of a delivery job and logs it. A failed attempt
+// is retried by the job queue unless it disabled the webhook.
+func (s *Service) deliver(ctx context.Context, job *jobs.Job, args DeliverArgs) error {
+	log := logger.FromContext(ctx).With("webhook_id", args.WebhookID, "event_id", args.Event.ID)
+	webhook, err := s.queries.GetWebhook(ctx, args.WebhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.Debug("webhook deleted, dropping delivery")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !webhook.Enabled {
+		return jobs.Permanent(ErrDisabled)
+	}
+
+	opts := s.options()
+	body, err := json.Marshal(args.Event)
+	if err != nil {
+		return jobs.Permanent(err)
+	}
+	start := time.Now()
+	status, err := s.post(ctx, webhook, args.Event, body, opts.Timeout)
+	elapsed := time.Since(start)
+
+	rctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+	delivery := queries.InsertWebhookDeliveryParams{
+		WebhookID:      webhook.ID,
+		EventID:        args.Event.ID,
+		EventType:      args.Event.Type,
+		Payload:        body,
+		Attempt:        job.Attempts,
+		ResponseStatus: sql.Null[int32]{V: int32(status), Valid: status != 0},
+		DurationMs:     int32(elapsed.Milliseconds()),
+		CreatedAt:      start,
+	}
+	if err != nil {
+		delivery.Error = sql.Null[string]{V: err.Error(), Valid: true}
+	}
+	if _, rerr := s.queries.InsertWebhookDelivery(rctx, delivery); rerr != nil {
+		log.Error("logging webhook delivery failed", "error", rerr)
+	}
+
+	if err == nil {
+		if rerr := s.queries.ResetWebhookFailures(rctx, webhook.ID); rerr != nil {
+			log.Error("resetting webhook failures failed", "error", rerr)
+		}
+		return nil
+	}
+
+	failures, rerr := s.queries.CountWebhookFailure(rctx, webhook.ID)
+	if rerr != nil {
+		log.Error("counting webhook failure failed", "error", rerr)
+		return err
+	}
+	if opts.DisableAfter > 0 && int(failures) >= opts.DisableAfter {
+		if _, rerr := s.queries.DisableWebhook(rctx, queries.DisableWebhookParams{Now: time.Now(), ID: webhook.ID}); rerr != nil {
+			log.Error("disabling webhook failed", "error", rerr)
+			return err
+		}
+		log.Warn("webhook disabled after repeated failures", "failures", failures, "error", err)
+		return jobs.Permanent(err)
+	}
+	return err
+}
+
+// post sends a signed delivery and returns the response status, 0 when no
+// response arrived. The response body is discarded: the destination is not
+// trusted, so nothing it returns is kept.
+func (s *Service) post(ctx context.Context, webhook queries.Webhook, e events.Event, body []byte, timeout time.Duration) (int, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderID, strconv.FormatInt(e.ID, 10))
+	req.Header.Set(HeaderEvent, e.Type)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, now, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
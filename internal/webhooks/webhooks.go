@@ -0,0 +1,275 @@
+// Package webhooks notifies partner endpoints of domain events. The Service
+// is an events.Sink: for every relayed event it enqueues a job per matching
+// webhook, and the job POSTs the event signed with the webhook's secret,
+// logging each attempt. Failed attempts are retried with the job queue's
+// backoff, and a webhook failing too many times in a row is disabled.
+package webhooks
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/database/queries"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrDisabled is returned when redelivering to a disabled webhook.
+var ErrDisabled = errors.New("webhooks: webhook is disabled")
+
+// Options are read for every delivery, so they can change at runtime.
+type Options struct {
+	// Timeout bounds a delivery, including reading the response.
+	Timeout time.Duration
+	// MaxAttempts is how often an event is tried before its job dies; 0
+	// leaves the job queue's default.
+	MaxAttempts int
+	// DisableAfter is how many attempts in a row may fail before the
+	// webhook is disabled; 0 never disables it.
+	DisableAfter int
+	// AllowPrivateNetworks lets webhooks point at loopback, link-local and
+	// private addresses, e.g. for receivers running next to the API in
+	// development.
+	AllowPrivateNetworks bool
+}
+
+type Service struct {
+	queries *queries.Queries
+	jobs    *jobs.Client
+	options func() Options
+	client  *http.Client
+}
+
+func NewService(db queries.DBTX, client *jobs.Client, options func() Options) *Service {
+	return &Service{
+		queries: database.NewQueries(db),
+		jobs:    client,
+		options: options,
+		client:  newClient(func() bool { return options().AllowPrivateNetworks }),
+	}
+}
+
+// checkURL rejects destinations webhooks may not point at.
+func (s *Service) checkURL(raw string) error {
+	if s.options().AllowPrivateNetworks {
+		return nil
+	}
+	return CheckURL(raw)
+}
+
+// Create adds a webhook, generating its secret unless one is given. The
+// returned webhook is the only one carrying the secret. URLs pointing at
+// internal addresses are rejected with ErrForbiddenDestination.
+func (s *Service) Create(ctx context.Context, req models.CreateWebhookRequest) (*models.Webhook, error) {
+	if err := s.checkURL(req.URL); err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		secret = "whsec_" + hex.EncodeToString(b)
+	}
+	patterns, err := json.Marshal(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := s.queries.InsertWebhook(ctx, queries.InsertWebhookParams{
+		URL:    req.URL,
+		Events: patterns,
+		Secret: secret,
+		Now:    time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	webhook := toWebhook(row)
+	webhook.Secret = row.Secret
+	return webhook, nil
+}
+
+func (s *Service) Get(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	row, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhook(row), nil
+}
+
+func (s *Service) List(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := s.queries.ListWebhooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	webhooks := make([]models.Webhook, len(rows))
+	for i, row := range rows {
+		webhooks[i] = *toWebhook(row)
+	}
+	return webhooks, nil
+}
+
+func (s *Service) Update(ctx context.Context, id uuid.UUID, req models.UpdateWebhookRequest) (*models.Webhook, error) {
+	row, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	arg := queries.UpdateWebhookParams{
+		URL:                 row.URL,
+		Events:              row.Events,
+		Enabled:             row.Enabled,
+		ConsecutiveFailures: row.ConsecutiveFailures,
+		DisabledAt:          row.DisabledAt,
+		Now:                 now,
+		ID:                  id,
+	}
+	if req.URL != nil {
+		if err := s.checkURL(*req.URL); err != nil {
+			return nil, err
+		}
+		arg.URL = *req.URL
+	}
+	if req.Events != nil {
+		if arg.Events, err = json.Marshal(req.Events); err != nil {
+			return nil, err
+		}
+	}
+	if req.Enabled != nil && *req.Enabled != row.Enabled {
+		arg.Enabled = *req.Enabled
+		if arg.Enabled {
+			arg.ConsecutiveFailures = 0
+			arg.DisabledAt = sql.Null[time.Time]{}
+		} else {
+			arg.DisabledAt = sql.Null[time.Time]{V: now, Valid: true}
+		}
+	}
+
+	row, err = s.queries.UpdateWebhook(ctx, arg)
+	if err != nil {
+		return nil, err
+	}
+	return toWebhook(row), nil
+}
+
+// Delete removes a webhook and its delivery log.
+func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
+	n, err := s.queries.DeleteWebhook(ctx, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("webhook %s: %w", id, database.ErrNotFound)
+	}
+	return nil
+}
+
+// Deliveries returns a webhook's delivery log, newest attempt first.
+func (s *Service) Deliveries(ctx context.Context, id uuid.UUID, limit, offset uint64) ([]models.WebhookDelivery, error) {
+	if _, err := s.get(ctx, id); err != nil {
+		return nil, err
+	}
+	rows, err := s.queries.ListWebhookDeliveries(ctx, queries.ListWebhookDeliveriesParams{
+		WebhookID: id,
+		Max:       int64(limit),
+		Skip:      int64(offset),
+	})
+	if err != nil {
+		return nil, err
+	}
+	deliveries := make([]models.WebhookDelivery, len(rows))
+	for i, row := range rows {
+		deliveries[i] = toDelivery(row)
+	}
+	return deliveries, nil
+}
+
+// Redeliver enqueues the event of a logged delivery again, with a fresh set
+// of attempts.
+func (s *Service) Redeliver(ctx context.Context, id uuid.UUID, deliveryID int64) (*jobs.Job, error) {
+	webhook, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !webhook.Enabled {
+		return nil, ErrDisabled
+	}
+	delivery, err := s.queries.GetWebhookDelivery(ctx, queries.GetWebhookDeliveryParams{ID: deliveryID, WebhookID: id})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("webhook delivery %d: %w", deliveryID, database.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var e events.Event
+	if err := json.Unmarshal(delivery.Payload, &e); err != nil {
+		return nil, fmt.Errorf("decoding webhook delivery %d: %w", deliveryID, err)
+	}
+	return s.enqueue(ctx, id, e)
+}
+
+func (s *Service) get(ctx context.Context, id uuid.UUID) (queries.Webhook, error) {
+	row, err := s.queries.GetWebhook(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return row, fmt.Errorf("webhook %s: %w", id, database.ErrNotFound)
+	}
+	return row, err
+}
+
+func toWebhook(row queries.Webhook) *models.Webhook {
+	webhook := &models.Webhook{
+		ID:                  row.ID,
+		URL:                 row.URL,
+		Events:              patterns(row),
+		Enabled:             row.Enabled,
+		ConsecutiveFailures: int(row.ConsecutiveFailures),
+		CreatedAt:           row.CreatedAt,
+		UpdatedAt:           row.UpdatedAt,
+	}
+	if row.DisabledAt.Valid {
+		webhook.DisabledAt = &row.DisabledAt.V
+	}
+	return webhook
+}
+
+func toDelivery(row queries.WebhookDelivery) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		ID:         row.ID,
+		WebhookID:  row.WebhookID,
+		EventID:    row.EventID,
+		EventType:  row.EventType,
+		Attempt:    int(row.Attempt),
+		DurationMs: int(row.DurationMs),
+		CreatedAt:  row.CreatedAt,
+	}
+	if row.ResponseStatus.Valid {
+		status := int(row.ResponseStatus.V)
+		delivery.ResponseStatus = &status
+	}
+	if row.Error.Valid {
+		delivery.Error = &row.Error.V
+	}
+	return delivery
+}
+
+// patterns decodes the event patterns of a webhook. Rows are only written
+// by this package, so a row that does not decode matches nothing.
+func patterns(row queries.Webhook) []string {
+	patterns := []string{}
+	json.Unmarshal(row.Events, &patterns)
+	return patterns
+}
@@ -0,0 +1,96 @@
+// Package webui bundles the browser UIs served next to the API: Swagger UI
+// and Redoc for the OpenAPI document and GraphiQL for GraphQL. The npm
+// packages pinned here are vendored under dist by cmd/tools/webui and
+// embedded, so the pages work offline and their CSP only trusts this
+// origin. A package that has not been vendored yet is loaded from jsDelivr,
+// and pages trust only its pinned files there.
+package webui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+// Prefix is the path the embedded assets are served under.
+const Prefix = "/ui/"
+
+const cdnOrigin = "https://cdn.jsdelivr.net"
+
+// Package is an npm package and the files of it pages load.
+type Package struct {
+	Name    string
+	Version string
+	Files   []string
+}
+
+var (
+	SwaggerUI = Package{Name: "swagger-ui-dist", Version: "5.18.2", Files: []string{"swagger-ui-bundle.js", "swagger-ui.css"}}
+	Redoc     = Package{Name: "redoc", Version: "2.1.5", Files: []string{"bundles/redoc.standalone.js"}}
+	GraphiQL  = Package{Name: "graphiql", Version: "3.7.1", Files: []string{"graphiql.min.js", "graphiql.min.css"}}
+	React     = Package{Name: "react", Version: "18.3.1", Files: []string{"umd/react.production.min.js"}}
+	ReactDOM  = Package{Name: "react-dom", Version: "18.3.1", Files: []string{"umd/react-dom.production.min.js"}}
+)
+
+// Packages lists every pinned package, as vendored by cmd/tools/webui.
+var Packages = []Package{SwaggerUI, Redoc, GraphiQL, React, ReactDOM}
+
+//go:embed dist
+var dist embed.FS
+
+// Dir is the directory holding the files of p, relative to dist.
+func (p Package) Dir() string {
+	return p.Name + "@" + p.Version
+}
+
+// Bundled reports whether every file of p is embedded.
+func (p Package) Bundled() bool {
+	for _, file := range p.Files {
+		if _, err := fs.Stat(dist, "dist/"+p.Dir()+"/"+file); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// URL returns where pages load file of p from.
+func (p Package) URL(file string) string {
+	if p.Bundled() {
+		return Prefix + p.Dir() + "/" + file
+	}
+	return cdnOrigin + "/npm/" + p.Dir() + "/" + file
+}
+
+// Sources returns the CSP sources a page loading pkgs has to trust for
+// assets: this origin, and the pinned files of packages not embedded.
+func Sources(pkgs ...Package) string {
+	sources := []string{"'self'"}
+	for _, p := range pkgs {
+		if p.Bundled() {
+			continue
+		}
+		for _, file := range p.Files {
+			sources = append(sources, p.URL(file))
+		}
+	}
+	return strings.Join(sources, " ")
+}
+
+// Handler serves the embedded assets under Prefix. Their paths carry the
+// package version, so they are cached for good.
+func Handler() http.Handler {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		panic(err)
+	}
+	files := http.StripPrefix(Prefix, http.FileServerFS(sub))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		files.ServeHTTP(w, r)
+	})
+}
@@ -64,3 +64,43 @@ func InternalServerError(errorType ErrorType, details ...interface{}) *APIError
 	}
 	return NewAPIError(http.StatusInternalServerError, errorType, detailsVal, false)
 }
+
+func ServiceUnavailableError(errorType ErrorType, details ...interface{}) *APIError {
+	detailsVal := interface{}(nil)
+	if len(details) > 0 {
+		detailsVal = details[0]
+	}
+	return NewAPIError(http.StatusServiceUnavailable, errorType, detailsVal, true)
+}
+
+func TooManyRequestsError(errorType ErrorType, details ...interface{}) *APIError {
+	detailsVal := interface{}(nil)
+	if len(details) > 0 {
+		detailsVal = details[0]
+	}
+	return NewAPIError(http.StatusTooManyRequests, errorType, detailsVal, true)
+}
+
+func ConflictError(errorType ErrorType, details ...interface{}) *APIError {
+	detailsVal := interface{}(nil)
+	if len(details) > 0 {
+		detailsVal = details[0]
+	}
+	return NewAPIError(http.StatusConflict, errorType, detailsVal, true)
+}
+
+func PayloadTooLargeError(errorType ErrorType, details ...interface{}) *APIError {
+	detailsVal := interface{}(nil)
+	if len(details) > 0 {
+		detailsVal = details[0]
+	}
+	return NewAPIError(http.StatusRequestEntityTooLarge, errorType, detailsVal, true)
+}
+
+func UnsupportedMediaTypeError(errorType ErrorType, details ...interface{}) *APIError {
+	detailsVal := interface{}(nil)
+	if len(details) > 0 {
+		detailsVal = details[0]
+	}
+	return NewAPIError(http.StatusUnsupportedMediaType, errorType, detailsVal, true)
+}
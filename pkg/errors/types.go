@@ -6,10 +6,23 @@ type ErrorType struct {
 }
 
 var (
-	ErrBadRequest          = ErrorType{Code: "BAD_REQUEST", Message: "Bad request"}
-	ErrValidationFailed    = ErrorType{Code: "VALIDATION_FAILED", Message: "Validation failed"}
-	ErrUnauthorized        = ErrorType{Code: "UNAUTHORIZED", Message: "Unauthorized: User not authenticated."}
-	ErrNotFound            = ErrorType{Code: "NOT_FOUND", Message: "Resource not found."}
-	ErrInternalServerError = ErrorType{Code: "INTERNAL_SERVER_ERROR", Message: "Internal server error"}
-	ErrSomethingWentWrong  = ErrorType{Code: "SOMETHING_WENT_WRONG", Message: "Something went wrong"}
+	ErrBadRequest           = ErrorType{Code: "BAD_REQUEST", Message: "Bad request"}
+	ErrValidationFailed     = ErrorType{Code: "VALIDATION_FAILED", Message: "Validation failed"}
+	ErrUnauthorized         = ErrorType{Code: "UNAUTHORIZED", Message: "Unauthorized: User not authenticated."}
+	ErrNotFound             = ErrorType{Code: "NOT_FOUND", Message: "Resource not found."}
+	ErrInternalServerError  = ErrorType{Code: "INTERNAL_SERVER_ERROR", Message: "Internal server error"}
+	ErrSomethingWentWrong   = ErrorType{Code: "SOMETHING_WENT_WRONG", Message: "Something went wrong"}
+	ErrServiceUnavailable   = ErrorType{Code: "SERVICE_UNAVAILABLE", Message: "Service unavailable"}
+	ErrTooManyRequests      = ErrorType{Code: "TOO_MANY_REQUESTS", Message: "Too many requests, slow down."}
+	ErrPayloadTooLarge      = ErrorType{Code: "PAYLOAD_TOO_LARGE", Message: "Request body too large"}
+	ErrUnsupportedMediaType = ErrorType{Code: "UNSUPPORTED_MEDIA_TYPE", Message: "Unsupported media type"}
+	ErrInvalidResponse      = ErrorType{Code: "INVALID_RESPONSE", Message: "Response does not match the API specification"}
+	ErrUpgradeRequired      = ErrorType{Code: "UPGRADE_REQUIRED", Message: "This endpoint only serves WebSocket connections."}
+	ErrMethodNotAllowed     = ErrorType{Code: "METHOD_NOT_ALLOWED", Message: "Method not allowed"}
+	ErrQueryTooComplex      = ErrorType{Code: "QUERY_TOO_COMPLEX", Message: "Query exceeds the depth or complexity limit"}
+
+	ErrIdempotencyKeyReused = ErrorType{Code: "IDEMPOTENCY_KEY_REUSED", Message: "Idempotency key was already used for a different request."}
+	ErrIdempotencyKeyInUse  = ErrorType{Code: "IDEMPOTENCY_KEY_IN_USE", Message: "A request with this idempotency key is still being processed."}
+
+	ErrWebhookDisabled = ErrorType{Code: "WEBHOOK_DISABLED", Message: "The webhook is disabled; enable it before redelivering."}
 )
@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type userIDKey struct{}
+
+// WithUserID stores the authenticated user's ID so it is included in every
+// log line written with FromContext.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func UserIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(userIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger annotated with the request ID, user
+// ID and trace ID carried by ctx.
+func FromContext(ctx context.Context) *slog.Logger {
+	l := slog.Default()
+
+	var attrs []any
+	if id := requestid.FromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	if id := UserIDFromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("user_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs, slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+
+	if len(attrs) == 0 {
+		return l
+	}
+	return l.With(attrs...)
+}
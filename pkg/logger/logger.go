@@ -1,17 +1,113 @@
 package logger
 
 import (
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/lmittmann/tint"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func Init() {
-	logger := slog.New(tint.NewHandler(os.Stdout, &tint.Options{
-		Level:      slog.LevelDebug,
-		TimeFormat: time.Kitchen,
-	}))
-	slog.SetDefault(logger)
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatTint = "tint"
+)
+
+type Options struct {
+	Format string
+	Level  string
+	// Output is "stdout", "stderr" or a file path. Files are rotated.
+	Output string
+
+	MaxSizeMB  int
+	MaxBackups int
+	MaxAgeDays int
+
+	// Sampling keeps the first SampleInitial records with the same level and
+	// message per SampleWindow, then every SampleThereafter-th one. A zero
+	// SampleInitial disables sampling.
+	SampleInitial    int
+	SampleThereafter int
+	SampleWindow     time.Duration
+}
+
+var level = new(slog.LevelVar)
+
+func Init(opts Options) error {
+	lvl, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+
+	w, err := openOutput(opts)
+	if err != nil {
+		return err
+	}
+
+	var handler slog.Handler
+	switch strings.ToLower(opts.Format) {
+	case "", FormatTint:
+		handler = tint.NewHandler(w, &tint.Options{
+			Level:       level,
+			TimeFormat:  time.Kitchen,
+			ReplaceAttr: Redact,
+		})
+	case FormatText:
+		handler = slog.NewTextHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: Redact})
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level, ReplaceAttr: Redact})
+	default:
+		return fmt.Errorf("unknown log format %q", opts.Format)
+	}
+
+	if opts.SampleInitial > 0 {
+		handler = newSamplingHandler(handler, opts.SampleInitial, opts.SampleThereafter, opts.SampleWindow)
+	}
+
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+// SetLevel changes the minimum level of the default logger at runtime.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+func Level() slog.Level {
+	return level.Level()
+}
+
+func ParseLevel(s string) (slog.Level, error) {
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return l, nil
+}
+
+func openOutput(opts Options) (io.Writer, error) {
+	switch opts.Output {
+	case "", "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	default:
+		return &lumberjack.Logger{
+			Filename:   opts.Output,
+			MaxSize:    opts.MaxSizeMB,
+			MaxBackups: opts.MaxBackups,
+			MaxAge:     opts.MaxAgeDays,
+			Compress:   true,
+		}, nil
+	}
 }
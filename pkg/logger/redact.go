@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var sensitiveKeys = []string{
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"authorization",
+	"api_key",
+	"api-key",
+	"apikey",
+	"cookie",
+	"credential",
+	"private_key",
+}
+
+var (
+	urlCredentialsPattern  = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/@\s]+:)([^@\s]+)(@)`)
+	keywordPasswordPattern = regexp.MustCompile(`(?i)(password=)('[^']*'|\S+)`)
+	bearerPattern          = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9\-._~+/]+=*`)
+)
+
+// Redact masks attributes whose key looks sensitive and scrubs credentials
+// embedded in string, error and fmt.Stringer values, such as DSN passwords
+// and bearer tokens. It is installed as the ReplaceAttr hook of every
+// handler created by Init.
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	if isSensitiveKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case http.Header:
+			return slog.Any(a.Key, redactHeader(v))
+		case error:
+			return redactText(a, v.Error())
+		case fmt.Stringer:
+			return redactText(a, v.String())
+		}
+	}
+	return a
+}
+
+// redactText replaces a with its text scrubbed, if that removed anything,
+// so values without credentials keep their own formatting.
+func redactText(a slog.Attr, text string) slog.Attr {
+	if clean := RedactString(text); clean != text {
+		return slog.String(a.Key, clean)
+	}
+	return a
+}
+
+// RedactString removes credentials from free-form text.
+func RedactString(s string) string {
+	s = urlCredentialsPattern.ReplaceAllString(s, "${1}"+redacted+"${3}")
+	s = keywordPasswordPattern.ReplaceAllString(s, "${1}"+redacted)
+	return bearerPattern.ReplaceAllString(s, "${1} "+redacted)
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func redactHeader(h http.Header) http.Header {
+	clean := h.Clone()
+	for name := range clean {
+		if isSensitiveKey(name) {
+			clean[name] = []string{redacted}
+		}
+	}
+	return clean
+}
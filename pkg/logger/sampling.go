@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type sampleKey struct {
+	level   slog.Level
+	message string
+}
+
+type sampler struct {
+	initial    int
+	thereafter int
+	window     time.Duration
+
+	mu      sync.Mutex
+	resetAt time.Time
+	counts  map[sampleKey]int
+}
+
+func (s *sampler) allow(r slog.Record) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.After(s.resetAt) {
+		s.counts = make(map[sampleKey]int)
+		s.resetAt = now.Add(s.window)
+	}
+
+	key := sampleKey{level: r.Level, message: r.Message}
+	s.counts[key]++
+	n := s.counts[key]
+
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
+
+// samplingHandler drops repetitive records. Errors are never sampled.
+type samplingHandler struct {
+	slog.Handler
+	sampler *sampler
+}
+
+func newSamplingHandler(next slog.Handler, initial, thereafter int, window time.Duration) slog.Handler {
+	if window <= 0 {
+		window = time.Second
+	}
+	return &samplingHandler{
+		Handler: next,
+		sampler: &sampler{initial: initial, thereafter: thereafter, window: window},
+	}
+}
+
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelError && !h.sampler.allow(r) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: h.Handler.WithGroup(name), sampler: h.sampler}
+}
@@ -0,0 +1,101 @@
+package request
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+)
+
+type decodeOptions struct {
+	disallowUnknownFields bool
+}
+
+type DecodeOption func(*decodeOptions)
+
+// DisallowUnknownFields rejects bodies with fields the target doesn't have.
+func DisallowUnknownFields() DecodeOption {
+	return func(o *decodeOptions) { o.disallowUnknownFields = true }
+}
+
+// DecodeJSON decodes a request body holding exactly one JSON object into dst.
+// The returned error is an *errors.APIError ready for response.JSONError:
+// 415 for other media types, 413 when the body exceeds the limit set by
+// LimitBody and 400 with the byte offset of the problem otherwise.
+func DecodeJSON(r *http.Request, dst interface{}, opts ...DecodeOption) error {
+	var o decodeOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if !isJSON(r.Header.Get("Content-Type")) {
+		return errors.UnsupportedMediaTypeError(errors.ErrUnsupportedMediaType, "Content-Type must be application/json")
+	}
+
+	dec := json.NewDecoder(r.Body)
+	if o.disallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+
+	// Anything after the first value, even another object, is rejected
+	end := dec.InputOffset()
+	if err := dec.Decode(&struct{}{}); !stderrors.Is(err, io.EOF) {
+		var maxErr *http.MaxBytesError
+		if stderrors.As(err, &maxErr) {
+			return decodeError(err)
+		}
+		return errors.BadRequestError(errors.ErrBadRequest,
+			fmt.Sprintf("request body must contain a single JSON object, unexpected data after byte offset %d", end))
+	}
+	return nil
+}
+
+func decodeError(err error) error {
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+		maxErr    *http.MaxBytesError
+	)
+
+	switch {
+	case stderrors.As(err, &maxErr):
+		return errors.PayloadTooLargeError(errors.ErrPayloadTooLarge,
+			fmt.Sprintf("request body must not exceed %d bytes", maxErr.Limit))
+	case stderrors.As(err, &syntaxErr):
+		return errors.BadRequestError(errors.ErrBadRequest,
+			fmt.Sprintf("malformed JSON at byte offset %d: %s", syntaxErr.Offset, strings.TrimPrefix(syntaxErr.Error(), "json: ")))
+	case stderrors.Is(err, io.ErrUnexpectedEOF):
+		return errors.BadRequestError(errors.ErrBadRequest, "malformed JSON: body ended unexpectedly")
+	case stderrors.Is(err, io.EOF):
+		return errors.BadRequestError(errors.ErrBadRequest, "request body must not be empty")
+	case stderrors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return errors.BadRequestError(errors.ErrBadRequest, "request body must be a JSON object")
+		}
+		return errors.BadRequestError(errors.ErrBadRequest,
+			fmt.Sprintf("field %q must be %s, got %s at byte offset %d", typeErr.Field, typeErr.Type, typeErr.Value, typeErr.Offset))
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json has no typed error for unknown fields
+		return errors.BadRequestError(errors.ErrBadRequest, "unknown field "+strings.TrimPrefix(err.Error(), "json: unknown field "))
+	default:
+		return errors.BadRequestError(errors.ErrBadRequest, err.Error())
+	}
+}
+
+// isJSON accepts application/json and structured +json types.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
@@ -0,0 +1,80 @@
+package request
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+)
+
+// BodyLimit overrides the default body size for requests matching Methods
+// and Path. Path is exact or a prefix ending in "*".
+type BodyLimit struct {
+	Path     string   `json:"path" yaml:"path"`
+	Methods  []string `json:"methods,omitempty" yaml:"methods,omitempty"`
+	MaxBytes int64    `json:"max_bytes" yaml:"max_bytes"`
+}
+
+func (l BodyLimit) matches(method, path string) bool {
+	if len(l.Methods) > 0 {
+		found := false
+		for _, m := range l.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if strings.HasSuffix(l.Path, "*") {
+		return strings.HasPrefix(path, strings.TrimSuffix(l.Path, "*"))
+	}
+	return path == l.Path
+}
+
+// LimitOptions are read on every request so reloaded settings apply
+// immediately. A MaxBytes of 0 means unlimited.
+type LimitOptions struct {
+	MaxBytes int64
+	Routes   []BodyLimit
+}
+
+// MaxBytesFor returns the limit for a request. The longest matching route
+// wins.
+func (o LimitOptions) MaxBytesFor(method, path string) int64 {
+	limit, longest := o.MaxBytes, -1
+	for _, l := range o.Routes {
+		if l.matches(method, path) && len(l.Path) > longest {
+			limit, longest = l.MaxBytes, len(l.Path)
+		}
+	}
+	return limit
+}
+
+// LimitBody caps request bodies. Requests declaring a larger Content-Length
+// are rejected with 413 up front; others fail with 413 once reading passes
+// the limit.
+func LimitBody(options func() LimitOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := options().MaxBytesFor(r.Method, r.URL.Path)
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > limit {
+				response.JSONError(w, errors.PayloadTooLargeError(errors.ErrPayloadTooLarge,
+					fmt.Sprintf("request body must not exceed %d bytes", limit)))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
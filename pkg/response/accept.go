@@ -0,0 +1,40 @@
+package response
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type acceptItem struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses Accept and Accept-Encoding style headers, dropping
+// media type parameters other than q. Items are sorted by descending q,
+// keeping header order for ties.
+func parseAccept(header string) []acceptItem {
+	var items []acceptItem
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(fields[0]))
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			k, v, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(k, "q") {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					q = f
+				}
+			}
+		}
+		items = append(items, acceptItem{value: value, q: q})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	return items
+}
@@ -0,0 +1,183 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatMsgPack = "msgpack"
+	FormatCBOR    = "cbor"
+	FormatYAML    = "yaml"
+)
+
+type codec struct {
+	format string
+	// mediaTypes lists accepted media types, the first is sent back
+	mediaTypes []string
+	marshal    func(v interface{}) ([]byte, error)
+}
+
+var jsonCodec = &codec{
+	format:     FormatJSON,
+	mediaTypes: []string{"application/json"},
+	marshal: func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		err := json.NewEncoder(&buf).Encode(v)
+		return buf.Bytes(), err
+	},
+}
+
+// codecs are tried in order when the client accepts several formats equally.
+// Binary formats use the json struct tags so field names match everywhere.
+var codecs = []*codec{
+	jsonCodec,
+	{
+		format:     FormatMsgPack,
+		mediaTypes: []string{"application/msgpack", "application/vnd.msgpack", "application/x-msgpack"},
+		marshal: func(v interface{}) ([]byte, error) {
+			var buf bytes.Buffer
+			enc := msgpack.NewEncoder(&buf)
+			enc.SetCustomStructTag("json")
+			enc.SetOmitEmpty(true)
+			err := enc.Encode(v)
+			return buf.Bytes(), err
+		},
+	},
+	{
+		format:     FormatCBOR,
+		mediaTypes: []string{"application/cbor"},
+		marshal:    cbor.Marshal,
+	},
+	{
+		format:     FormatYAML,
+		mediaTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"},
+		marshal:    marshalYAML,
+	},
+}
+
+// marshalYAML goes through JSON so the output follows the json struct tags
+// and MarshalJSON methods, matching the JSON representation.
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(generic)
+}
+
+// negotiatedWriter carries the codec chosen by Negotiate to the response
+// helpers.
+type negotiatedWriter struct {
+	http.ResponseWriter
+	codec     *codec
+	mediaType string
+}
+
+func (w *negotiatedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *negotiatedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Negotiate picks the response format from the Accept header among the
+// enabled formats. JSON is always available and used when nothing else
+// matches, so clients that send unrelated Accept headers keep working.
+func Negotiate(formats func() []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept")
+			c, mediaType := negotiate(r.Header.Get("Accept"), formats())
+			next.ServeHTTP(&negotiatedWriter{ResponseWriter: w, codec: c, mediaType: mediaType}, r)
+		})
+	}
+}
+
+func negotiate(accept string, formats []string) (*codec, string) {
+	enabled := []*codec{jsonCodec}
+	for _, c := range codecs[1:] {
+		for _, f := range formats {
+			if f == c.format {
+				enabled = append(enabled, c)
+			}
+		}
+	}
+
+	for _, item := range parseAccept(accept) {
+		if item.q <= 0 {
+			continue
+		}
+		for _, c := range enabled {
+			for _, mt := range c.mediaTypes {
+				if mediaTypeMatches(item.value, mt) {
+					// Echo the exact type asked for, not a wildcard
+					if strings.Contains(item.value, "*") {
+						return c, c.mediaTypes[0]
+					}
+					return c, mt
+				}
+			}
+		}
+	}
+	return jsonCodec, jsonCodec.mediaTypes[0]
+}
+
+// mediaTypeMatches supports one wildcard, e.g. "*/*", "text/*" or
+// "application/*+json".
+func mediaTypeMatches(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	return ok && len(mediaType) >= len(prefix)+len(suffix) &&
+		strings.HasPrefix(mediaType, prefix) && strings.HasSuffix(mediaType, suffix)
+}
+
+// codecFor finds the codec chosen by Negotiate, looking through writers
+// wrapped by later middleware.
+func codecFor(w http.ResponseWriter) (*codec, string) {
+	for {
+		switch rw := w.(type) {
+		case *negotiatedWriter:
+			return rw.codec, rw.mediaType
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return jsonCodec, jsonCodec.mediaTypes[0]
+		}
+	}
+}
+
+// write encodes v in the negotiated format, falling back to JSON when the
+// value can't be represented in it.
+func write(w http.ResponseWriter, statusCode int, v interface{}) {
+	c, mediaType := codecFor(w)
+	body, err := c.marshal(v)
+	if err != nil && c != jsonCodec {
+		c, mediaType = jsonCodec, jsonCodec.mediaTypes[0]
+		body, err = c.marshal(v)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
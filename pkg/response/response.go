@@ -1,10 +1,10 @@
 package response
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/ctrixcode/go-chi-postgres/pkg/errors"
+	"github.com/ctrixcode/go-chi-postgres/pkg/requestid"
 )
 
 type SuccessResponse struct {
@@ -14,17 +14,16 @@ type SuccessResponse struct {
 }
 
 type ErrorResponse struct {
-	Success bool        `json:"success"`
-	Code    string      `json:"code,omitempty"`
-	Message string      `json:"message,omitempty"`
-	Details interface{} `json:"details,omitempty"`
+	Success   bool        `json:"success"`
+	Code      string      `json:"code,omitempty"`
+	Message   string      `json:"message,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
-// JSONSuccess sends a success JSON response.
+// JSONSuccess sends a success response. The body is JSON unless Negotiate
+// picked another format from the Accept header.
 func JSONSuccess(w http.ResponseWriter, data interface{}, statusCode int, message ...string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-
 	resp := SuccessResponse{
 		Success: true,
 		Data:    data,
@@ -33,25 +32,24 @@ func JSONSuccess(w http.ResponseWriter, data interface{}, statusCode int, messag
 		resp.Message = message[0]
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	write(w, statusCode, resp)
 }
 
+// JSONError sends an error response in the same format as JSONSuccess.
 func JSONError(w http.ResponseWriter, err error) {
-	w.Header().Set("Content-Type", "application/json")
-
 	apiErr, ok := err.(*errors.APIError)
 	if !ok {
 		apiErr = errors.InternalServerError(errors.ErrInternalServerError)
 	}
 
-	w.WriteHeader(apiErr.StatusCode)
-
+	// The request ID header is set by the middleware before the handler runs
 	resp := ErrorResponse{
-		Success: false,
-		Code:    apiErr.Type,
-		Message: apiErr.Message,
-		Details: apiErr.Details,
+		Success:   false,
+		Code:      apiErr.Type,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		RequestID: w.Header().Get(requestid.Header),
 	}
 
-	json.NewEncoder(w).Encode(resp)
+	write(w, apiErr.StatusCode, resp)
 }
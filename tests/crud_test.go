@@ -0,0 +1,102 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Masterminds/squirrel"
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newExampleCRUD(t *testing.T) (*database.CRUDRepository[models.Example, uuid.UUID], sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, mock.ExpectationsWereMet()) })
+	return database.NewCRUDRepository[models.Example, uuid.UUID](sqlx.NewDb(sqlDB, "sqlmock"), "examples"), mock
+}
+
+func TestCRUDRepositoryColumnsFromTags(t *testing.T) {
+	repo, _ := newExampleCRUD(t)
+	assert.Equal(t, []string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}, repo.Columns())
+
+	type account struct {
+		Email string `db:"email,pk"`
+		Notes string `db:"-"`
+	}
+	accounts := database.NewCRUDRepository[account, string](nil, "accounts")
+	assert.Equal(t, []string{"email"}, accounts.Columns())
+
+	assert.Panics(t, func() { database.NewCRUDRepository[struct{ Name string }, int](nil, "names") })
+}
+
+func TestCRUDRepositoryListWithFilter(t *testing.T) {
+	repo, mock := newExampleCRUD(t)
+
+	mock.ExpectQuery("SELECT id, name, lucky_number, is_premium, created_at, updated_at FROM examples WHERE is_premium = $1 ORDER BY created_at DESC LIMIT 5 OFFSET 10").
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows(repo.Columns()).AddRow(uuid.New(), "Premium", 7.0, true, time.Now(), time.Now()))
+
+	rows, err := repo.List(context.Background(), database.Query{
+		Filter:  squirrel.Eq{"is_premium": true},
+		OrderBy: []string{"created_at DESC"},
+		Limit:   5,
+		Offset:  10,
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Premium", rows[0].Name)
+}
+
+func TestCRUDRepositoryCountAndExists(t *testing.T) {
+	repo, mock := newExampleCRUD(t)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT COUNT(*) FROM examples WHERE lucky_number > $1").
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	n, err := repo.Count(ctx, squirrel.Gt{"lucky_number": 10})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), n)
+
+	mock.ExpectQuery("SELECT EXISTS( SELECT 1 FROM examples WHERE name = $1 )").
+		WithArgs("taken").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	exists, err := repo.Exists(ctx, squirrel.Eq{"name": "taken"})
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestCRUDRepositoryUpdateAndNotFound(t *testing.T) {
+	repo, mock := newExampleCRUD(t)
+	ctx := context.Background()
+	id := uuid.New()
+
+	// updated_at is set first, then columns in struct order
+	mock.ExpectQuery("UPDATE examples SET updated_at = $1, name = $2, is_premium = $3 WHERE id = $4 RETURNING id, name, lucky_number, is_premium, created_at, updated_at").
+		WithArgs(sqlmock.AnyArg(), "Renamed", false, id).
+		WillReturnRows(sqlmock.NewRows(repo.Columns()).AddRow(id, "Renamed", 1.0, false, time.Now(), time.Now()))
+	updated, err := repo.Update(ctx, id, map[string]interface{}{"is_premium": false, "name": "Renamed"})
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Name)
+
+	_, err = repo.Update(ctx, id, map[string]interface{}{"colour": "red"})
+	assert.ErrorContains(t, err, `unknown column "colour"`)
+
+	mock.ExpectQuery("SELECT id, name, lucky_number, is_premium, created_at, updated_at FROM examples WHERE id = $1").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(repo.Columns()))
+	_, err = repo.GetByID(ctx, id)
+	assert.ErrorIs(t, err, database.ErrNotFound)
+
+	mock.ExpectExec("DELETE FROM examples WHERE id = $1").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Delete(ctx, id), database.ErrNotFound)
+}
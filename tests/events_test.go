@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/pkg/requestid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var outboxColumns = []string{"id", "type", "aggregate_type", "aggregate_id", "payload", "request_id", "attempts",
+	"last_error", "next_attempt_at", "dispatched_at", "failed_at", "created_at"}
+
+// outboxRow returns a pending event due now.
+func outboxRow(id int64, eventType string, attempts int) []driver.Value {
+	now := time.Now()
+	return []driver.Value{id, eventType, models.ExampleAggregate, "a1", []byte(`{"id":"a1"}`), "req-1", attempts,
+		nil, now.Add(-time.Second), nil, nil, now}
+}
+
+func TestTransactorCommitsAndRollsBack(t *testing.T) {
+	db, mock := newJobsMock(t)
+	tx := database.NewTransactor(db)
+	outbox := events.NewOutbox(db)
+	event, err := events.New(models.EventExampleDeleted, models.ExampleAggregate, "a1", map[string]string{"id": "a1"})
+	require.NoError(t, err)
+
+	// A nested call joins the outer transaction
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO outbox").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO outbox").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectCommit()
+	err = tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := outbox.Publish(ctx, event); err != nil {
+			return err
+		}
+		return tx.WithinTx(ctx, func(ctx context.Context) error {
+			return outbox.Publish(ctx, event)
+		})
+	})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO outbox").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectRollback()
+	err = tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := outbox.Publish(ctx, event); err != nil {
+			return err
+		}
+		return errors.New("change rejected")
+	})
+	assert.EqualError(t, err, "change rejected")
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	assert.Panics(t, func() {
+		tx.WithinTx(context.Background(), func(ctx context.Context) error { panic("boom") })
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxPublish(t *testing.T) {
+	db, mock := newJobsMock(t)
+	outbox := events.NewOutbox(db)
+	event, err := events.New(models.EventExampleCreated, models.ExampleAggregate, "a1", map[string]string{"name": "Test"})
+	require.NoError(t, err)
+
+	mock.ExpectQuery("INSERT INTO outbox").
+		WithArgs(models.EventExampleCreated, models.ExampleAggregate, "a1", []byte(`{"name":"Test"}`), "req-1", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	require.NoError(t, outbox.Publish(ctx, event))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSubscribers(t *testing.T) {
+	subscribers := events.NewSubscribers()
+	var got []string
+	record := func(name string) events.Handler {
+		return func(ctx context.Context, e events.Event) error {
+			got = append(got, name)
+			return nil
+		}
+	}
+	subscribers.Subscribe(models.EventExampleCreated, record("created"))
+	subscribers.Subscribe("example.*", record("example"))
+	subscribers.Subscribe("*", record("all"))
+	subscribers.Subscribe("order.*", record("order"))
+	subscribers.Subscribe("*", func(ctx context.Context, e events.Event) error { panic("boom") })
+
+	err := subscribers.Deliver(context.Background(), events.Event{Type: models.EventExampleCreated})
+	assert.Equal(t, []string{"created", "example", "all"}, got)
+	assert.ErrorContains(t, err, "subscriber *: panic: boom")
+}
+
+func TestWebhookSink(t *testing.T) {
+	status := http.StatusNoContent
+	var received events.Event
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		json.NewDecoder(r.Body).Decode(&received)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sink, err := events.NewWebhookSink(srv.URL, srv.Client())
+	require.NoError(t, err)
+	event := events.Event{ID: 7, Type: models.EventExampleUpdated, AggregateID: "a1", Payload: json.RawMessage(`{"id":"a1"}`)}
+
+	require.NoError(t, sink.Deliver(context.Background(), event))
+	assert.Equal(t, "7", header.Get("X-Event-ID"))
+	assert.Equal(t, models.EventExampleUpdated, header.Get("X-Event-Type"))
+	assert.Equal(t, "a1", received.AggregateID)
+	assert.JSONEq(t, `{"id":"a1"}`, string(received.Payload))
+
+	status = http.StatusServiceUnavailable
+	assert.EqualError(t, sink.Deliver(context.Background(), event), "webhook responded 503 Service Unavailable")
+
+	_, err = events.NewWebhookSink("ftp://example.com", http.DefaultClient)
+	assert.Error(t, err)
+}
+
+var testRelayOptions = events.Options{
+	PollInterval: time.Hour,
+	BatchSize:    10,
+	MaxAttempts:  3,
+	BackoffBase:  time.Second,
+	BackoffMax:   time.Minute,
+}
+
+// relayTo runs one batch of rows through a relay delivering to handler and
+// shuts it down.
+func relayTo(t *testing.T, db *sqlx.DB, mock sqlmock.Sqlmock, handler events.Handler) {
+	subscribers := events.NewSubscribers()
+	subscribers.Subscribe("*", handler)
+	relay := events.NewRelay(db, []events.Sink{subscribers}, func() events.Options { return testRelayOptions }, metrics.New())
+	relay.Start()
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, relay.Shutdown(ctx))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectBatch expects the relay to lock the outbox and read rows.
+func expectBatch(mock sqlmock.Sqlmock, rows ...[]driver.Value) {
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(true))
+	result := sqlmock.NewRows(outboxColumns)
+	for _, row := range rows {
+		result.AddRow(row...)
+	}
+	mock.ExpectQuery("SELECT (.+) FROM outbox").WithArgs(int64(10)).WillReturnRows(result)
+}
+
+// expectUnlock expects the relay to release the outbox after a batch.
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("SELECT pg_advisory_unlock").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestRelayDeliversInOrder(t *testing.T) {
+	db, mock := newJobsMock(t)
+	expectBatch(mock, outboxRow(1, models.EventExampleCreated, 0), outboxRow(2, models.EventExampleUpdated, 0))
+	var recorded atomic.Bool
+	mock.ExpectExec("UPDATE outbox SET dispatched_at").
+		WithArgs(sqlmock.AnyArg(), argFunc(func(v driver.Value) bool {
+			recorded.Store(true)
+			return v == int64(1)
+		})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE outbox SET dispatched_at").WithArgs(sqlmock.AnyArg(), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlock(mock)
+
+	var mu sync.Mutex
+	var got []int64
+	relayTo(t, db, mock, func(ctx context.Context, e events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, e.ID)
+		assert.Equal(t, "req-1", requestid.FromContext(ctx))
+		// Each event is recorded as soon as it is delivered
+		assert.Equal(t, e.ID == 2, recorded.Load())
+		return nil
+	})
+	assert.Equal(t, []int64{1, 2}, got)
+}
+
+func TestRelayRetryHoldsBackLaterEvents(t *testing.T) {
+	db, mock := newJobsMock(t)
+	start := time.Now()
+	expectBatch(mock, outboxRow(1, models.EventExampleCreated, 1), outboxRow(2, models.EventExampleUpdated, 0))
+	// The second attempt waits twice the base backoff, less jitter
+	mock.ExpectExec("UPDATE outbox SET attempts = attempts \\+ 1, last_error").
+		WithArgs("subscribers: subscriber *: unavailable", argFunc(func(v driver.Value) bool {
+			t, ok := v.(time.Time)
+			return ok && t.After(start.Add(1800*time.Millisecond)) && t.Before(time.Now().Add(2*time.Second))
+		}), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlock(mock)
+
+	var mu sync.Mutex
+	var got []int64
+	relayTo(t, db, mock, func(ctx context.Context, e events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, e.ID)
+		return errors.New("unavailable")
+	})
+	assert.Equal(t, []int64{1}, got)
+}
+
+func TestRelayFailsEventOutOfAttempts(t *testing.T) {
+	db, mock := newJobsMock(t)
+	expectBatch(mock, outboxRow(1, models.EventExampleCreated, 2), outboxRow(2, models.EventExampleUpdated, 0))
+	mock.ExpectExec("UPDATE outbox SET attempts = attempts \\+ 1, last_error = \\$1::text, failed_at").
+		WithArgs("subscribers: subscriber *: rejected", sqlmock.AnyArg(), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE outbox SET dispatched_at").WithArgs(sqlmock.AnyArg(), int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlock(mock)
+
+	relayTo(t, db, mock, func(ctx context.Context, e events.Event) error {
+		if e.ID == 1 {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+}
+
+func TestRelayWaitsForLock(t *testing.T) {
+	db, mock := newJobsMock(t)
+	// Another replica is relaying
+	mock.ExpectQuery("SELECT pg_try_advisory_lock").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(false))
+
+	relayTo(t, db, mock, func(ctx context.Context, e events.Event) error {
+		t.Error("event delivered without the lock")
+		return nil
+	})
+}
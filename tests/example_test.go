@@ -30,9 +30,11 @@ func TestCreateExample(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}).
 		AddRow(uuid.New(), "Test Example", 42.0, true, time.Now(), time.Now())
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(`INSERT INTO examples`).
 		WithArgs("Test Example", 42.0, true).
 		WillReturnRows(rows)
+	expectExampleEvent(mock, models.EventExampleCreated)
 
 	// Create Request
 	req, _ := http.NewRequest("POST", "/examples/", bytes.NewBuffer(body))
@@ -59,7 +61,7 @@ func TestListExamples(t *testing.T) {
 		AddRow(uuid.New(), "Example 1", 10.0, true, time.Now(), time.Now()).
 		AddRow(uuid.New(), "Example 2", 20.0, false, time.Now(), time.Now())
 
-	mock.ExpectQuery(`SELECT \* FROM examples`).
+	mock.ExpectQuery(`SELECT (.+) FROM examples`).
 		WillReturnRows(rows)
 
 	// Create Request
@@ -96,7 +98,7 @@ func TestGetExample(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}).
 		AddRow(testID, "Test Example", 42.0, true, time.Now(), time.Now())
 
-	mock.ExpectQuery(`SELECT \* FROM examples`).
+	mock.ExpectQuery(`SELECT (.+) FROM examples`).
 		WithArgs(testID).
 		WillReturnRows(rows)
 
@@ -130,9 +132,11 @@ func TestUpdateExample(t *testing.T) {
 	rows := sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}).
 		AddRow(testID, "Updated Example", 42.0, true, time.Now(), time.Now())
 
+	mock.ExpectBegin()
 	mock.ExpectQuery(`UPDATE examples`).
 		WithArgs(sqlmock.AnyArg(), "Updated Example", testID).
 		WillReturnRows(rows)
+	expectExampleEvent(mock, models.EventExampleUpdated)
 
 	// Create Request
 	req, _ := http.NewRequest("PUT", "/examples/"+testID.String(), bytes.NewBuffer(body))
@@ -157,9 +161,11 @@ func TestDeleteExample(t *testing.T) {
 	testID := uuid.New()
 
 	// Set up mock expectations
+	mock.ExpectBegin()
 	mock.ExpectExec(`DELETE FROM examples`).
 		WithArgs(testID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectExampleEvent(mock, models.EventExampleDeleted)
 
 	// Create Request
 	req, _ := http.NewRequest("DELETE", "/examples/"+testID.String(), nil)
@@ -0,0 +1,223 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var exampleColumns = []string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}
+
+type graphQLResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string                 `json:"message"`
+		Extensions map[string]interface{} `json:"extensions"`
+	} `json:"errors"`
+}
+
+func postGraphQL(t *testing.T, handler http.Handler, query string, variables map[string]interface{}) (int, graphQLResponse) {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var resp graphQLResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), rr.Body.String())
+	return rr.Code, resp
+}
+
+// newGraphQLServer is NewTestServer with GraphQL limits.
+func newGraphQLServer(graphQL config.GraphQLConfig) (http.Handler, sqlmock.Sqlmock) {
+	s, mock := NewTestServerWithConfig(&config.Config{
+		Port:    8080,
+		OpenAPI: config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: openapi.ResponsesStrict},
+		GraphQL: graphQL,
+	})
+	return s.RegisterRoutes(), mock
+}
+
+func TestGraphQLListsFilteredSortedExamples(t *testing.T) {
+	handler, mock := newGraphQLServer(config.GraphQLConfig{MaxDepth: 10, MaxComplexity: 1000})
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	id := uuid.New()
+	mock.ExpectQuery(`SELECT (.+) FROM examples WHERE \(is_premium = \$1 AND lucky_number >= \$2\) ORDER BY lucky_number DESC, id LIMIT 5 OFFSET 10`).
+		WithArgs(true, 7.0).
+		WillReturnRows(sqlmock.NewRows(exampleColumns).AddRow(id, "Lucky", 42.0, true, created, created))
+
+	status, resp := postGraphQL(t, handler, `query($filter: ExampleFilter) {
+		examples(filter: $filter, sort: {field: LUCKY_NUMBER, descending: true}, limit: 5, offset: 10) {
+			id name luckyNumber isPremium createdAt
+		}
+	}`, map[string]interface{}{"filter": map[string]interface{}{"isPremium": true, "minLuckyNumber": 7}})
+
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `[{"id": "`+id.String()+`", "name": "Lucky", "luckyNumber": 42, "isPremium": true, "createdAt": "2026-10-19T12:00:00Z"}]`,
+		string(resp.Data["examples"]))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraphQLBatchesExampleLookups(t *testing.T) {
+	handler, mock := newGraphQLServer(config.GraphQLConfig{})
+	a, b, missing := uuid.New(), uuid.New(), uuid.New()
+	now := time.Now()
+	// One query for the three fields, with the ids in field resolution order
+	anyID := argFunc(func(v driver.Value) bool {
+		return v == a.String() || v == b.String() || v == missing.String()
+	})
+	mock.ExpectQuery(`SELECT (.+) FROM examples WHERE id IN \(\$1,\$2,\$3\)`).
+		WithArgs(anyID, anyID, anyID).
+		WillReturnRows(sqlmock.NewRows(exampleColumns).
+			AddRow(b, "Second", 2.0, false, now, now).
+			AddRow(a, "First", 1.0, true, now, now))
+
+	status, resp := postGraphQL(t, handler, `query($a: ID!, $b: ID!, $missing: ID!) {
+		a: example(id: $a) { name }
+		b: example(id: $b) { name }
+		missing: example(id: $missing) { name }
+		again: example(id: $a) { isPremium }
+	}`, map[string]interface{}{"a": a, "b": b, "missing": missing})
+
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"name": "First"}`, string(resp.Data["a"]))
+	assert.JSONEq(t, `{"name": "Second"}`, string(resp.Data["b"]))
+	assert.JSONEq(t, `null`, string(resp.Data["missing"]))
+	assert.JSONEq(t, `{"isPremium": true}`, string(resp.Data["again"]))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraphQLLookupBesideListUsesListedExample(t *testing.T) {
+	handler, mock := newGraphQLServer(config.GraphQLConfig{})
+	id := uuid.New()
+	now := time.Now()
+	// Sibling fields resolve in map order, so cover both orders
+	for i := 0; i < 20; i++ {
+		mock.ExpectQuery(`SELECT (.+) FROM examples ORDER BY`).
+			WillReturnRows(sqlmock.NewRows(exampleColumns).AddRow(id, "Listed", 1.0, false, now, now))
+
+		status, resp := postGraphQL(t, handler, `query($id: ID!) {
+			a: example(id: $id) { name }
+			examples { id }
+		}`, map[string]interface{}{"id": id})
+
+		require.Equal(t, http.StatusOK, status)
+		require.Empty(t, resp.Errors)
+		assert.JSONEq(t, `{"name": "Listed"}`, string(resp.Data["a"]))
+		assert.JSONEq(t, `[{"id": "`+id.String()+`"}]`, string(resp.Data["examples"]))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraphQLEnforcesLimits(t *testing.T) {
+	handler, mock := newGraphQLServer(config.GraphQLConfig{MaxDepth: 1, MaxComplexity: 100})
+
+	// Nothing reaches the database
+	status, resp := postGraphQL(t, handler, `{ examples { id } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "QUERY_TOO_COMPLEX", resp.Errors[0].Extensions["code"])
+	assert.Contains(t, resp.Errors[0].Message, "depth 2")
+
+	handler, _ = newGraphQLServer(config.GraphQLConfig{MaxDepth: 10, MaxComplexity: 100})
+	status, resp = postGraphQL(t, handler, `query($n: Int) { examples(limit: $n) { ...fields } } fragment fields on Example { id name }`,
+		map[string]interface{}{"n": 50})
+	assert.Equal(t, http.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "complexity 101")
+
+	// A variable left to its default is costed at the default
+	status, resp = postGraphQL(t, handler, `query($n: Int = 50) { examples(limit: $n) { id name } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "complexity 101")
+
+	// One that cannot be told counts as the largest limit
+	status, resp = postGraphQL(t, handler, `query($n: Int = 5) { examples(limit: $n) { id } }`, map[string]interface{}{"n": nil})
+	assert.Equal(t, http.StatusBadRequest, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Contains(t, resp.Errors[0].Message, "complexity 101")
+
+	// Introspection is free
+	status, resp = postGraphQL(t, handler, `{ __schema { types { name fields { name type { name ofType { name ofType { name } } } } } } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Empty(t, resp.Errors)
+
+	status, resp = postGraphQL(t, handler, `{ examples { nope } }`, nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.NotEmpty(t, resp.Errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGraphQLMutations(t *testing.T) {
+	handler, mock := newGraphQLServer(config.GraphQLConfig{})
+	id := uuid.New()
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO examples`).
+		WithArgs("Created", 7.0, false).
+		WillReturnRows(sqlmock.NewRows(exampleColumns).AddRow(id, "Created", 7.0, false, now, now))
+	expectExampleEvent(mock, models.EventExampleCreated)
+
+	status, resp := postGraphQL(t, handler, `mutation { createExample(input: {name: "Created", luckyNumber: 7}) { id name isPremium } }`, nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Empty(t, resp.Errors)
+	assert.JSONEq(t, `{"id": "`+id.String()+`", "name": "Created", "isPremium": false}`, string(resp.Data["createExample"]))
+
+	// Inputs are validated like the REST body
+	status, resp = postGraphQL(t, handler, `mutation { createExample(input: {name: "ab", luckyNumber: 7}) { id } }`, nil)
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "VALIDATION_FAILED", resp.Errors[0].Extensions["code"])
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM examples`).WithArgs(id).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	status, resp = postGraphQL(t, handler, `mutation($id: ID!) { deleteExample(id: $id) }`, map[string]interface{}{"id": id})
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, resp.Errors, 1)
+	assert.Equal(t, "NOT_FOUND", resp.Errors[0].Extensions["code"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Mutations are not run from GET requests
+	req := httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape(`mutation { deleteExample(id: "`+id.String()+`") }`), nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestGraphiQLServedWhenEnabled(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		handler, _ := newGraphQLServer(config.GraphQLConfig{GraphiQL: enabled})
+		req := httptest.NewRequest(http.MethodGet, "/graphql", nil)
+		req.Header.Set("Accept", "text/html")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if enabled {
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Contains(t, rr.Body.String(), "graphiql")
+		} else {
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		}
+	}
+}
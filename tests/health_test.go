@@ -1,11 +1,22 @@
 package tests
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/health"
+	"github.com/ctrixcode/go-chi-postgres/internal/server"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHealthCheck(t *testing.T) {
@@ -21,5 +32,108 @@ func TestHealthCheck(t *testing.T) {
 
 	// Assertions
 	assert.Equal(t, http.StatusOK, rr.Code)
-	assert.Equal(t, "health check", rr.Body.String())
+
+	var response map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "up", response["data"].(map[string]interface{})["status"])
+}
+
+func TestHealthEndpointReportsDatabaseDown(t *testing.T) {
+	sqlDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	s := server.NewServer(&config.Config{Port: 8080}, &mockDB{db: sqlx.NewDb(sqlDB, "sqlmock"), down: true})
+
+	req, _ := http.NewRequest("GET", "/health", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "down", body["details"].(map[string]interface{})["status"])
+}
+
+func TestLivenessProbe(t *testing.T) {
+	s, _ := NewTestServer()
+
+	req, _ := http.NewRequest("GET", "/healthz/live", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+}
+
+func TestReadinessProbe(t *testing.T) {
+	s, _ := NewTestServer()
+
+	req, _ := http.NewRequest("GET", "/healthz/ready", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	checks := response["data"].(map[string]interface{})["checks"].(map[string]interface{})
+	assert.Equal(t, "pass", checks["database"].(map[string]interface{})["status"])
+}
+
+func TestReadinessFailsDuringShutdown(t *testing.T) {
+	s, _ := NewTestServer()
+	s.Health().SetShuttingDown()
+
+	req, _ := http.NewRequest("GET", "/healthz/ready", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	// Liveness is unaffected by shutdown
+	req, _ = http.NewRequest("GET", "/healthz/live", nil)
+	rr = httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestCustomCheckFailsStartup(t *testing.T) {
+	s, _ := NewTestServer()
+	s.Health().Register(health.Check{
+		Name:   "cache",
+		Probes: health.Startup,
+		Func: func(ctx context.Context) error {
+			return errors.New("cache not warmed")
+		},
+	})
+
+	req, _ := http.NewRequest("GET", "/healthz/startup", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "cache not warmed")
+}
+
+func TestCheckTimeoutAndCache(t *testing.T) {
+	registry := health.NewRegistry()
+	var calls atomic.Int32
+	registry.Register(health.Check{
+		Name:     "slow",
+		Probes:   health.Readiness,
+		Timeout:  10 * time.Millisecond,
+		CacheTTL: time.Minute,
+		Func: func(ctx context.Context) error {
+			calls.Add(1)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+
+	report := registry.Ready(context.Background())
+	assert.Equal(t, health.StatusFail, report.Status)
+	assert.Contains(t, report.Checks["slow"].Error, "timed out")
+
+	registry.Ready(context.Background())
+	assert.Equal(t, int32(1), calls.Load())
 }
@@ -3,15 +3,21 @@ package tests
 import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
 	"github.com/ctrixcode/go-chi-postgres/internal/server"
 	"github.com/jmoiron/sqlx"
 )
 
 type mockDB struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	down bool
 }
 
 func (m *mockDB) Health() map[string]string {
+	if m.down {
+		return map[string]string{"status": "down", "error": "db down: connection refused"}
+	}
 	return map[string]string{"status": "up"}
 }
 
@@ -26,10 +32,15 @@ func (m *mockDB) GetDB() *sqlx.DB {
 // NewTestServer creates a server and returns both the server and the sqlmock
 // This allows tests to set expectations on the mock
 func NewTestServer() (*server.Server, sqlmock.Sqlmock) {
-	cfg := &config.Config{
+	return NewTestServerWithConfig(&config.Config{
 		Port: 8080,
-	}
+		// Every handler test also checks responses against the OpenAPI document
+		OpenAPI: config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: openapi.ResponsesStrict},
+	})
+}
 
+// NewTestServerWithConfig is like NewTestServer but uses the given config
+func NewTestServerWithConfig(cfg *config.Config) (*server.Server, sqlmock.Sqlmock) {
 	// Create a mock database connection
 	sqlDB, mock, err := sqlmock.New()
 	if err != nil {
@@ -44,3 +55,12 @@ func NewTestServer() (*server.Server, sqlmock.Sqlmock) {
 
 	return server.NewServer(cfg, db), mock
 }
+
+// expectExampleEvent expects a change to an example, expected after Begin,
+// to publish eventType to the outbox and commit.
+func expectExampleEvent(mock sqlmock.Sqlmock, eventType string) {
+	mock.ExpectQuery("INSERT INTO outbox").
+		WithArgs(eventType, models.ExampleAggregate, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
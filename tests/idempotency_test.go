@@ -0,0 +1,185 @@
+package tests
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const createBody = `{"name":"Test Example","lucky_number":42,"is_premium":true}`
+
+func idempotentServer(t *testing.T) (http.Handler, sqlmock.Sqlmock) {
+	s, mock := NewTestServerWithConfig(&config.Config{
+		Port: 8080,
+		Idempotency: config.IdempotencyConfig{
+			Enabled:     true,
+			TTL:         time.Hour,
+			LockTimeout: time.Minute,
+		},
+	})
+	t.Cleanup(func() { assert.NoError(t, mock.ExpectationsWereMet()) })
+	return s.RegisterRoutes(), mock
+}
+
+func postWithKey(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/examples/", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func fingerprint(body string) string {
+	sum := sha256.Sum256([]byte("POST /examples/\n" + body))
+	return hex.EncodeToString(sum[:])
+}
+
+func expectExistingKey(mock sqlmock.Sqlmock, fp string, status interface{}, headers, body []byte) {
+	mock.ExpectQuery("INSERT INTO idempotency_keys").WillReturnRows(sqlmock.NewRows([]string{"locked_until"}))
+	mock.ExpectQuery("SELECT (.+) FROM idempotency_keys").
+		WithArgs(" POST /examples/", "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "response_status", "response_headers", "response_body"}).
+			AddRow(fp, status, headers, body))
+}
+
+func TestIdempotencyStoresFirstResponse(t *testing.T) {
+	handler, mock := idempotentServer(t)
+
+	// Lock and expiry times are on the database clock
+	mock.ExpectQuery(`INSERT INTO idempotency_keys (.+) VALUES \(\$1, \$2, \$3, now\(\) \+ \$4::bigint`).
+		WithArgs(" POST /examples/", "abc", fingerprint(createBody), time.Minute.Milliseconds(), time.Hour.Milliseconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"locked_until"}).AddRow(time.Now().Add(time.Minute)))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO examples").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}).
+			AddRow(uuid.New(), "Test Example", 42.0, true, time.Now(), time.Now()))
+	expectExampleEvent(mock, models.EventExampleCreated)
+	mock.ExpectExec("UPDATE idempotency_keys").
+		WithArgs(" POST /examples/", "abc", http.StatusCreated, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := postWithKey(handler, "abc", createBody)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Empty(t, rr.Header().Get("Idempotent-Replayed"))
+}
+
+func TestIdempotencyReplaysStoredResponse(t *testing.T) {
+	handler, mock := idempotentServer(t)
+
+	headers, _ := json.Marshal(http.Header{"Content-Type": {"application/json"}})
+	expectExistingKey(mock, fingerprint(createBody), http.StatusCreated, headers, []byte(`{"success":true}`))
+
+	rr := postWithKey(handler, "abc", createBody)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"success":true}`, rr.Body.String())
+}
+
+func TestIdempotencyScopesKeysByUser(t *testing.T) {
+	s, mock := NewTestServerWithConfig(&config.Config{
+		Port:        8080,
+		JWTSecret:   socketSecret,
+		Idempotency: config.IdempotencyConfig{Enabled: true, TTL: time.Hour, LockTimeout: time.Minute},
+	})
+
+	// The key is looked up among the user's own
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WithArgs("client POST /examples/", "abc", fingerprint(createBody), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"locked_until"}))
+	mock.ExpectQuery("SELECT (.+) FROM idempotency_keys").
+		WithArgs("client POST /examples/", "abc").
+		WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "response_status", "response_headers", "response_body"}).
+			AddRow(fingerprint(createBody), http.StatusCreated, []byte(`{}`), []byte(`{"success":true}`)))
+
+	req, _ := http.NewRequest("POST", "/examples/", bytes.NewBufferString(createBody))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", "abc")
+	req.Header.Set("Authorization", "Bearer "+signToken(t, socketSecret))
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "true", rr.Header().Get("Idempotent-Replayed"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdempotencyRejectsDifferentBody(t *testing.T) {
+	handler, mock := idempotentServer(t)
+
+	expectExistingKey(mock, fingerprint(`{"name":"Other"}`), http.StatusCreated, []byte(`{}`), nil)
+
+	rr := postWithKey(handler, "abc", createBody)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "IDEMPOTENCY_KEY_REUSED", body["code"])
+}
+
+func TestIdempotencyRejectsInFlightDuplicate(t *testing.T) {
+	handler, mock := idempotentServer(t)
+
+	expectExistingKey(mock, fingerprint(createBody), nil, nil, nil)
+
+	rr := postWithKey(handler, "abc", createBody)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "IDEMPOTENCY_KEY_IN_USE", body["code"])
+}
+
+func TestIdempotencyReleasesKeyOnServerError(t *testing.T) {
+	handler, mock := idempotentServer(t)
+
+	lockedUntil := time.Now().Add(time.Minute)
+	mock.ExpectQuery("INSERT INTO idempotency_keys").
+		WillReturnRows(sqlmock.NewRows([]string{"locked_until"}).AddRow(lockedUntil))
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO examples").WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	// Only this request's claim is released, not one a retry took over
+	// after it expired
+	mock.ExpectExec("DELETE FROM idempotency_keys").
+		WithArgs(" POST /examples/", "abc", fingerprint(createBody), lockedUntil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	rr := postWithKey(handler, "abc", createBody)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestIdempotencyIgnoresRequestsWithoutKey(t *testing.T) {
+	handler, mock := idempotentServer(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO examples").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}).
+			AddRow(uuid.New(), "Test Example", 42.0, true, time.Now(), time.Now()))
+	expectExampleEvent(mock, models.EventExampleCreated)
+
+	req, _ := http.NewRequest("POST", "/examples/", bytes.NewBufferString(createBody))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+}
@@ -0,0 +1,299 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sendEmailArgs struct {
+	To string `json:"to"`
+}
+
+func (sendEmailArgs) Kind() string { return "send_email" }
+
+type resizeImageArgs struct {
+	Path string `json:"path"`
+}
+
+func (resizeImageArgs) Kind() string { return "resize_image" }
+
+// jobArgsConverter passes the []string arguments pgx accepts for text[]
+// through to sqlmock.
+type jobArgsConverter struct{}
+
+func (jobArgsConverter) ConvertValue(v interface{}) (driver.Value, error) {
+	if s, ok := v.([]string); ok {
+		return s, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+// argFunc matches a query argument with a predicate.
+type argFunc func(driver.Value) bool
+
+func (f argFunc) Match(v driver.Value) bool { return f(v) }
+
+var jobColumns = []string{"id", "kind", "queue", "payload", "priority", "state", "attempts", "max_attempts", "run_at",
+	"unique_key", "locked_by", "locked_until", "last_error", "created_at", "updated_at", "finished_at"}
+
+func newJobsMock(t *testing.T) (*sqlx.DB, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New(sqlmock.ValueConverterOption(jobArgsConverter{}))
+	require.NoError(t, err)
+	return sqlx.NewDb(sqlDB, "sqlmock"), mock
+}
+
+// claimedJob returns a row for a job leased until a minute from now.
+func claimedJob(id int64, kind, payload string, attempts, maxAttempts int) []driver.Value {
+	now := time.Now()
+	return []driver.Value{id, kind, jobs.DefaultQueue, []byte(payload), 0, jobs.StateRunning, attempts, maxAttempts, now,
+		nil, "worker", now.Add(time.Minute), nil, now, now, nil}
+}
+
+func TestJobsEnqueue(t *testing.T) {
+	db, mock := newJobsMock(t)
+	client := jobs.NewClient(db)
+	runAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	row := claimedJob(1, "send_email", `{"to":"a@example.com"}`, 0, 3)
+	row[5] = jobs.StateAvailable
+	mock.ExpectQuery("INSERT INTO jobs").
+		WithArgs("send_email", "mail", []byte(`{"to":"a@example.com"}`), int64(5), runAt, int64(3), "welcome:a@example.com").
+		WillReturnRows(sqlmock.NewRows(jobColumns).AddRow(row...))
+	// ON CONFLICT DO NOTHING returns no row for a duplicate
+	mock.ExpectQuery("INSERT INTO jobs").
+		WillReturnRows(sqlmock.NewRows(jobColumns))
+
+	opts := []jobs.EnqueueOption{
+		jobs.WithQueue("mail"),
+		jobs.WithPriority(5),
+		jobs.WithRunAt(runAt),
+		jobs.WithMaxAttempts(3),
+		jobs.WithUniqueKey("welcome:a@example.com"),
+	}
+	job, err := client.Enqueue(context.Background(), sendEmailArgs{To: "a@example.com"}, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), job.ID)
+	assert.Equal(t, jobs.StateAvailable, job.State)
+
+	_, err = client.Enqueue(context.Background(), sendEmailArgs{To: "a@example.com"}, opts...)
+	assert.ErrorIs(t, err, jobs.ErrDuplicate)
+
+	_, err = client.Enqueue(context.Background(), sendEmailArgs{}, jobs.WithMaxAttempts(0))
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobsRegistry(t *testing.T) {
+	registry := jobs.NewRegistry()
+	jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args sendEmailArgs) error { return nil })
+	jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args resizeImageArgs) error { return nil })
+
+	assert.Equal(t, []string{"resize_image", "send_email"}, registry.Kinds())
+	assert.Panics(t, func() {
+		jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args sendEmailArgs) error { return nil })
+	})
+}
+
+func TestJobsBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{30, time.Minute},
+	}
+
+	for _, tt := range tests {
+		got := jobs.Backoff(tt.attempt, time.Second, time.Minute)
+		// Up to 10% is taken off as jitter
+		assert.LessOrEqual(t, got, tt.want, "attempt %d", tt.attempt)
+		assert.GreaterOrEqual(t, got, tt.want*9/10, "attempt %d", tt.attempt)
+	}
+}
+
+var testJobOptions = jobs.Options{
+	Queues:       []string{jobs.DefaultQueue},
+	Concurrency:  2,
+	PollInterval: time.Hour,
+	LockTimeout:  time.Minute,
+	BackoffBase:  time.Second,
+	BackoffMax:   time.Minute,
+}
+
+// expectClaim expects a poll of the default queue returning rows.
+func expectClaim(mock sqlmock.Sqlmock, registry *jobs.Registry, rows *sqlmock.Rows) {
+	// Leases are computed by the database from the lock timeout
+	mock.ExpectQuery(`(?s)UPDATE jobs SET state = 'running'.+locked_until = now\(\) \+ \$2::bigint`).
+		WithArgs(sqlmock.AnyArg(), testJobOptions.LockTimeout.Milliseconds(), []string{jobs.DefaultQueue}, registry.Kinds(), int64(2)).
+		WillReturnRows(rows)
+}
+
+// startJobWorker starts a worker whose first poll claims row.
+func startJobWorker(t *testing.T, db *sqlx.DB, mock sqlmock.Sqlmock, registry *jobs.Registry, row []driver.Value) *jobs.Worker {
+	mock.MatchExpectationsInOrder(false)
+	expectClaim(mock, registry, sqlmock.NewRows(jobColumns).AddRow(row...))
+
+	worker := jobs.NewWorker(db, registry, func() jobs.Options { return testJobOptions }, metrics.New())
+	worker.Start()
+	return worker
+}
+
+// runJob runs row to its outcome and shuts the worker down.
+func runJob(t *testing.T, db *sqlx.DB, mock sqlmock.Sqlmock, registry *jobs.Registry, row []driver.Value) {
+	mock.MatchExpectationsInOrder(false)
+	expectClaim(mock, registry, sqlmock.NewRows(jobColumns).AddRow(row...))
+	// The finished job wakes the worker, whose next poll finds nothing and
+	// waits out the poll interval
+	expectClaim(mock, registry, sqlmock.NewRows(jobColumns))
+
+	worker := jobs.NewWorker(db, registry, func() jobs.Options { return testJobOptions }, metrics.New())
+	worker.Start()
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, 5*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, worker.Shutdown(ctx))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobsWorkerCompletesJob(t *testing.T) {
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	var got sendEmailArgs
+	jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args sendEmailArgs) error {
+		got = args
+		return nil
+	})
+
+	mock.ExpectExec("UPDATE jobs SET state = 'completed'").
+		WithArgs(sqlmock.AnyArg(), int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	runJob(t, db, mock, registry, claimedJob(7, "send_email", `{"to":"a@example.com"}`, 1, 3))
+	assert.Equal(t, "a@example.com", got.To)
+}
+
+func TestJobsWorkerDeadlineIgnoresDatabaseClock(t *testing.T) {
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	var deadline time.Time
+	jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args sendEmailArgs) error {
+		deadline, _ = ctx.Deadline()
+		return nil
+	})
+
+	mock.ExpectExec("UPDATE jobs SET state = 'completed'").
+		WithArgs(sqlmock.AnyArg(), int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	// The database clock is an hour behind, so the lease it returns ended an
+	// hour ago by this clock; the handler still gets the lock timeout
+	row := claimedJob(7, "send_email", `{}`, 1, 3)
+	row[11] = time.Now().Add(time.Minute - time.Hour)
+	start := time.Now()
+	runJob(t, db, mock, registry, row)
+	assert.WithinRange(t, deadline, start.Add(testJobOptions.LockTimeout), time.Now().Add(testJobOptions.LockTimeout))
+}
+
+func TestJobsWorkerRetriesWithBackoff(t *testing.T) {
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args sendEmailArgs) error {
+		return errors.New("smtp unavailable")
+	})
+
+	// The second attempt waits twice the base backoff, less jitter
+	start := time.Now()
+	runAt := argFunc(func(v driver.Value) bool {
+		t, ok := v.(time.Time)
+		return ok && t.After(start.Add(1800*time.Millisecond)) && t.Before(time.Now().Add(2*time.Second))
+	})
+	mock.ExpectExec("UPDATE jobs SET state = 'available'").
+		WithArgs(runAt, "smtp unavailable", sqlmock.AnyArg(), int64(7), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	runJob(t, db, mock, registry, claimedJob(7, "send_email", `{}`, 2, 3))
+}
+
+func TestJobsWorkerKillsJob(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		payload  string
+		want     string
+	}{
+		{"out of attempts", 3, errors.New("smtp unavailable"), `{}`, "smtp unavailable"},
+		{"permanent error", 1, jobs.Permanent(errors.New("no such mailbox")), `{}`, "no such mailbox"},
+		{"undecodable payload", 1, nil, `[]`, "decoding args: json: cannot unmarshal array into Go value of type tests.sendEmailArgs"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newJobsMock(t)
+			registry := jobs.NewRegistry()
+			jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args sendEmailArgs) error {
+				return tt.err
+			})
+
+			mock.ExpectExec("UPDATE jobs SET state = 'dead'").
+				WithArgs(sqlmock.AnyArg(), tt.want, int64(7), int64(tt.attempts)).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			runJob(t, db, mock, registry, claimedJob(7, "send_email", tt.payload, tt.attempts, 3))
+		})
+	}
+}
+
+func TestJobsWorkerShutdownCancelsJobs(t *testing.T) {
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	started := make(chan struct{})
+	jobs.Register(registry, func(ctx context.Context, job *jobs.Job, args sendEmailArgs) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	// A job interrupted by shutdown counts as a failed attempt
+	mock.ExpectExec("UPDATE jobs SET state = 'available'").
+		WithArgs(sqlmock.AnyArg(), context.Canceled.Error(), sqlmock.AnyArg(), int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	worker := startJobWorker(t, db, mock, registry, claimedJob(7, "send_email", `{}`, 1, 3))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, worker.Shutdown(ctx), context.DeadlineExceeded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobsRequeueDead(t *testing.T) {
+	db, mock := newJobsMock(t)
+	client := jobs.NewClient(db)
+
+	mock.ExpectExec("UPDATE jobs SET state = 'available', attempts = 0").
+		WithArgs(sqlmock.AnyArg(), int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE jobs SET state = 'available', attempts = 0").
+		WithArgs(sqlmock.AnyArg(), int64(8)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, client.RequeueDead(context.Background(), 7))
+	assert.ErrorIs(t, client.RequeueDead(context.Background(), 8), database.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
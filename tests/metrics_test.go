@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	s, mock := NewTestServerWithConfig(&config.Config{
+		Port:       8080,
+		AdminToken: "admin-secret",
+		OpenAPI:    config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: openapi.ResponsesStrict},
+	})
+	defer mock.ExpectationsWereMet()
+	handler := s.RegisterRoutes()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}).
+		AddRow(uuid.New(), "Premium Example", 42.0, true, time.Now(), time.Now())
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO examples`).
+		WithArgs("Premium Example", 42.0, true).
+		WillReturnRows(rows)
+	expectExampleEvent(mock, models.EventExampleCreated)
+
+	body, _ := json.Marshal(models.CreateExampleRequest{Name: "Premium Example", LuckyNumber: 42.0, IsPremium: true})
+	req, _ := http.NewRequest("POST", "/examples/", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Scraping on the main port takes the admin token
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req, _ = http.NewRequest("GET", "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	metrics := rr.Body.String()
+	assert.Contains(t, metrics, `app_http_requests_total{method="POST",route="/examples",status="201"} 1`)
+	assert.Contains(t, metrics, `app_http_requests_in_flight`)
+	assert.Contains(t, metrics, `app_db_query_duration_seconds_count{method="Create",outcome="success",repository="example"} 1`)
+	assert.Contains(t, metrics, `app_examples_created_total{premium="true"} 1`)
+	assert.Contains(t, metrics, `go_sql_open_connections{db_name="postgres"}`)
+}
@@ -0,0 +1,147 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/internal/webui"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// undocumentedRoutes are served on the main router but intentionally left
+// out of the OpenAPI document.
+var undocumentedRoutes = []string{"/openapi.json", "/docs/*", "/ui/*", "/metrics", "/admin/*", "/graphql"}
+
+func fetchSpec(t *testing.T, handler http.Handler) *openapi.Document {
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	return &doc
+}
+
+func isUndocumented(path string) bool {
+	for _, p := range undocumentedRoutes {
+		if path == p || strings.HasSuffix(p, "*") && strings.HasPrefix(path, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOpenAPIMatchesRegisteredRoutes(t *testing.T) {
+	s, _ := NewTestServer()
+	handler := s.RegisterRoutes()
+	doc := fetchSpec(t, handler)
+
+	var registered []string
+	err := chi.Walk(handler.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if !isUndocumented(route) {
+			registered = append(registered, method+" "+route)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range *item {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	assert.Equal(t, registered, documented, "OpenAPI document is out of date with the registered routes")
+}
+
+func TestOpenAPISchemasFollowModelTags(t *testing.T) {
+	s, _ := NewTestServer()
+	doc := fetchSpec(t, s.RegisterRoutes())
+
+	assert.Equal(t, "3.1.0", doc.OpenAPI)
+
+	create := doc.Components.Schemas["CreateExampleRequest"]
+	require.NotNil(t, create)
+	assert.Equal(t, []string{"name", "lucky_number"}, create.Required)
+	assert.Equal(t, 3, *create.Properties["name"].MinLength)
+	assert.Equal(t, "boolean", create.Properties["is_premium"].Type)
+	assert.Equal(t, false, create.AdditionalProperties)
+
+	update := doc.Components.Schemas["UpdateExampleRequest"]
+	require.NotNil(t, update)
+	assert.Empty(t, update.Required)
+	assert.Equal(t, []interface{}{"string", "null"}, update.Properties["name"].Type)
+
+	example := doc.Components.Schemas["Example"]
+	require.NotNil(t, example)
+	assert.Equal(t, "uuid", example.Properties["id"].Format)
+	assert.Equal(t, "date-time", example.Properties["created_at"].Format)
+
+	list := (*doc.Paths["/examples/"])["get"]
+	require.NotNil(t, list)
+	assert.Equal(t, "listExamples", list.OperationID)
+	require.Len(t, list.Parameters, 2)
+	assert.Equal(t, "limit", list.Parameters[0].Name)
+	assert.Equal(t, "query", list.Parameters[0].In)
+
+	get := (*doc.Paths["/examples/{id}"])["get"]
+	require.NotNil(t, get)
+	data := get.Responses["200"].Content["application/json"].Schema.Properties["data"]
+	assert.Equal(t, "#/components/schemas/Example", data.Ref)
+	assert.Equal(t, "#/components/schemas/ErrorResponse", get.Responses["404"].Content["application/json"].Schema.Ref)
+}
+
+func TestAPIDocsPages(t *testing.T) {
+	cfg := config.Defaults(config.Test)
+	s, _ := NewTestServerWithConfig(cfg)
+	handler := s.RegisterRoutes()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/docs/")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "SwaggerUIBundle")
+	csp := rr.Header().Get("Content-Security-Policy-Report-Only")
+	assert.Contains(t, csp, "script-src 'nonce-")
+	assert.Contains(t, rr.Body.String(), `nonce="`)
+
+	// Swagger UI is served from the binary, so the page trusts no other origin
+	assert.NotContains(t, csp, "https://")
+	assert.Contains(t, rr.Body.String(), `src="/ui/swagger-ui-dist@`)
+	rr = get(webui.SwaggerUI.URL("swagger-ui-bundle.js"))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Type"), "javascript")
+	assert.Contains(t, rr.Header().Get("Cache-Control"), "immutable")
+	assert.Equal(t, http.StatusNotFound, get("/ui/").Code)
+
+	rr = get("/docs/redoc")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `spec-url="/openapi.json"`)
+	// An asset loaded from the CDN is trusted by its pinned path, not the
+	// whole origin
+	assert.NotRegexp(t, `https://[^/ ;]+[ ;]`, rr.Header().Get("Content-Security-Policy-Report-Only"))
+
+	next := *cfg
+	next.APIDocs = false
+	s.Config().Apply(&next)
+	assert.Equal(t, http.StatusNotFound, get("/docs/").Code)
+	assert.Equal(t, http.StatusOK, get("/openapi.json").Code)
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/pkg/response"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIRequestValidation(t *testing.T) {
+	s, _ := NewTestServer()
+	handler := s.RegisterRoutes()
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		body    string
+		details []interface{}
+	}{
+		{"path param", "GET", "/examples/not-a-uuid", "", []interface{}{"path.id: must be a valid uuid"}},
+		{"query type", "GET", "/examples?limit=ten", "", []interface{}{"query.limit: must be integer"}},
+		{"query bound", "GET", "/examples/?offset=-1", "", []interface{}{"query.offset: must be at least 0"}},
+		{"body", "POST", "/examples/", `{"name": "ab", "colour": "red"}`, []interface{}{
+			"body.lucky_number: is required",
+			"body.colour: is not allowed",
+			"body.name: length must be at least 3",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+			assert.Equal(t, tt.details, resp["details"])
+		})
+	}
+}
+
+func TestOpenAPIValidatesEmptyList(t *testing.T) {
+	s, mock := NewTestServer()
+	mock.ExpectQuery(`SELECT (.+) FROM examples`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}))
+
+	req, _ := http.NewRequest("GET", "/examples?limit=5", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	// An empty page must still be an array to match the document
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"success": true, "data": []}`, rr.Body.String())
+}
+
+type widget struct {
+	ID   int    `json:"id" validate:"required"`
+	Name string `json:"name" validate:"required"`
+}
+
+// widgetServer serves a documented route whose handler returns data, so
+// responses can drift from the document on purpose.
+func widgetServer(mode string, data interface{}, status int) http.Handler {
+	doc := openapi.NewBuilder(openapi.Info{Title: "widgets", Version: "1"}).
+		Mount("", "widgets", []openapi.Route{{Method: http.MethodGet, Path: "/widget", Response: widget{}}}).
+		Document()
+	validator := openapi.NewValidator(doc, func() openapi.ValidatorOptions {
+		return openapi.ValidatorOptions{Requests: true, Responses: mode}
+	})
+	return validator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.JSONSuccess(w, data, status)
+	}))
+}
+
+func getWidget(handler http.Handler) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/widget", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestOpenAPIResponseValidation(t *testing.T) {
+	valid := widget{ID: 1, Name: "gear"}
+	drifted := map[string]interface{}{"id": "1", "label": "gear"}
+
+	rr := getWidget(widgetServer(openapi.ResponsesStrict, valid, http.StatusOK))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"success": true, "data": {"id": 1, "name": "gear"}}`, rr.Body.String())
+
+	rr = getWidget(widgetServer(openapi.ResponsesStrict, drifted, http.StatusOK))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "INVALID_RESPONSE", resp["code"])
+	assert.Equal(t, []interface{}{
+		"body.data.name: is required",
+		"body.data.id: must be integer",
+		"body.data.label: is not allowed",
+	}, resp["details"])
+
+	// Statuses missing from the document are drift too
+	rr = getWidget(widgetServer(openapi.ResponsesStrict, valid, http.StatusAccepted))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), "status 202 is not documented")
+
+	// Log mode reports the mismatch but sends the response as written
+	logs := captureLogs(t)
+	rr = getWidget(widgetServer(openapi.ResponsesLog, drifted, http.StatusOK))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"label":"gear"`)
+	assert.Contains(t, logs.String(), "response does not match the OpenAPI document")
+
+	rr = getWidget(widgetServer(openapi.ResponsesOff, drifted, http.StatusOK))
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestOpenAPIResponseValidationSkipsStreams(t *testing.T) {
+	doc := openapi.NewBuilder(openapi.Info{Title: "widgets", Version: "1"}).
+		Mount("", "widgets", []openapi.Route{{Method: http.MethodGet, Path: "/widget", Response: widget{}}}).
+		Document()
+	validator := openapi.NewValidator(doc, func() openapi.ValidatorOptions {
+		return openapi.ValidatorOptions{Responses: openapi.ResponsesStrict}
+	})
+	handler := validator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"partial":`))
+		w.(http.Flusher).Flush()
+		w.Write([]byte(`true}`))
+	}))
+
+	rr := getWidget(handler)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, `{"partial":true}`, rr.Body.String())
+}
@@ -0,0 +1,248 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/ratelimit"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rateLimitedConfig(policies ...ratelimit.Policy) *config.Config {
+	return &config.Config{
+		Port: 8080,
+		RateLimit: config.RateLimitConfig{
+			Enabled:  true,
+			Backend:  "memory",
+			Policies: policies,
+		},
+	}
+}
+
+func TestRateLimitRejectsWithHeaders(t *testing.T) {
+	s, mock := NewTestServerWithConfig(rateLimitedConfig(ratelimit.Policy{
+		Name: "examples", Path: "/examples*", Limit: 2, Window: time.Minute,
+	}))
+	handler := s.RegisterRoutes()
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery("SELECT (.+) FROM examples").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}))
+
+		req, _ := http.NewRequest("GET", "/examples", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, []string{"1", "0"}[i], rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+	}
+
+	req, _ := http.NewRequest("GET", "/examples", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "TOO_MANY_REQUESTS", body["code"])
+
+	// Routes without a matching policy are untouched
+	req, _ = http.NewRequest("GET", "/", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+}
+
+func TestRateLimitKeysByAPIKey(t *testing.T) {
+	s, _ := NewTestServerWithConfig(rateLimitedConfig(ratelimit.Policy{
+		Name: "root", Path: "/", Limit: 1, Window: time.Minute, Key: ratelimit.KeyAPIKey,
+	}))
+	handler := s.RegisterRoutes()
+
+	status := func(apiKey string) int {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("X-API-Key", apiKey)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, status("key-a"))
+	assert.Equal(t, http.StatusTooManyRequests, status("key-a"))
+	assert.Equal(t, http.StatusOK, status("key-b"))
+}
+
+func TestRateLimitKeysByUser(t *testing.T) {
+	cfg := rateLimitedConfig(ratelimit.Policy{
+		Name: "root", Path: "/", Limit: 1, Window: time.Minute, Key: ratelimit.KeyUser,
+	})
+	cfg.JWTSecret = socketSecret
+	s, _ := NewTestServerWithConfig(cfg)
+	handler := s.RegisterRoutes()
+
+	status := func(subject string) int {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if subject != "" {
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: subject}).SignedString([]byte(socketSecret))
+			require.NoError(t, err)
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Public routes see the user too
+	assert.Equal(t, http.StatusOK, status("alice"))
+	assert.Equal(t, http.StatusTooManyRequests, status("alice"))
+	assert.Equal(t, http.StatusOK, status("bob"))
+	// Anonymous clients fall back to their address
+	assert.Equal(t, http.StatusOK, status(""))
+}
+
+func TestRateLimitPolicyMatchesMethods(t *testing.T) {
+	p := ratelimit.Policy{Name: "writes", Methods: []string{"POST", "DELETE"}, Path: "/examples*"}
+
+	assert.True(t, p.Matches("POST", "/examples"))
+	assert.True(t, p.Matches("delete", "/examples/1"))
+	assert.False(t, p.Matches("GET", "/examples"))
+	assert.False(t, p.Matches("POST", "/health"))
+}
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	policy := ratelimit.Policy{Name: "sw", Limit: 4, Window: time.Minute, Algorithm: ratelimit.AlgorithmSlidingWindow}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		res, err := store.Allow(ctx, "client", policy, start.Add(50*time.Second))
+		require.NoError(t, err)
+		assert.True(t, res.Allowed)
+	}
+	res, _ := store.Allow(ctx, "client", policy, start.Add(55*time.Second))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 5*time.Second, res.RetryAfter)
+
+	// Halfway through the next window half of the previous count still applies
+	res, _ = store.Allow(ctx, "client", policy, start.Add(90*time.Second))
+	assert.True(t, res.Allowed)
+	res, _ = store.Allow(ctx, "client", policy, start.Add(90*time.Second))
+	assert.True(t, res.Allowed)
+	res, _ = store.Allow(ctx, "client", policy, start.Add(90*time.Second))
+	assert.False(t, res.Allowed)
+}
+
+func TestRateLimitTokenBucketRefills(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+	policy := ratelimit.Policy{Name: "tb", Limit: 2, Window: 2 * time.Second}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	store.Allow(ctx, "client", policy, start)
+	store.Allow(ctx, "client", policy, start)
+	res, _ := store.Allow(ctx, "client", policy, start)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, time.Second, res.RetryAfter)
+
+	res, _ = store.Allow(ctx, "client", policy, start.Add(time.Second))
+	assert.True(t, res.Allowed)
+}
+
+func TestRateLimitPostgresStore(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	store := ratelimit.NewPostgresStore(sqlx.NewDb(sqlDB, "sqlmock"))
+
+	policy := ratelimit.Policy{Name: "pg", Limit: 1, Window: time.Minute}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	// The replica's clock is ahead; the database clock decides
+	skewed := now.Add(10 * time.Minute)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT now\(\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"now"}).AddRow(now))
+	// A new key gets a row to lock before it is read
+	mock.ExpectExec("INSERT INTO rate_limits (.+) ON CONFLICT \\(key\\) DO NOTHING").
+		WithArgs("pg:ip:10.0.0.1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT (.+) FROM rate_limits WHERE key = \\$1 FOR UPDATE").
+		WithArgs("pg:ip:10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"tokens", "prev_count", "curr_count", "stamp", "expires_at"}).
+			AddRow(0.0, 0, 0, now, now.Add(time.Minute)))
+	mock.ExpectExec("INSERT INTO rate_limits").
+		WithArgs("pg:ip:10.0.0.1", 0.0, 0, 0, now, now.Add(2*time.Minute)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	res, err := store.Allow(context.Background(), "pg:ip:10.0.0.1", policy, skewed)
+	require.NoError(t, err)
+	assert.False(t, res.Allowed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRateLimitPoliciesFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", `
+database_url: postgres://app@localhost/app
+rate_limit:
+  policies:
+    - name: examples
+      path: /examples*
+      limit: 10
+      window: 30s
+      algorithm: sliding_window
+`)
+	t.Setenv("APP_ENV", "")
+	t.Setenv("DATABASE_URL", "")
+
+	cfg, err := config.Load([]string{"--config", file})
+	require.NoError(t, err)
+	require.Len(t, cfg.RateLimit.Policies, 1)
+	assert.Equal(t, 30*time.Second, cfg.RateLimit.Policies[0].Window)
+	assert.Equal(t, ratelimit.AlgorithmSlidingWindow, cfg.RateLimit.Policies[0].Algorithm)
+
+	t.Setenv("RATE_LIMIT_POLICIES", `[{"name":"a","limit":0,"window":"1m"},{"name":"b","limit":5,"window":"1m","key":"cookie"}]`)
+	_, err = config.Load([]string{"--config", file})
+	var verr *config.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, `rate_limit.policies: policy "a": limit must be positive`)
+	assert.Contains(t, verr.Problems, `rate_limit.policies: policy "b": unknown key "cookie"`)
+}
+
+func TestRateLimitPoliciesReload(t *testing.T) {
+	cfg := rateLimitedConfig(ratelimit.Policy{Name: "root", Path: "/", Limit: 1, Window: time.Minute})
+	s, _ := NewTestServerWithConfig(cfg)
+	handler := s.RegisterRoutes()
+
+	get := func() int {
+		req, _ := http.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get())
+	assert.Equal(t, http.StatusTooManyRequests, get())
+
+	next := *cfg
+	next.RateLimit.Enabled = false
+	changed, _ := s.Config().Apply(&next)
+	assert.Equal(t, []string{"rate_limit.enabled"}, changed)
+	assert.Equal(t, http.StatusOK, get())
+}
@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigManagerAppliesOnlyReloadableSettings(t *testing.T) {
+	initial := config.Defaults(config.Development)
+	manager := config.NewManager(initial)
+
+	var notified atomic.Int32
+	manager.Subscribe(func(old, new *config.Config) { notified.Add(1) })
+
+	next := config.Defaults(config.Development)
+	next.Port = 9999
+	next.Log.Level = "error"
+	next.Features = []string{"new-dashboard"}
+
+	changed, ignored := manager.Apply(next)
+
+	assert.ElementsMatch(t, []string{"log.level", "features"}, changed)
+	assert.Equal(t, []string{"port"}, ignored)
+	assert.Equal(t, 8080, manager.Current().Port)
+	assert.Equal(t, "error", manager.Current().Log.Level)
+	assert.True(t, manager.Current().FeatureEnabled("new-dashboard"))
+	assert.Equal(t, int32(1), notified.Load())
+
+	// The previous snapshot is never mutated
+	assert.Equal(t, "debug", initial.Log.Level)
+}
+
+func TestCORSOriginsReload(t *testing.T) {
+	cfg := config.Defaults(config.Production)
+	cfg.CORS.AllowedOrigins = []string{"https://a.example.com"}
+	s, _ := NewTestServerWithConfig(cfg)
+	handler := s.RegisterRoutes()
+
+	allowedOrigin := func(origin string) string {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set("Origin", origin)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Header().Get("Access-Control-Allow-Origin")
+	}
+
+	assert.Equal(t, "https://a.example.com", allowedOrigin("https://a.example.com"))
+	assert.Empty(t, allowedOrigin("https://b.example.com"))
+
+	next := *cfg
+	next.CORS.AllowedOrigins = []string{"https://b.example.com"}
+	s.Config().Apply(&next)
+
+	assert.Empty(t, allowedOrigin("https://a.example.com"))
+	assert.Equal(t, "https://b.example.com", allowedOrigin("https://b.example.com"))
+}
+
+func TestLoaderReloadsChangedFile(t *testing.T) {
+	dir := t.TempDir()
+	file := writeFile(t, dir, "config.yaml", "database_url: postgres://app@db/app\nlog:\n  level: info\n")
+	t.Setenv("APP_ENV", "")
+	t.Setenv("LOG_LEVEL", "")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := config.NewLoader(fs)
+	require.NoError(t, fs.Parse([]string{"--config", file, "--env-file", filepath.Join(dir, ".env")}))
+
+	writeFile(t, dir, ".env", "CORS_ALLOWED_ORIGINS=https://one.example.com\n")
+	cfg, err := loader.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.Log.Level)
+	assert.Equal(t, []string{"https://one.example.com"}, cfg.CORS.AllowedOrigins)
+	t.Cleanup(func() { os.Unsetenv("CORS_ALLOWED_ORIGINS") })
+
+	writeFile(t, dir, "config.yaml", "database_url: postgres://app@db/app\nlog:\n  level: warn\n")
+	writeFile(t, dir, ".env", "CORS_ALLOWED_ORIGINS=https://two.example.com\n")
+	cfg, err = loader.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "warn", cfg.Log.Level)
+	assert.Equal(t, []string{"https://two.example.com"}, cfg.CORS.AllowedOrigins)
+}
+
+func TestWatchNotifiesOnFileChange(t *testing.T) {
+	file := writeFile(t, t.TempDir(), "config.yaml", "port: 8080\n")
+
+	var calls atomic.Int32
+	require.NoError(t, config.Watch(t.Context(), []string{file}, func() { calls.Add(1) }))
+
+	writeFile(t, filepath.Dir(file), "config.yaml", "port: 8081\n")
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, 3*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/pkg/request"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postExample(t *testing.T, handler http.Handler, contentType, body string) (int, map[string]interface{}) {
+	req, _ := http.NewRequest("POST", "/examples/", strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	return rr.Code, resp
+}
+
+func TestDecodeJSONRejectsBadBodies(t *testing.T) {
+	// OpenAPI validation would reject most of these before the decoder runs
+	s, _ := NewTestServerWithConfig(&config.Config{Port: 8080})
+	handler := s.RegisterRoutes()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		details     string
+	}{
+		{"wrong media type", "text/plain", `{}`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"missing media type", "", `{}`, http.StatusUnsupportedMediaType, "Content-Type must be application/json"},
+		{"empty body", "application/json", ``, http.StatusBadRequest, "request body must not be empty"},
+		{"syntax error", "application/json", `{"name": "abc",}`, http.StatusBadRequest, "malformed JSON at byte offset 16: invalid character '}' looking for beginning of object key string"},
+		{"truncated", "application/json", `{"name": "abc"`, http.StatusBadRequest, "malformed JSON: body ended unexpectedly"},
+		{"wrong type", "application/json", `{"name": 12}`, http.StatusBadRequest, `field "name" must be string, got number at byte offset 11`},
+		{"not an object", "application/json", `[{"name": "abc"}]`, http.StatusBadRequest, "request body must be a JSON object"},
+		{"unknown field", "application/json", `{"name": "abc", "colour": "red"}`, http.StatusBadRequest, `unknown field "colour"`},
+		{"trailing data", "application/json", `{"name": "abc"} {"name": "def"}`, http.StatusBadRequest, "request body must contain a single JSON object, unexpected data after byte offset 15"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := postExample(t, handler, tt.contentType, tt.body)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.details, resp["details"])
+		})
+	}
+}
+
+func TestBodyLimitPerRoute(t *testing.T) {
+	s, _ := NewTestServerWithConfig(&config.Config{
+		Port: 8080,
+		Request: config.RequestConfig{
+			MaxBodyBytes: 1 << 20,
+			BodyLimits:   []request.BodyLimit{{Path: "/examples*", Methods: []string{"POST"}, MaxBytes: 32}},
+		},
+	})
+	handler := s.RegisterRoutes()
+
+	body := `{"name": "` + strings.Repeat("a", 64) + `"}`
+	status, resp := postExample(t, handler, "application/json", body)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+	assert.Equal(t, "PAYLOAD_TOO_LARGE", resp["code"])
+	assert.Equal(t, "request body must not exceed 32 bytes", resp["details"])
+
+	// Chunked bodies have no Content-Length and fail while decoding instead
+	req, _ := http.NewRequest("POST", "/examples/", strings.NewReader(body))
+	req.ContentLength = -1
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestBodyLimitLongestRouteWins(t *testing.T) {
+	opts := request.LimitOptions{
+		MaxBytes: 100,
+		Routes: []request.BodyLimit{
+			{Path: "/examples*", MaxBytes: 10},
+			{Path: "/examples/import", MaxBytes: 1000},
+		},
+	}
+
+	assert.Equal(t, int64(100), opts.MaxBytesFor("POST", "/health"))
+	assert.Equal(t, int64(10), opts.MaxBytesFor("POST", "/examples/"))
+	assert.Equal(t, int64(1000), opts.MaxBytesFor("POST", "/examples/import"))
+}
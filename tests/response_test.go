@@ -0,0 +1,165 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/andybalholm/brotli"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/google/uuid"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func responseServer(t *testing.T, rows int) (http.Handler, sqlmock.Sqlmock) {
+	cfg := config.Defaults(config.Test)
+	s, mock := NewTestServerWithConfig(cfg)
+
+	result := sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"})
+	for i := 0; i < rows; i++ {
+		result.AddRow(uuid.New(), fmt.Sprintf("Example %d", i), float64(i), i%2 == 0, time.Now(), time.Now())
+	}
+	mock.ExpectQuery("SELECT (.+) FROM examples").WillReturnRows(result)
+	return s.RegisterRoutes(), mock
+}
+
+func getExamples(handler http.Handler, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/examples/", nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestContentNegotiation(t *testing.T) {
+	decoders := map[string]func([]byte, interface{}) error{
+		"application/json":    json.Unmarshal,
+		"application/msgpack": msgpack.Unmarshal,
+		"application/cbor":    cbor.Unmarshal,
+		"application/yaml":    yaml.Unmarshal,
+	}
+
+	for mediaType, decode := range decoders {
+		t.Run(mediaType, func(t *testing.T) {
+			handler, _ := responseServer(t, 2)
+			rr := getExamples(handler, map[string]string{"Accept": mediaType})
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, mediaType, rr.Header().Get("Content-Type"))
+			assert.Contains(t, rr.Header().Values("Vary"), "Accept")
+
+			var body map[string]interface{}
+			require.NoError(t, decode(rr.Body.Bytes(), &body))
+			assert.Equal(t, true, body["success"])
+			assert.Len(t, body["data"], 2)
+		})
+	}
+}
+
+func TestContentNegotiationPrecedence(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"", "application/json"},
+		{"text/html", "application/json"},
+		{"application/cbor;q=0.5, application/x-msgpack", "application/x-msgpack"},
+		{"application/msgpack;q=0, */*;q=0.1", "application/json"},
+		{"application/*", "application/json"},
+	}
+
+	for _, tt := range tests {
+		handler, _ := responseServer(t, 1)
+		rr := getExamples(handler, map[string]string{"Accept": tt.accept})
+		assert.Equal(t, tt.want, rr.Header().Get("Content-Type"), "Accept: %q", tt.accept)
+	}
+}
+
+func TestContentNegotiationErrors(t *testing.T) {
+	s, _ := NewTestServerWithConfig(config.Defaults(config.Test))
+
+	req, _ := http.NewRequest("GET", "/examples/not-a-uuid", nil)
+	req.Header.Set("Accept", "application/yaml")
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/yaml", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), "code: VALIDATION_FAILED")
+}
+
+func TestCompression(t *testing.T) {
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+		"zstd": func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+
+	for encoding, decode := range decoders {
+		t.Run(encoding, func(t *testing.T) {
+			handler, _ := responseServer(t, 50)
+			rr := getExamples(handler, map[string]string{"Accept-Encoding": encoding})
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, encoding, rr.Header().Get("Content-Encoding"))
+			assert.Contains(t, rr.Header().Values("Vary"), "Accept-Encoding")
+
+			r, err := decode(rr.Body)
+			require.NoError(t, err)
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r).Decode(&body))
+			assert.Len(t, body["data"], 50)
+		})
+	}
+}
+
+func TestCompressionPrefersServerOrder(t *testing.T) {
+	handler, _ := responseServer(t, 50)
+	rr := getExamples(handler, map[string]string{"Accept-Encoding": "gzip, deflate, br, zstd"})
+	assert.Equal(t, "zstd", rr.Header().Get("Content-Encoding"))
+
+	handler, _ = responseServer(t, 50)
+	rr = getExamples(handler, map[string]string{"Accept-Encoding": "gzip, zstd;q=0.5"})
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+}
+
+func TestCompressionSkipsSmallAndIncompressibleBodies(t *testing.T) {
+	handler, _ := responseServer(t, 1)
+	rr := getExamples(handler, map[string]string{"Accept-Encoding": "gzip"})
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+	assert.True(t, json.Valid(rr.Body.Bytes()))
+
+	cfg := config.Defaults(config.Test)
+	cfg.Response.CompressibleTypes = []string{"text/*"}
+	cfg.Response.CompressionMinSize = 0
+	s, mock := NewTestServerWithConfig(cfg)
+	mock.ExpectQuery("SELECT (.+) FROM examples").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}))
+	rr = getExamples(s.RegisterRoutes(), map[string]string{"Accept-Encoding": "gzip"})
+	assert.Empty(t, rr.Header().Get("Content-Encoding"))
+
+	// text/plain matches text/*
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr = httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+	assert.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+	r, err := gzip.NewReader(bytes.NewReader(rr.Body.Bytes()))
+	require.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	assert.Equal(t, "Hello World", string(data))
+}
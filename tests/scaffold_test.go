@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/scaffold"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScaffoldParseResource(t *testing.T) {
+	res, err := scaffold.ParseResource("ProductCategory", []string{"name:string:required,min=3", "website_url:string:url"}, "")
+	require.NoError(t, err)
+	assert.Equal(t, "ProductCategory", res.Name)
+	assert.Equal(t, "productCategory", res.Var)
+	assert.Equal(t, "ProductCategories", res.Plural)
+	assert.Equal(t, "product_categories", res.Table)
+	assert.Equal(t, "/product-categories", res.Path)
+	assert.Equal(t, "WebsiteURL", res.Fields[1].Name)
+	assert.Equal(t, "omitempty,min=3", res.Fields[0].UpdateValidate())
+
+	for _, fields := range [][]string{nil, {"name"}, {"name:blob"}, {"created_at:time"}, {"a:string", "a:int"}} {
+		_, err := scaffold.ParseResource("product", fields, "")
+		assert.Error(t, err, "fields %v", fields)
+	}
+}
+
+// scaffoldRoutes stands in for the routes file, so the test does not depend
+// on the resources registered in this repository.
+const scaffoldRoutes = `package server
+
+func (s *Server) RegisterRoutes() http.Handler {
+	resources := []resource{
+		{"/examples", "examples", handlers.NewExampleHandler(exampleService)},
+		// ` + scaffold.RoutesMarker + `
+	}
+	return mount(resources)
+}
+`
+
+func TestScaffoldGenerate(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "go.mod", "module example.com/app\n\ngo 1.24\n")
+	require.NoError(t, os.MkdirAll(filepath.Join(root, filepath.Dir(scaffold.RoutesFile)), 0o755))
+	writeFile(t, root, scaffold.RoutesFile, scaffoldRoutes)
+
+	res, err := scaffold.ParseResource("widget", []string{"name:string:required", "weight:float:gt=0", "active:bool"}, "")
+	require.NoError(t, err)
+	written, err := scaffold.Generate(root, res, time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"internal/models/widget.go",
+		"internal/repository/widget.go",
+		"internal/database/widget_repository.go",
+		"internal/services/widget_service.go",
+		"internal/handlers/widget.go",
+		"database/migrations/20261019120000_create_widgets_table.sql",
+		"tests/widget_test.go",
+		scaffold.RoutesFile,
+	}, written)
+
+	for _, path := range written {
+		if strings.HasSuffix(path, ".go") {
+			_, err := parser.ParseFile(token.NewFileSet(), filepath.Join(root, path), nil, 0)
+			assert.NoError(t, err, path)
+		}
+	}
+
+	migration, _ := os.ReadFile(filepath.Join(root, written[5]))
+	assert.Contains(t, string(migration), "weight DOUBLE PRECISION NOT NULL,")
+	model, _ := os.ReadFile(filepath.Join(root, written[0]))
+	assert.Contains(t, string(model), "Weight    float64   `json:\"weight\" db:\"weight\" validate:\"gt=0\"`")
+
+	handler, _ := os.ReadFile(filepath.Join(root, written[4]))
+	assert.Contains(t, string(handler), `"example.com/app/internal/services"`)
+
+	registered, _ := os.ReadFile(filepath.Join(root, scaffold.RoutesFile))
+	assert.Contains(t, string(registered), `{"/widgets", "widgets", handlers.NewWidgetHandler(services.NewWidgetService(database.NewWidgetRepository(s.db.GetDB())))},`)
+
+	// Running it again must not overwrite anything
+	_, err = scaffold.Generate(root, res, time.Now())
+	assert.ErrorContains(t, err, "already exists")
+}
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var scheduleColumns = []string{"name", "scheduled_at", "started_at", "finished_at", "last_error", "created_at"}
+
+func newSchedulerMock(t *testing.T) (*scheduler.Scheduler, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	// Ticks after the ones a test expects run into an exhausted mock and are
+	// only logged
+	mock.MatchExpectationsInOrder(false)
+	s := scheduler.New(sqlDB, func() scheduler.Options {
+		return scheduler.Options{LeaseTTL: time.Minute}
+	}, metrics.New())
+	return s, mock
+}
+
+// expectLeadership expects the lease to be granted and the schedules to be
+// loaded with the given rows.
+func expectLeadership(mock sqlmock.Sqlmock, tasks int, rows *sqlmock.Rows) {
+	// Expiry is left to the database clock; only the TTL is sent
+	mock.ExpectExec(`(?s)INSERT INTO scheduler_leases .+ now\(\) .+\.expires_at < now\(\)`).
+		WithArgs("scheduler", sqlmock.AnyArg(), time.Minute.Milliseconds()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for i := 0; i < tasks; i++ {
+		mock.ExpectExec("INSERT INTO schedules").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectQuery("SELECT (.+) FROM schedules").WillReturnRows(rows)
+}
+
+func shutdownScheduler(t *testing.T, s *scheduler.Scheduler) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.Shutdown(ctx))
+}
+
+func TestSchedulerAddRejectsTasks(t *testing.T) {
+	s, _ := newSchedulerMock(t)
+	run := func(ctx context.Context) error { return nil }
+
+	s.Add(scheduler.Task{Name: "report", Schedule: "0 3 * * *", Run: run})
+	assert.Panics(t, func() { s.Add(scheduler.Task{Name: "report", Schedule: "@hourly", Run: run}) })
+	assert.Panics(t, func() { s.Add(scheduler.Task{Name: "bad", Schedule: "every minute", Run: run}) })
+	assert.Panics(t, func() { s.Add(scheduler.Task{Name: "bad", Schedule: "@hourly", Missed: "all", Run: run}) })
+	assert.Panics(t, func() { s.Add(scheduler.Task{Name: "bad", Schedule: "@hourly"}) })
+}
+
+func TestSchedulerLeaderRunsMissedTaskOnce(t *testing.T) {
+	s, mock := newSchedulerMock(t)
+	ran := make(chan struct{}, 10)
+	s.Add(scheduler.Task{
+		Name:     "report",
+		Schedule: "@every 1h",
+		Missed:   scheduler.MissedRunOnce,
+		Run: func(ctx context.Context) error {
+			ran <- struct{}{}
+			return nil
+		},
+	})
+
+	// The last run was three hours ago; only the first missed slot runs
+	last := time.Now().Add(-3 * time.Hour)
+	expectLeadership(mock, 1, sqlmock.NewRows(scheduleColumns).AddRow("report", last, last, last, nil, last.Add(-time.Hour)))
+	slot := argFunc(func(v driver.Value) bool {
+		t, ok := v.(time.Time)
+		return ok && t.Sub(last) > 59*time.Minute && t.Sub(last) <= time.Hour
+	})
+	mock.ExpectExec("UPDATE schedules SET scheduled_at").
+		WithArgs(slot, sqlmock.AnyArg(), "report").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE schedules SET finished_at").
+		WithArgs(sqlmock.AnyArg(), nil, "report").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM scheduler_leases").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s.Start()
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("task did not run")
+	}
+	shutdownScheduler(t, s)
+
+	assert.Empty(t, ran, "task ran more than once")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSchedulerRecordsTaskError(t *testing.T) {
+	s, mock := newSchedulerMock(t)
+	s.Add(scheduler.Task{
+		Name:     "report",
+		Schedule: "@every 1h",
+		Missed:   scheduler.MissedRunOnce,
+		Run:      func(ctx context.Context) error { return errors.New("report failed") },
+	})
+
+	last := time.Now().Add(-2 * time.Hour)
+	expectLeadership(mock, 1, sqlmock.NewRows(scheduleColumns).AddRow("report", last, last, last, nil, last))
+	mock.ExpectExec("UPDATE schedules SET scheduled_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	var finished atomic.Bool
+	mock.ExpectExec("UPDATE schedules SET finished_at").
+		WithArgs(sqlmock.AnyArg(), "report failed", argFunc(func(v driver.Value) bool {
+			finished.Store(true)
+			return v == "report"
+		})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM scheduler_leases").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s.Start()
+	assert.Eventually(t, finished.Load, time.Second, 5*time.Millisecond)
+	shutdownScheduler(t, s)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSchedulerSkipsSlotAnotherLeaderRan(t *testing.T) {
+	s, mock := newSchedulerMock(t)
+	var ran atomic.Bool
+	s.Add(scheduler.Task{
+		Name:     "report",
+		Schedule: "@every 1h",
+		Missed:   scheduler.MissedRunOnce,
+		Run: func(ctx context.Context) error {
+			ran.Store(true)
+			return nil
+		},
+	})
+
+	last := time.Now().Add(-2 * time.Hour)
+	expectLeadership(mock, 1, sqlmock.NewRows(scheduleColumns).AddRow("report", last, last, last, nil, last))
+	var claimed atomic.Bool
+	mock.ExpectExec("UPDATE schedules SET scheduled_at").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), argFunc(func(v driver.Value) bool {
+			claimed.Store(true)
+			return v == "report"
+		})).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM scheduler_leases").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s.Start()
+	assert.Eventually(t, claimed.Load, time.Second, 5*time.Millisecond)
+	shutdownScheduler(t, s)
+
+	assert.False(t, ran.Load())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSchedulerFollowerRunsNothing(t *testing.T) {
+	s, mock := newSchedulerMock(t)
+	var ran atomic.Bool
+	s.Add(scheduler.Task{
+		Name:     "report",
+		Schedule: "@every 1s",
+		Run: func(ctx context.Context) error {
+			ran.Store(true)
+			return nil
+		},
+	})
+
+	// Another replica holds the lease
+	var asked atomic.Bool
+	mock.ExpectExec("INSERT INTO scheduler_leases").
+		WithArgs("scheduler", argFunc(func(v driver.Value) bool {
+			asked.Store(true)
+			return true
+		}), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s.Start()
+	assert.Eventually(t, asked.Load, time.Second, 5*time.Millisecond)
+	shutdownScheduler(t, s)
+
+	assert.False(t, ran.Load())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSchedulerStatus(t *testing.T) {
+	s, mock := newSchedulerMock(t)
+	run := func(ctx context.Context) error { return nil }
+	s.Add(scheduler.Task{Name: "purge", Schedule: "@every 1h", Run: run})
+	s.Add(scheduler.Task{Name: "report", Schedule: "@daily", Missed: scheduler.MissedRunOnce, Run: run})
+
+	now := time.Now()
+	mock.ExpectQuery(`(?s)SELECT .+ FROM scheduler_leases WHERE .+ expires_at > now\(\)`).
+		WithArgs("scheduler").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "holder", "expires_at"}).AddRow("scheduler", "api-1", now.Add(time.Minute)))
+	// purge failed three hours ago; report has never run and is running now
+	mock.ExpectQuery("SELECT (.+) FROM schedules").
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow("purge", now.Add(-3*time.Hour), now.Add(-3*time.Hour), now.Add(-3*time.Hour), "boom", now.Add(-24*time.Hour)).
+			AddRow("report", now.Add(-48*time.Hour), now.Add(-time.Minute), now.Add(-47*time.Hour), nil, now.Add(-72*time.Hour)))
+
+	status, err := s.Status(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "api-1", status.Leader)
+	require.Len(t, status.Tasks, 2)
+
+	purge := status.Tasks[0]
+	assert.Equal(t, "purge", purge.Name)
+	assert.False(t, purge.Running)
+	require.NotNil(t, purge.LastError)
+	assert.Equal(t, "boom", *purge.LastError)
+	// Missed slots are skipped, so the next run is within the hour
+	assert.WithinDuration(t, now.Add(time.Hour), purge.NextRunAt, time.Second)
+
+	report := status.Tasks[1]
+	assert.True(t, report.Running)
+	assert.Nil(t, report.LastError)
+	// A missed slot that runs once is due straight away
+	assert.WithinDuration(t, now, report.NextRunAt, time.Second)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSchedulesAdminEndpoint(t *testing.T) {
+	s, mock := NewTestServerWithConfig(&config.Config{Port: 8080, AdminToken: "secret"})
+
+	mock.ExpectQuery("SELECT (.+) FROM scheduler_leases").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "holder", "expires_at"}))
+	mock.ExpectQuery("SELECT (.+) FROM schedules").
+		WillReturnRows(sqlmock.NewRows(scheduleColumns))
+
+	req, _ := http.NewRequest("GET", "/admin/schedules", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"leader":""`)
+	assert.Contains(t, rr.Body.String(), `"name":"purge_idempotency_keys"`)
+	assert.Contains(t, rr.Body.String(), `"name":"purge_rate_limits"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
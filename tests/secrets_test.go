@@ -0,0 +1,196 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/secrets"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault serves a Vault KV v2 engine mounted at "secret".
+type fakeVault struct {
+	mu      sync.Mutex
+	token   string
+	secrets map[string]map[string]interface{}
+}
+
+func (v *fakeVault) set(path, key, value string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.secrets[path] == nil {
+		v.secrets[path] = map[string]interface{}{}
+	}
+	v.secrets[path][key] = value
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if r.Header.Get("X-Vault-Token") != v.token {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	data, ok := v.secrets[r.URL.Path[len("/v1/secret/data/"):]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{}})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{"data": data, "metadata": map[string]interface{}{"version": 1}},
+	})
+}
+
+func newFakeVault(t *testing.T) (*fakeVault, *httptest.Server) {
+	vault := &fakeVault{token: "root", secrets: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(vault)
+	t.Cleanup(server.Close)
+	return vault, server
+}
+
+func TestSecretFromFileVariant(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "db_password", "from-file\n")
+	t.Setenv("APP_ENV", "")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "app")
+	t.Setenv("DB_NAME", "app")
+	t.Setenv("DB_PASSWORD", "")
+	t.Setenv("DB_PASSWORD_FILE", path)
+
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", cfg.Database.Password)
+	file, ok := cfg.SecretFile("database.password")
+	assert.True(t, ok)
+	assert.Equal(t, path, file)
+
+	// Setting both is ambiguous
+	t.Setenv("DB_PASSWORD", "from-env")
+	_, err = config.Load(nil)
+	assert.ErrorContains(t, err, "both DB_PASSWORD and DB_PASSWORD_FILE are set")
+}
+
+func TestVaultProvider(t *testing.T) {
+	vault, server := newFakeVault(t)
+	vault.set("app/db", "password", "vault-password")
+
+	provider := secrets.NewVaultProvider(server.URL, "root", "secret")
+
+	value, err := provider.GetSecret(context.Background(), "app/db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "vault-password", value)
+
+	_, err = provider.GetSecret(context.Background(), "app/missing#password")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+
+	_, err = secrets.NewVaultProvider(server.URL, "wrong", "secret").GetSecret(context.Background(), "app/db#password")
+	assert.ErrorContains(t, err, "permission denied")
+}
+
+func TestConfigResolvesSecretReferences(t *testing.T) {
+	vault, server := newFakeVault(t)
+	vault.set("app/db", "password", "vault-password")
+	vault.set("app/jwt", "value", "vault-jwt")
+
+	t.Setenv("APP_ENV", "")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_HOST", "localhost")
+	t.Setenv("DB_USER", "app")
+	t.Setenv("DB_NAME", "app")
+	t.Setenv("DB_PASSWORD", "secret:app/db#password")
+	t.Setenv("JWT_SECRET", "secret:app/jwt")
+	t.Setenv("SECRETS_PROVIDER", "vault")
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "vault-password", cfg.Database.Password)
+	assert.Equal(t, "vault-jwt", cfg.JWTSecret)
+	assert.Contains(t, cfg.DatabaseURL, "app:vault-password@")
+
+	ref, ok := cfg.SecretRef("database.password")
+	assert.True(t, ok)
+	assert.Equal(t, "app/db#password", ref)
+}
+
+func TestEncryptedFileProvider(t *testing.T) {
+	key := make([]byte, 32)
+	rand.Read(key)
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	require.NoError(t, secrets.EncryptFile(path, key, map[string]interface{}{
+		"jwt": "file-jwt",
+		"db":  map[string]interface{}{"password": "file-password"},
+	}))
+
+	provider, err := secrets.NewEncryptedFileProvider(path, key)
+	require.NoError(t, err)
+
+	value, err := provider.GetSecret(context.Background(), "db#password")
+	require.NoError(t, err)
+	assert.Equal(t, "file-password", value)
+
+	// Wrong key cannot decrypt
+	otherKey := make([]byte, 32)
+	rand.Read(otherKey)
+	other, _ := secrets.NewEncryptedFileProvider(path, otherKey)
+	_, err = other.GetSecret(context.Background(), "jwt")
+	assert.Error(t, err)
+
+	// Through config
+	t.Setenv("APP_ENV", "")
+	t.Setenv("DATABASE_URL", "postgres://app@localhost:5432/app")
+	t.Setenv("JWT_SECRET", "secret:jwt")
+	t.Setenv("SECRETS_PROVIDER", "file")
+	t.Setenv("SECRETS_FILE", path)
+	t.Setenv("SECRETS_KEY", base64.StdEncoding.EncodeToString(key))
+
+	cfg, err := config.Load(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "file-jwt", cfg.JWTSecret)
+}
+
+func TestSecretRefresherPicksUpRotation(t *testing.T) {
+	vault, server := newFakeVault(t)
+	vault.set("app/db", "password", "v1")
+
+	provider := secrets.NewVaultProvider(server.URL, "root", "secret")
+	refresher := secrets.NewRefresher(provider, "app/db#password", "v1", 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go refresher.Run(ctx)
+
+	vault.set("app/db", "password", "v2")
+	assert.Eventually(t, func() bool { return refresher.Value() == "v2" }, time.Second, 10*time.Millisecond)
+}
+
+func TestSecretRefresherPicksUpRotatedFile(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "db_password", "v1\n")
+	refresher := secrets.NewRefresher(secrets.NewFileProvider(), path, "v1", 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go refresher.Run(ctx)
+
+	require.NoError(t, os.WriteFile(path, []byte("v2\n"), 0o600))
+	assert.Eventually(t, func() bool { return refresher.Value() == "v2" }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,121 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/security"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func preflight(handler http.Handler, path, origin, method string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("OPTIONS", path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", method)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCORSDevelopmentDefaults(t *testing.T) {
+	s, _ := NewTestServerWithConfig(config.Defaults(config.Development))
+	handler := s.RegisterRoutes()
+
+	rr := preflight(handler, "/examples/1", "http://localhost:3000", "PATCH")
+	assert.Equal(t, "http://localhost:3000", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "PATCH", rr.Header().Get("Access-Control-Allow-Methods"))
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+
+	// Arbitrary sites are no longer allowed outside production
+	rr = preflight(handler, "/examples/1", "https://evil.example.com", "GET")
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSRouteOverride(t *testing.T) {
+	cfg := config.Defaults(config.Production)
+	cfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+	cfg.CORS.Routes = []security.CORSRule{
+		{Path: "/health*", AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}},
+		{Path: "/healthz/live", AllowedOrigins: []string{"https://status.example.com"}},
+	}
+	s, _ := NewTestServerWithConfig(cfg)
+	handler := s.RegisterRoutes()
+
+	rr := preflight(handler, "/health", "https://anyone.example.com", "GET")
+	assert.Equal(t, "*", rr.Header().Get("Access-Control-Allow-Origin"))
+
+	rr = preflight(handler, "/health", "https://anyone.example.com", "POST")
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+	// The longest matching route wins and inherits the default methods
+	rr = preflight(handler, "/healthz/live", "https://status.example.com", "PUT")
+	assert.Equal(t, "https://status.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	rr = preflight(handler, "/healthz/live", "https://anyone.example.com", "GET")
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+
+	rr = preflight(handler, "/examples", "https://app.example.com", "POST")
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSRejectsWildcardWithCredentials(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	t.Setenv("DATABASE_URL", "postgres://app@db/app")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+
+	_, err := config.Load(nil)
+	var verr *config.ValidationError
+	require.ErrorAs(t, err, &verr)
+	assert.Contains(t, verr.Problems, "cors: allowing credentials from any origin (*) is not permitted")
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	cfg := config.Defaults(config.Production)
+	s, _ := NewTestServerWithConfig(cfg)
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	h := rr.Header()
+	assert.Equal(t, "max-age=31536000; includeSubDomains", h.Get("Strict-Transport-Security"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'", h.Get("Content-Security-Policy"))
+	assert.Empty(t, h.Get("Content-Security-Policy-Report-Only"))
+	assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+	assert.Equal(t, "no-referrer", h.Get("Referrer-Policy"))
+	assert.Equal(t, "camera=(), microphone=(), geolocation=(), payment=()", h.Get("Permissions-Policy"))
+	assert.Equal(t, "DENY", h.Get("X-Frame-Options"))
+}
+
+func TestSecurityHeadersReportOnly(t *testing.T) {
+	cfg := config.Defaults(config.Development)
+	cfg.SecurityHeaders.ReportURI = "https://csp.example.com/report"
+	s, _ := NewTestServerWithConfig(cfg)
+	handler := s.RegisterRoutes()
+
+	get := func() http.Header {
+		req, _ := http.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Header()
+	}
+
+	h := get()
+	assert.Empty(t, h.Get("Content-Security-Policy"))
+	assert.Equal(t, "default-src 'none'; frame-ancestors 'none'; report-uri https://csp.example.com/report", h.Get("Content-Security-Policy-Report-Only"))
+	assert.Empty(t, h.Get("Strict-Transport-Security"))
+
+	// Switching to enforcement is a reload away
+	next := *cfg
+	next.SecurityHeaders.ReportOnly = false
+	next.SecurityHeaders.HSTSMaxAge = time.Hour
+	s.Config().Apply(&next)
+
+	h = get()
+	assert.NotEmpty(t, h.Get("Content-Security-Policy"))
+	assert.Equal(t, "max-age=3600", h.Get("Strict-Transport-Security"))
+}
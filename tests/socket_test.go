@@ -0,0 +1,203 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/ctrixcode/go-chi-postgres/internal/auth"
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/handlers"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const socketSecret = "socket-secret"
+
+func signToken(t *testing.T, secret string) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   "client",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+// expectSocketChange expects the feed to read a change to streamExampleID.
+func expectSocketChange(mock sqlmock.Sqlmock, id int64, changeType, previous, data string) {
+	var prev []byte
+	if previous != "" {
+		prev = []byte(previous)
+	}
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(changeColumns).AddRow(id, changeType, streamExampleID, []byte(data), time.Now(), prev))
+}
+
+func dialSocket(t *testing.T, url string, header http.Header) *websocket.Conn {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(url, "http"), &websocket.DialOptions{HTTPHeader: header})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+func readSocket(t *testing.T, conn *websocket.Conn) models.ExampleSocketMessage {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var msg models.ExampleSocketMessage
+	require.NoError(t, wsjson.Read(ctx, conn, &msg))
+	return msg
+}
+
+func sendSocket(t *testing.T, conn *websocket.Conn, req any) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, wsjson.Write(ctx, conn, req))
+}
+
+func TestExampleSocketSendsFilteredChanges(t *testing.T) {
+	db, mock := newJobsMock(t)
+	l := newFakeListener()
+	opts := testStreamOptions
+	opts.MaxSubscriptions = 2
+	feed := changefeed.New(db, l.listen, func() changefeed.Options { return opts }, metrics.New())
+	startFeed(t, mock, feed, l, 4)
+
+	authenticator := auth.NewAuthenticator(func() string { return socketSecret })
+	srv := httptest.NewServer(handlers.NewExampleHandler(nil, feed, authenticator).RegisterRoutes())
+	t.Cleanup(srv.Close)
+
+	conn := dialSocket(t, srv.URL+"/ws", http.Header{"Authorization": {"Bearer " + signToken(t, socketSecret)}})
+	sendSocket(t, conn, map[string]any{"type": "subscribe", "id": "premium", "filter": map[string]any{"is_premium": true}})
+	assert.Equal(t, models.ExampleSocketMessage{Type: models.SocketSubscribed, ID: "premium"}, readSocket(t, conn))
+
+	// Created premium: added
+	expectSocketChange(mock, 5, models.EventExampleCreated, "", `{"name": "a", "is_premium": true}`)
+	l.notify <- "5"
+	msg := readSocket(t, conn)
+	assert.Equal(t, models.SocketAdded, msg.Type)
+	assert.Equal(t, "premium", msg.ID)
+	assert.Equal(t, int64(5), msg.ChangeID)
+	assert.JSONEq(t, `{"name": "a", "is_premium": true}`, string(msg.Example))
+
+	// Renamed: updated with the changed field only
+	expectSocketChange(mock, 6, models.EventExampleUpdated, `{"name": "a", "is_premium": true}`, `{"name": "b", "is_premium": true}`)
+	l.notify <- "6"
+	msg = readSocket(t, conn)
+	assert.Equal(t, models.SocketUpdated, msg.Type)
+	assert.Equal(t, map[string]json.RawMessage{"name": json.RawMessage(`"b"`)}, msg.Changes)
+
+	// Not premium any more: removed, then nothing for changes outside the filter
+	expectSocketChange(mock, 7, models.EventExampleUpdated, `{"name": "b", "is_premium": true}`, `{"name": "b", "is_premium": false}`)
+	l.notify <- "7"
+	assert.Equal(t, models.SocketRemoved, readSocket(t, conn).Type)
+	expectSocketChange(mock, 8, models.EventExampleDeleted, `{"name": "b", "is_premium": false}`, `{"id": "`+streamExampleID+`"}`)
+	l.notify <- "8"
+	require.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, 5*time.Millisecond)
+
+	sendSocket(t, conn, map[string]any{"type": "unsubscribe", "id": "premium"})
+	assert.Equal(t, models.ExampleSocketMessage{Type: models.SocketUnsubscribed, ID: "premium"}, readSocket(t, conn))
+	sendSocket(t, conn, map[string]any{"type": "unsubscribe", "id": "premium"})
+	assert.Equal(t, models.SocketError, readSocket(t, conn).Type)
+
+	// Invalid requests and subscriptions past the limit are refused
+	sendSocket(t, conn, map[string]any{"type": "watch", "id": "x"})
+	assert.Equal(t, models.SocketError, readSocket(t, conn).Type)
+	for _, id := range []string{"a", "b", "c"} {
+		sendSocket(t, conn, map[string]any{"type": "subscribe", "id": id})
+	}
+	assert.Equal(t, models.SocketSubscribed, readSocket(t, conn).Type)
+	assert.Equal(t, models.SocketSubscribed, readSocket(t, conn).Type)
+	assert.Equal(t, models.ExampleSocketMessage{Type: models.SocketError, ID: "c", Error: "Too many subscriptions"}, readSocket(t, conn))
+
+	conn.Close(websocket.StatusNormalClosure, "")
+}
+
+// newSocketServer is NewTestServer with authentication and stream settings.
+func newSocketServer(t *testing.T) *httptest.Server {
+	s, _ := NewTestServerWithConfig(&config.Config{
+		Port:      8080,
+		JWTSecret: socketSecret,
+		OpenAPI:   config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: openapi.ResponsesStrict},
+		Stream:    config.StreamConfig{Heartbeat: time.Hour, WriteTimeout: time.Second, Buffer: 8, MaxSubscriptions: 20},
+	})
+	srv := httptest.NewServer(s.RegisterRoutes())
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NoError(t, s.ChangeFeed().Shutdown(ctx))
+	})
+	return srv
+}
+
+func TestExampleSocketRequiresToken(t *testing.T) {
+	srv := newSocketServer(t)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/examples/ws"
+
+	for name, header := range map[string]http.Header{
+		"missing":    nil,
+		"bad secret": {"Authorization": {"Bearer " + signToken(t, "other")}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, resp, err := websocket.Dial(context.Background(), url, &websocket.DialOptions{HTTPHeader: header})
+			require.Error(t, err)
+			require.NotNil(t, resp)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		})
+	}
+}
+
+func TestExampleSocketRequiresUpgrade(t *testing.T) {
+	s, _ := NewTestServer()
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/examples/ws", nil))
+	assert.Equal(t, http.StatusUpgradeRequired, rr.Code)
+	assert.Contains(t, rr.Body.String(), "UPGRADE_REQUIRED")
+}
+
+func TestExampleSocketClosesOnShutdown(t *testing.T) {
+	s, _ := NewTestServerWithConfig(&config.Config{
+		Port:      8080,
+		JWTSecret: socketSecret,
+		OpenAPI:   config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: openapi.ResponsesStrict},
+		Stream:    config.StreamConfig{Heartbeat: time.Hour, WriteTimeout: time.Second, Buffer: 8, MaxSubscriptions: 20},
+	})
+	srv := httptest.NewServer(s.RegisterRoutes())
+	t.Cleanup(srv.Close)
+
+	// Browsers pass the token as a parameter
+	conn := dialSocket(t, srv.URL+"/examples/ws?access_token="+signToken(t, socketSecret), nil)
+	sendSocket(t, conn, map[string]any{"type": "subscribe", "id": "all"})
+	assert.Equal(t, models.SocketSubscribed, readSocket(t, conn).Type)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		shutdown <- s.ChangeFeed().Shutdown(ctx)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, _, err := conn.Read(ctx)
+	assert.Equal(t, websocket.StatusGoingAway, websocket.CloseStatus(err))
+	assert.NoError(t, <-shutdown, "shutdown waits for the socket to close")
+}
@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ctrixcode/go-chi-postgres/internal/sqlgen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSQLGenUpToDate fails when a migration or query file changed without
+// regenerating internal/database/queries.
+func TestSQLGenUpToDate(t *testing.T) {
+	outdated, err := sqlgen.Check("..", sqlgen.DefaultConfig)
+	require.NoError(t, err)
+	assert.Empty(t, outdated, "run go run ./cmd/tools/sqlgen")
+}
+
+var sqlgenTestConfig = sqlgen.Config{Migrations: "migrations", Queries: "queries", Out: "out", Package: "out"}
+
+const sqlgenTestMigration = `-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE authors (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    bio TEXT
+);
+-- +goose StatementEnd
+
+CREATE TABLE books (
+    id UUID NOT NULL,
+    author_id BIGINT REFERENCES authors (id),
+    title TEXT NOT NULL,
+    price NUMERIC(10, 2),
+    PRIMARY KEY (id)
+);
+
+-- +goose Down
+DROP TABLE books;
+DROP TABLE authors;
+`
+
+func TestSQLGenSchema(t *testing.T) {
+	root := sqlgenRoot(t)
+	writeFile(t, root, "migrations/003_alter.sql", `-- +goose Up
+ALTER TABLE books ADD COLUMN pages INTEGER NOT NULL DEFAULT 0, DROP COLUMN price;
+ALTER TABLE books RENAME COLUMN title TO name;
+ALTER TABLE authors ALTER COLUMN bio SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE authors ALTER COLUMN bio DROP NOT NULL;
+`)
+
+	schema, err := sqlgen.LoadSchema(filepath.Join(root, "migrations"))
+	require.NoError(t, err)
+
+	books := schema.Table("books")
+	require.NotNil(t, books)
+	assert.Equal(t, []sqlgen.Column{
+		{Name: "id", Type: "uuid", NotNull: true},
+		{Name: "author_id", Type: "bigint"},
+		{Name: "name", Type: "text", NotNull: true},
+		{Name: "pages", Type: "integer", NotNull: true},
+	}, derefColumns(books.Columns))
+	assert.Equal(t, sqlgen.Column{Name: "bio", Type: "text", NotNull: true}, *schema.Table("authors").Column("bio"))
+}
+
+func TestSQLGenRender(t *testing.T) {
+	root := sqlgenRoot(t)
+	writeFile(t, root, "queries/books.sql", `-- name: GetBook :one
+-- GetBook returns a book by id.
+SELECT * FROM books WHERE id = $1;
+
+-- name: ListBooks :many
+SELECT b.id, b.title, a.name AS author FROM books b LEFT JOIN authors a ON a.id = b.author_id
+WHERE b.price > $1 ORDER BY b.title LIMIT $2 OFFSET $3;
+
+-- name: CreateBook :one
+INSERT INTO books (id, author_id, title) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: CountBooks :one
+SELECT count(*) FROM books WHERE author_id = $1::bigint;
+
+-- name: DeleteBooks :execrows
+DELETE FROM books WHERE id = ANY($1);
+`)
+
+	files, err := sqlgen.Render(root, sqlgenTestConfig)
+	require.NoError(t, err)
+	require.Len(t, files, 3)
+	for _, f := range files {
+		_, err := parser.ParseFile(token.NewFileSet(), f.Path, f.Content, 0)
+		assert.NoError(t, err, f.Path)
+	}
+	code := string(files[2].Content)
+	assert.Equal(t, filepath.Join("out", "books.sql.go"), files[2].Path)
+
+	for _, want := range []string{
+		"const getBook = `SELECT id, author_id, title, price FROM books WHERE id = $1`",
+		"// GetBook returns a book by id.\nfunc (q *Queries) GetBook(ctx context.Context, id uuid.UUID) (Book, error) {",
+		"func (q *Queries) ListBooks(ctx context.Context, arg ListBooksParams) ([]ListBooksRow, error) {",
+		// Compared values are never NULL, LIMIT and OFFSET are bigints
+		"Price  string\n\tLimit  int64\n\tOffset int64",
+		// The outer side of a LEFT JOIN may be NULL
+		"Author sql.Null[string]",
+		// Stored values may be NULL when the column allows it
+		"AuthorID sql.Null[int64]",
+		"RETURNING id, author_id, title, price`",
+		"func (q *Queries) CreateBook(ctx context.Context, arg CreateBookParams) (Book, error) {",
+		"func (q *Queries) CountBooks(ctx context.Context, authorID int64) (int64, error) {",
+		"func (q *Queries) DeleteBooks(ctx context.Context, ids []uuid.UUID) (int64, error) {",
+	} {
+		assert.Contains(t, code, want)
+	}
+	assert.Contains(t, string(files[1].Content), "type Author struct {")
+}
+
+func TestSQLGenRejectsQueries(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"SELECT nope FROM books", "unknown column nope"},
+		{"SELECT * FROM nope", "unknown table nope"},
+		{"SELECT id FROM books WHERE title = $2", "$1 is never used"},
+		{"SELECT id FROM books WHERE $1 IS NULL", "cannot infer the type of $1, add a cast such as $1::text"},
+		{"SELECT upper(title) || '!' AS shout FROM books", `cannot infer the type of "upper(title) || '!'"`},
+		{"SELECT id FROM authors JOIN books ON true", "column reference id is ambiguous"},
+		{"DELETE FROM books; DELETE FROM authors", "a query must be a single statement"},
+	}
+
+	for _, tt := range tests {
+		root := sqlgenRoot(t)
+		writeFile(t, root, "queries/books.sql", "-- name: Query :one\n"+tt.query+"\n")
+
+		_, err := sqlgen.Render(root, sqlgenTestConfig)
+		if assert.Error(t, err, tt.query) {
+			assert.Contains(t, err.Error(), tt.err)
+		}
+	}
+}
+
+func TestSQLGenCheck(t *testing.T) {
+	root := sqlgenRoot(t)
+	writeFile(t, root, "queries/books.sql", "-- name: GetBook :one\nSELECT * FROM books WHERE id = $1;\n")
+
+	_, err := sqlgen.Write(root, sqlgenTestConfig)
+	require.NoError(t, err)
+	outdated, err := sqlgen.Check(root, sqlgenTestConfig)
+	require.NoError(t, err)
+	assert.Empty(t, outdated)
+
+	// Only queried tables get a model, so new tables leave the code alone
+	models, err := os.ReadFile(filepath.Join(root, "out", "models.go"))
+	require.NoError(t, err)
+	assert.Contains(t, string(models), "type Book struct")
+	assert.NotContains(t, string(models), "type Author struct")
+	writeFile(t, root, "migrations/002_widgets.sql", "-- +goose Up\nCREATE TABLE widgets (id UUID PRIMARY KEY);\n")
+	outdated, err = sqlgen.Check(root, sqlgenTestConfig)
+	require.NoError(t, err)
+	assert.Empty(t, outdated)
+
+	// A migration changing a queried table makes the code out of date
+	writeFile(t, root, "migrations/003_alter.sql", "-- +goose Up\nALTER TABLE books ADD COLUMN pages INTEGER;\n")
+	outdated, err = sqlgen.Check(root, sqlgenTestConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("out", "books.sql.go"), filepath.Join("out", "models.go")}, outdated)
+
+	// So does removing a query file
+	_, err = sqlgen.Write(root, sqlgenTestConfig)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(root, "queries/books.sql")))
+	outdated, err = sqlgen.Check(root, sqlgenTestConfig)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join("out", "books.sql.go"), filepath.Join("out", "models.go")}, outdated)
+}
+
+// sqlgenRoot returns a directory laid out as sqlgenTestConfig expects, with
+// the initial migration in place.
+func sqlgenRoot(t *testing.T) string {
+	root := t.TempDir()
+	for _, dir := range []string{"migrations", "queries"} {
+		require.NoError(t, os.Mkdir(filepath.Join(root, dir), 0o755))
+	}
+	writeFile(t, root, "migrations/001_init.sql", sqlgenTestMigration)
+	return root
+}
+
+func derefColumns(columns []*sqlgen.Column) []sqlgen.Column {
+	out := make([]sqlgen.Column, len(columns))
+	for i, c := range columns {
+		out[i] = *c
+	}
+	return out
+}
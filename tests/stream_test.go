@@ -0,0 +1,289 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/changefeed"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/handlers"
+	"github.com/ctrixcode/go-chi-postgres/internal/metrics"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/openapi"
+	"github.com/ctrixcode/go-chi-postgres/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var changeColumns = []string{"id", "type", "example_id", "data", "created_at", "previous"}
+
+const streamExampleID = "0b6f1a52-3c8e-4f7a-9d2b-5e4c3a2b1f00"
+
+func changeRow(id int64, changeType string) []driver.Value {
+	return []driver.Value{id, changeType, streamExampleID, []byte(fmt.Sprintf(`{"id": "%s", "name": "change %d"}`, streamExampleID, id)), time.Now(), nil}
+}
+
+func changeRows(rows ...[]driver.Value) *sqlmock.Rows {
+	result := sqlmock.NewRows(changeColumns)
+	for _, row := range rows {
+		result.AddRow(row...)
+	}
+	return result
+}
+
+var testStreamOptions = changefeed.Options{Buffer: 8, Heartbeat: time.Hour, WriteTimeout: time.Second, Retry: 3 * time.Second}
+
+// fakeListener stands in for LISTEN: the test sends notifications on notify
+// and ends the connection with fail.
+type fakeListener struct {
+	notify    chan string
+	fail      chan struct{}
+	connected chan struct{}
+}
+
+func newFakeListener() *fakeListener {
+	return &fakeListener{notify: make(chan string), fail: make(chan struct{}), connected: make(chan struct{}, 1)}
+}
+
+func (l *fakeListener) listen(ctx context.Context, channel string, listening func(), fn func(string)) error {
+	listening()
+	l.connected <- struct{}{}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.fail:
+			return errors.New("connection lost")
+		case payload := <-l.notify:
+			fn(payload)
+		}
+	}
+}
+
+// startFeed starts a feed on a listener whose log ends at change last.
+func startFeed(t *testing.T, mock sqlmock.Sqlmock, feed *changefeed.Feed, l *fakeListener, last int64) {
+	mock.ExpectQuery("SELECT id FROM example_changes ORDER BY id DESC").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(last))
+	feed.Start()
+	<-l.connected
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NoError(t, feed.Shutdown(ctx))
+	})
+}
+
+// readEvent reads the fields of the next event, comments under ":".
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+	event := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return event
+		}
+		field, value, _ := strings.Cut(line, ":")
+		event[field] = strings.TrimPrefix(value, " ")
+	}
+}
+
+func openStream(t *testing.T, url, lastEventID string) (*http.Response, *bufio.Reader) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	body := bufio.NewReader(resp.Body)
+	assert.Equal(t, map[string]string{"retry": "3000"}, readEvent(t, body))
+	return resp, body
+}
+
+func TestExampleStreamSendsChangesPastWriteTimeout(t *testing.T) {
+	db, mock := newJobsMock(t)
+	l := newFakeListener()
+	opts := testStreamOptions
+	opts.Heartbeat = 50 * time.Millisecond
+	feed := changefeed.New(db, l.listen, func() changefeed.Options { return opts }, metrics.New())
+	startFeed(t, mock, feed, l, 4)
+
+	srv := httptest.NewUnstartedServer(handlers.NewExampleHandler(nil, feed, nil).RegisterRoutes())
+	srv.Config.ReadTimeout = 50 * time.Millisecond
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	_, body := openStream(t, srv.URL+"/stream", "")
+	time.Sleep(150 * time.Millisecond)
+
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(int64(5)).
+		WillReturnRows(changeRows(changeRow(5, models.EventExampleCreated)))
+	l.notify <- "5"
+
+	event := readEvent(t, body)
+	for event[""] == "heartbeat" {
+		event = readEvent(t, body)
+	}
+	assert.Equal(t, map[string]string{
+		"id":    "5",
+		"event": models.EventExampleCreated,
+		"data":  fmt.Sprintf(`{"id":"%s","name":"change 5"}`, streamExampleID),
+	}, event)
+	assert.Equal(t, map[string]string{"": "heartbeat"}, readEvent(t, body))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// newStreamServer is NewTestServer with stream settings.
+func newStreamServer() (*server.Server, sqlmock.Sqlmock) {
+	return NewTestServerWithConfig(&config.Config{
+		Port:    8080,
+		OpenAPI: config.OpenAPIConfig{ValidateRequests: true, ValidateResponses: openapi.ResponsesStrict},
+		Stream:  config.StreamConfig{Heartbeat: time.Hour, WriteTimeout: time.Second, Retry: 3 * time.Second, Buffer: 8},
+	})
+}
+
+func TestExampleStreamResumesFromLastEventID(t *testing.T) {
+	s, mock := newStreamServer()
+	srv := httptest.NewServer(s.RegisterRoutes())
+	t.Cleanup(srv.Close)
+
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(int64(3)).
+		WillReturnRows(changeRows(changeRow(3, models.EventExampleCreated)))
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id >").WithArgs(int64(3), int64(500)).
+		WillReturnRows(changeRows(changeRow(4, models.EventExampleUpdated), changeRow(5, models.EventExampleDeleted)))
+
+	_, body := openStream(t, srv.URL+"/examples/stream", "3")
+	assert.Equal(t, "4", readEvent(t, body)["id"])
+	event := readEvent(t, body)
+	assert.Equal(t, "5", event["id"])
+	assert.Equal(t, models.EventExampleDeleted, event["event"])
+
+	// Shutting the feed down ends the stream
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, s.ChangeFeed().Shutdown(ctx))
+	_, err := body.ReadString('\n')
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExampleStreamResumesWhileChangesArrive(t *testing.T) {
+	db, mock := newJobsMock(t)
+	mock.MatchExpectationsInOrder(false)
+	l := newFakeListener()
+	feed := changefeed.New(db, l.listen, func() changefeed.Options { return testStreamOptions }, metrics.New())
+	startFeed(t, mock, feed, l, 3)
+
+	srv := httptest.NewServer(handlers.NewExampleHandler(nil, feed, nil).RegisterRoutes())
+	t.Cleanup(srv.Close)
+
+	// Changes 5 and 6 commit while the replay is still reading the log, so
+	// 5 arrives both ways
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(int64(3)).
+		WillReturnRows(changeRows(changeRow(3, models.EventExampleCreated)))
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id >").WithArgs(int64(3), int64(500)).
+		WillDelayFor(100 * time.Millisecond).
+		WillReturnRows(changeRows(changeRow(4, models.EventExampleUpdated), changeRow(5, models.EventExampleUpdated)))
+	for _, id := range []int64{5, 6, 7} {
+		mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(id).
+			WillReturnRows(changeRows(changeRow(id, models.EventExampleUpdated)))
+	}
+
+	_, body := openStream(t, srv.URL+"/stream", "3")
+	l.notify <- "5"
+	l.notify <- "6"
+
+	var got []string
+	for range 3 {
+		got = append(got, readEvent(t, body)["id"])
+	}
+	assert.Equal(t, []string{"4", "5", "6"}, got)
+
+	// Nothing was sent twice: the next event is the next change
+	l.notify <- "7"
+	assert.Equal(t, "7", readEvent(t, body)["id"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExampleStreamResetsWhenLogExpired(t *testing.T) {
+	s, mock := newStreamServer()
+	srv := httptest.NewServer(s.RegisterRoutes())
+	t.Cleanup(srv.Close)
+	defer s.ChangeFeed().Shutdown(context.Background())
+
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(int64(3)).
+		WillReturnRows(changeRows())
+
+	_, body := openStream(t, srv.URL+"/examples/stream", "3")
+	assert.Equal(t, map[string]string{"event": "reset", "data": "{}"}, readEvent(t, body))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExampleStreamRejectsInvalidLastEventID(t *testing.T) {
+	s, _ := NewTestServer()
+	req := httptest.NewRequest(http.MethodGet, "/examples/stream", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestChangeFeedDropsSlowSubscribersAndCatchesUp(t *testing.T) {
+	db, mock := newJobsMock(t)
+	l := newFakeListener()
+	opts := testStreamOptions
+	opts.Buffer = 1
+	feed := changefeed.New(db, l.listen, func() changefeed.Options { return opts }, metrics.New())
+	startFeed(t, mock, feed, l, 4)
+
+	slow := feed.Subscribe()
+	defer slow.Close()
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(int64(5)).
+		WillReturnRows(changeRows(changeRow(5, models.EventExampleCreated)))
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id").WithArgs(int64(6)).
+		WillReturnRows(changeRows(changeRow(6, models.EventExampleUpdated)))
+	l.notify <- "5"
+	l.notify <- "6"
+	require.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, time.Second, 5*time.Millisecond)
+
+	c, ok := <-slow.Changes()
+	require.True(t, ok)
+	assert.Equal(t, int64(5), c.ID)
+	_, ok = <-slow.Changes()
+	assert.False(t, ok, "a subscriber falling behind is dropped")
+	assert.ErrorIs(t, slow.Err(), changefeed.ErrFellBehind)
+
+	// Changes made while reconnecting are read from the log
+	sub := feed.Subscribe()
+	defer sub.Close()
+	mock.ExpectQuery("SELECT (.+) FROM example_changes WHERE id >").WithArgs(int64(6), int64(500)).
+		WillReturnRows(changeRows(changeRow(7, models.EventExampleDeleted)))
+	l.fail <- struct{}{}
+	<-l.connected
+
+	select {
+	case c := <-sub.Changes():
+		assert.Equal(t, int64(7), c.ID)
+		assert.Equal(t, models.EventExampleDeleted, c.Type)
+	case <-time.After(time.Second):
+		t.Fatal("missed change not replayed")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
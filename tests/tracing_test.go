@@ -0,0 +1,149 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/database"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	tracingOnce     sync.Once
+	tracingExporter *tracetest.InMemoryExporter
+)
+
+// setupTracing returns an empty exporter recording every span. Package
+// tracers bind to the first global provider set, so all tests share one.
+func setupTracing(t *testing.T) *tracetest.InMemoryExporter {
+	tracingOnce.Do(func() {
+		tracingExporter = tracetest.NewInMemoryExporter()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracingExporter)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	tracingExporter.Reset()
+	t.Cleanup(tracingExporter.Reset)
+	return tracingExporter
+}
+
+func findSpan(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for i := range spans {
+		if spans[i].Name == name {
+			return &spans[i]
+		}
+	}
+	return nil
+}
+
+func spanAttr(span *tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingSpans(t *testing.T) {
+	exporter := setupTracing(t)
+	s, mock := NewTestServer()
+	defer mock.ExpectationsWereMet()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "lucky_number", "is_premium", "created_at", "updated_at"}).
+		AddRow(uuid.New(), "Example 1", 10.0, true, time.Now(), time.Now()).
+		AddRow(uuid.New(), "Example 2", 20.0, false, time.Now(), time.Now())
+	mock.ExpectQuery(`SELECT (.+) FROM examples`).
+		WillReturnRows(rows)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, _ := http.NewRequest("GET", "/examples/?limit=5", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	spans := exporter.GetSpans()
+	httpSpan := findSpan(spans, "GET /examples")
+	serviceSpan := findSpan(spans, "ExampleService.List")
+	sqlSpan := findSpan(spans, "SELECT examples")
+	require.NotNil(t, httpSpan)
+	require.NotNil(t, serviceSpan)
+	require.NotNil(t, sqlSpan)
+
+	// All spans belong to the incoming trace and are correctly nested
+	assert.Equal(t, traceID, httpSpan.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", httpSpan.Parent.SpanID().String())
+	assert.Equal(t, httpSpan.SpanContext.SpanID(), serviceSpan.Parent.SpanID())
+	assert.Equal(t, serviceSpan.SpanContext.SpanID(), sqlSpan.Parent.SpanID())
+
+	assert.Equal(t, trace.SpanKindServer, httpSpan.SpanKind)
+	assert.Equal(t, int64(200), spanAttr(httpSpan, "http.response.status_code").AsInt64())
+	assert.Equal(t, "SELECT id, name, lucky_number, is_premium, created_at, updated_at FROM examples LIMIT ? OFFSET ?", spanAttr(sqlSpan, "db.query.text").AsString())
+	assert.Equal(t, int64(2), spanAttr(sqlSpan, "db.response.returned_rows").AsInt64())
+}
+
+func TestTracingGeneratedQueriesIncludeReadingRows(t *testing.T) {
+	exporter := setupTracing(t)
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	q := database.NewQueries(sqlx.NewDb(sqlDB, "sqlmock"))
+	ctx := context.Background()
+
+	// Scan errors fail the span of a single row query
+	mock.ExpectQuery(`SELECT (.+) FROM examples WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(exampleColumns).AddRow("not-a-uuid", "Bad", 1.0, true, time.Now(), time.Now()))
+	_, err = q.GetExample(ctx, uuid.New())
+	require.Error(t, err)
+
+	span := findSpan(exporter.GetSpans(), "SELECT examples")
+	require.NotNil(t, span)
+	assert.Equal(t, codes.Error, span.Status.Code)
+	assert.Equal(t, int64(0), spanAttr(span, "db.response.returned_rows").AsInt64())
+	exporter.Reset()
+
+	// A missing row is not a failure
+	mock.ExpectQuery(`SELECT (.+) FROM examples WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows(exampleColumns))
+	_, err = q.GetExample(ctx, uuid.New())
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	span = findSpan(exporter.GetSpans(), "SELECT examples")
+	require.NotNil(t, span)
+	assert.Equal(t, codes.Unset, span.Status.Code)
+	exporter.Reset()
+
+	// Spans of queries returning rows end once the rows are read
+	now := time.Now()
+	mock.ExpectQuery(`SELECT (.+) FROM schedules`).
+		WillReturnRows(sqlmock.NewRows(scheduleColumns).
+			AddRow("purge", now, now, now, nil, now).
+			AddRow("report", now, now, now, nil, now).
+			RowError(1, errors.New("connection reset")))
+	_, err = q.ListSchedules(ctx)
+	require.Error(t, err)
+
+	span = findSpan(exporter.GetSpans(), "SELECT schedules")
+	require.NotNil(t, span)
+	assert.Equal(t, codes.Error, span.Status.Code)
+	assert.Equal(t, "connection reset", span.Status.Description)
+	assert.Equal(t, int64(1), spanAttr(span, "db.response.returned_rows").AsInt64())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
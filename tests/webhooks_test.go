@@ -0,0 +1,374 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/ctrixcode/go-chi-postgres/internal/config"
+	"github.com/ctrixcode/go-chi-postgres/internal/events"
+	"github.com/ctrixcode/go-chi-postgres/internal/jobs"
+	"github.com/ctrixcode/go-chi-postgres/internal/models"
+	"github.com/ctrixcode/go-chi-postgres/internal/webhooks"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebhookSecret = "whsec_0123456789abcdef"
+
+var webhookColumns = []string{"id", "url", "events", "secret", "enabled", "consecutive_failures", "disabled_at", "created_at", "updated_at"}
+
+func webhookRow(id uuid.UUID, url, patterns string, enabled bool) []driver.Value {
+	now := time.Now()
+	return []driver.Value{id, url, []byte(patterns), testWebhookSecret, enabled, 0, nil, now, now}
+}
+
+// The receivers in these tests listen on loopback
+var testWebhookOptions = webhooks.Options{Timeout: time.Second, MaxAttempts: 5, DisableAfter: 3, AllowPrivateNetworks: true}
+
+func newWebhooksService(db *sqlx.DB) *webhooks.Service {
+	return webhooks.NewService(db, jobs.NewClient(db), func() webhooks.Options { return testWebhookOptions })
+}
+
+// deliveryJob returns a claimed job delivering an example.created event.
+func deliveryJob(t *testing.T, webhookID uuid.UUID, attempt int) []driver.Value {
+	payload, err := json.Marshal(webhooks.DeliverArgs{
+		WebhookID: webhookID,
+		Event:     events.Event{ID: 42, Type: models.EventExampleCreated, AggregateType: models.ExampleAggregate, AggregateID: "a1", Payload: json.RawMessage(`{"id":"a1"}`)},
+	})
+	require.NoError(t, err)
+	return claimedJob(7, "deliver_webhook", string(payload), attempt, testWebhookOptions.MaxAttempts)
+}
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+	header := http.Header{}
+	header.Set(webhooks.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	header.Set(webhooks.HeaderSignature, webhooks.Sign(testWebhookSecret, now, body))
+
+	assert.NoError(t, webhooks.Verify(testWebhookSecret, header, body, time.Minute))
+	assert.ErrorIs(t, webhooks.Verify("whsec_other", header, body, time.Minute), webhooks.ErrInvalidSignature)
+	assert.ErrorIs(t, webhooks.Verify(testWebhookSecret, header, []byte(`{"id":2}`), time.Minute), webhooks.ErrInvalidSignature)
+
+	// A captured delivery replayed later is rejected
+	old := now.Add(-10 * time.Minute)
+	header.Set(webhooks.HeaderTimestamp, strconv.FormatInt(old.Unix(), 10))
+	header.Set(webhooks.HeaderSignature, webhooks.Sign(testWebhookSecret, old, body))
+	assert.ErrorIs(t, webhooks.Verify(testWebhookSecret, header, body, 5*time.Minute), webhooks.ErrStaleTimestamp)
+}
+
+func TestWebhookDeliverySignsAndLogs(t *testing.T) {
+	var verifyErr error
+	var received events.Event
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = webhooks.Verify(testWebhookSecret, r.Header, body, time.Minute)
+		json.Unmarshal(body, &received)
+		assert.Equal(t, "42", r.Header.Get(webhooks.HeaderID))
+		assert.Equal(t, models.EventExampleCreated, r.Header.Get(webhooks.HeaderEvent))
+		w.Write([]byte("ok"))
+	}))
+	defer receiver.Close()
+
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	newWebhooksService(db).Register(registry)
+	id := uuid.New()
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, receiver.URL, `["example.*"]`, true)...))
+	mock.ExpectQuery("INSERT INTO webhook_deliveries").
+		WithArgs(id, int64(42), models.EventExampleCreated, sqlmock.AnyArg(), int64(1), int64(200), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec("UPDATE webhooks SET consecutive_failures = 0").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE jobs SET state = 'completed'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	runJob(t, db, mock, registry, deliveryJob(t, id, 1))
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, int64(42), received.ID)
+	assert.Equal(t, "a1", received.AggregateID)
+}
+
+func TestWebhookDeliveryRetriesFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	newWebhooksService(db).Register(registry)
+	id := uuid.New()
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, receiver.URL, `["*"]`, true)...))
+	mock.ExpectQuery("INSERT INTO webhook_deliveries").
+		WithArgs(id, int64(42), models.EventExampleCreated, sqlmock.AnyArg(), int64(1), int64(503),
+			"webhook responded 503 Service Unavailable", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"consecutive_failures"}).AddRow(1))
+	mock.ExpectExec("UPDATE jobs SET state = 'available'").
+		WithArgs(sqlmock.AnyArg(), "webhook responded 503 Service Unavailable", sqlmock.AnyArg(), int64(7), int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	runJob(t, db, mock, registry, deliveryJob(t, id, 1))
+}
+
+func TestWebhookDisabledAfterRepeatedFailures(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer receiver.Close()
+
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	newWebhooksService(db).Register(registry)
+	id := uuid.New()
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, receiver.URL, `["*"]`, true)...))
+	mock.ExpectQuery("INSERT INTO webhook_deliveries").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	// The third failure in a row reaches DisableAfter
+	mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+		WillReturnRows(sqlmock.NewRows([]string{"consecutive_failures"}).AddRow(3))
+	mock.ExpectExec("UPDATE webhooks SET enabled = false").
+		WithArgs(sqlmock.AnyArg(), id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE jobs SET state = 'dead'").
+		WithArgs(sqlmock.AnyArg(), "webhook responded 410 Gone", int64(7), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	runJob(t, db, mock, registry, deliveryJob(t, id, 2))
+}
+
+func TestWebhookDeliveryRefusesPrivateAddresses(t *testing.T) {
+	var hit atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hit.Store(true)
+	}))
+	defer receiver.Close()
+
+	db, mock := newJobsMock(t)
+	registry := jobs.NewRegistry()
+	opts := testWebhookOptions
+	opts.AllowPrivateNetworks = false
+	webhooks.NewService(db, jobs.NewClient(db), func() webhooks.Options { return opts }).Register(registry)
+	id := uuid.New()
+
+	// A stored URL whose name resolves to loopback is caught when dialing
+	_, port, _ := net.SplitHostPort(receiver.Listener.Addr().String())
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, "http://localhost:"+port+"/hook", `["*"]`, true)...))
+	mock.ExpectQuery("INSERT INTO webhook_deliveries").
+		WithArgs(id, int64(42), models.EventExampleCreated, sqlmock.AnyArg(), int64(1), nil,
+			argFunc(func(v driver.Value) bool {
+				s, ok := v.(string)
+				return ok && strings.Contains(s, webhooks.ErrForbiddenDestination.Error())
+			}), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("UPDATE webhooks SET consecutive_failures = consecutive_failures \\+ 1").
+		WillReturnRows(sqlmock.NewRows([]string{"consecutive_failures"}).AddRow(1))
+	mock.ExpectExec("UPDATE jobs SET state = 'available'").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	runJob(t, db, mock, registry, deliveryJob(t, id, 1))
+	assert.False(t, hit.Load(), "delivery reached a loopback address")
+}
+
+func TestWebhookCheckURL(t *testing.T) {
+	for _, url := range []string{
+		"https://partner.example.com/hook",
+		"http://203.0.113.7:8080/hook",
+		"https://[2001:db8::1]/hook",
+	} {
+		assert.NoError(t, webhooks.CheckURL(url), url)
+	}
+	for _, url := range []string{
+		"ftp://partner.example.com/hook",
+		"http://localhost:8080/admin",
+		"http://api.localhost/",
+		"http://127.0.0.1/",
+		"http://[::1]/",
+		"http://0.0.0.0:8080/",
+		"http://10.1.2.3/",
+		"http://192.168.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::ffff:127.0.0.1]/",
+		"http://[fe80::1]/",
+		"http://100.100.100.200/latest/meta-data/",
+		"http://100.64.0.1/",
+		"http://0.1.2.3/",
+		"http://[64:ff9b::7f00:1]/",
+		"http://224.0.0.1/",
+		"http://255.255.255.255/",
+		"http://[ff02::1]/",
+		"http://[fd00::1]/",
+	} {
+		assert.ErrorIs(t, webhooks.CheckURL(url), webhooks.ErrForbiddenDestination, url)
+	}
+}
+
+func TestWebhookSinkEnqueuesSubscribedWebhooks(t *testing.T) {
+	db, mock := newJobsMock(t)
+	service := newWebhooksService(db)
+	examples, orders := uuid.New(), uuid.New()
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE enabled").
+		WillReturnRows(sqlmock.NewRows(webhookColumns).
+			AddRow(webhookRow(examples, "https://partner.example.com/hook", `["example.*"]`, true)...).
+			AddRow(webhookRow(orders, "https://orders.example.com/hook", `["order.created"]`, true)...))
+	mock.ExpectQuery("INSERT INTO jobs").
+		WithArgs("deliver_webhook", jobs.DefaultQueue, sqlmock.AnyArg(), int64(0), sqlmock.AnyArg(), int64(5), examples.String()+":42").
+		WillReturnRows(sqlmock.NewRows(jobColumns).AddRow(claimedJob(1, "deliver_webhook", `{}`, 0, 5)...))
+
+	err := service.Deliver(context.Background(), events.Event{ID: 42, Type: models.EventExampleUpdated})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhooksAPICreate(t *testing.T) {
+	s, mock := NewTestServer()
+	id := uuid.New()
+
+	mock.ExpectQuery("INSERT INTO webhooks").
+		WithArgs("https://partner.example.com/hook", []byte(`["example.*"]`), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, "https://partner.example.com/hook", `["example.*"]`, true)...))
+
+	body := `{"url":"https://partner.example.com/hook","events":["example.*"]}`
+	req, _ := http.NewRequest("POST", "/webhooks/", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	var resp struct {
+		Data models.Webhook `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, id, resp.Data.ID)
+	assert.Equal(t, []string{"example.*"}, resp.Data.Events)
+	assert.Equal(t, testWebhookSecret, resp.Data.Secret)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The secret is only returned on creation
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, "https://partner.example.com/hook", `["example.*"]`, true)...))
+	req, _ = http.NewRequest("GET", "/webhooks/"+id.String(), nil)
+	rr = httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "secret")
+}
+
+func TestWebhooksAPIRejectsInvalidWebhook(t *testing.T) {
+	s, _ := NewTestServer()
+
+	for _, body := range []string{
+		`{"url":"not a url","events":["*"]}`,
+		`{"url":"https://partner.example.com/hook","events":[]}`,
+		`{"url":"https://partner.example.com/hook","events":["*"],"secret":"short"}`,
+		`{"url":"http://169.254.169.254/latest/meta-data/","events":["*"]}`,
+		`{"url":"http://127.0.0.1:8080/admin/log-level","events":["*"]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/webhooks/", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		s.RegisterRoutes().ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+}
+
+func TestWebhooksAPIRequiresAuthentication(t *testing.T) {
+	s, mock := NewTestServerWithConfig(&config.Config{Port: 8080, JWTSecret: socketSecret})
+
+	req, _ := http.NewRequest("GET", "/webhooks/", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// The access_token parameter is only read for WebSocket upgrades
+	req, _ = http.NewRequest("GET", "/webhooks/?access_token="+signToken(t, socketSecret), nil)
+	rr = httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks ORDER BY").
+		WillReturnRows(sqlmock.NewRows(webhookColumns))
+	req, _ = http.NewRequest("GET", "/webhooks/", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, socketSecret))
+	rr = httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhooksAPIRedeliver(t *testing.T) {
+	s, mock := NewTestServer()
+	id := uuid.New()
+	payload, _ := json.Marshal(events.Event{ID: 42, Type: models.EventExampleDeleted, Payload: json.RawMessage(`{"id":"a1"}`)})
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, "https://partner.example.com/hook", `["*"]`, true)...))
+	mock.ExpectQuery("SELECT (.+) FROM webhook_deliveries").
+		WithArgs(int64(9), id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event_type", "payload", "attempt", "response_status",
+			"error", "duration_ms", "created_at"}).
+			AddRow(9, id, 42, models.EventExampleDeleted, payload, 5, 500, "webhook responded 500", 12, time.Now()))
+	// A redelivery has no unique key, so it runs even if the event's job
+	// is still pending
+	mock.ExpectQuery("INSERT INTO jobs").
+		WithArgs("deliver_webhook", jobs.DefaultQueue, sqlmock.AnyArg(), int64(0), sqlmock.AnyArg(), int64(jobs.DefaultMaxAttempts), nil).
+		WillReturnRows(sqlmock.NewRows(jobColumns).AddRow(claimedJob(77, "deliver_webhook", `{}`, 0, jobs.DefaultMaxAttempts)...))
+
+	req, _ := http.NewRequest("POST", "/webhooks/"+id.String()+"/deliveries/9/redeliver", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+	assert.Contains(t, rr.Body.String(), `"job_id":77`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWebhooksAPIRedeliverErrors(t *testing.T) {
+	s, mock := NewTestServer()
+	id := uuid.New()
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WillReturnRows(sqlmock.NewRows(webhookColumns).AddRow(webhookRow(id, "https://partner.example.com/hook", `["*"]`, false)...))
+	req, _ := http.NewRequest("POST", "/webhooks/"+id.String()+"/deliveries/9/redeliver", nil)
+	rr := httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "WEBHOOK_DISABLED")
+
+	mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE id").
+		WillReturnRows(sqlmock.NewRows(webhookColumns))
+	req, _ = http.NewRequest("GET", "/webhooks/"+id.String()+"/deliveries", nil)
+	rr = httptest.NewRecorder()
+	s.RegisterRoutes().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}